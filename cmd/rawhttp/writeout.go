@@ -6,6 +6,7 @@ import (
 	"net"
 	"os"
 	"strings"
+	"time"
 
 	rawhttp "github.com/WhileEndless/go-rawhttp"
 )
@@ -106,11 +107,11 @@ func writeOutVar(name string, res *result, resp *rawhttp.Response, cfg *Config)
 		if resp.TLSVersion == "" {
 			return "0.000000"
 		}
-		return secs(m.DNSLookup + m.TCPConnect + m.TLSHandshake)
+		return secs(m.GetConnectionTime())
 	case "time_pretransfer":
-		return secs(m.DNSLookup + m.TCPConnect + m.TLSHandshake)
+		return secs(m.GetConnectionTime() + m.SettingsExchange)
 	case "time_starttransfer":
-		return secs(m.DNSLookup + m.TCPConnect + m.TLSHandshake + m.TTFB)
+		return secs(m.GetConnectionTime() + m.SettingsExchange + m.RequestWrite + m.TTFB)
 	case "time_total":
 		return secs(m.TotalTime)
 	default:
@@ -121,15 +122,35 @@ func writeOutVar(name string, res *result, resp *rawhttp.Response, cfg *Config)
 	}
 }
 
-// printTimings writes a human-readable timing breakdown to stderr (--timings).
+// printTimings writes a human-readable timing breakdown to stderr (--timings),
+// followed by the measured phases with their offsets from the request start.
 func printTimings(resp *rawhttp.Response) {
 	m := resp.Timings
 	fmt.Fprintln(os.Stderr, "* Timing breakdown:")
-	fmt.Fprintf(os.Stderr, "*   DNS lookup:     %s\n", m.DNSLookup)
-	fmt.Fprintf(os.Stderr, "*   TCP connect:    %s\n", m.TCPConnect)
-	fmt.Fprintf(os.Stderr, "*   TLS handshake:  %s\n", m.TLSHandshake)
-	fmt.Fprintf(os.Stderr, "*   TTFB:           %s\n", m.TTFB)
-	fmt.Fprintf(os.Stderr, "*   Total:          %s\n", m.TotalTime)
+	optional := func(label string, d time.Duration) {
+		if d > 0 {
+			fmt.Fprintf(os.Stderr, "*   %-18s%s\n", label+":", d)
+		}
+	}
+	optional("Pool wait", m.PoolWait)
+	fmt.Fprintf(os.Stderr, "*   DNS lookup:       %s\n", m.DNSLookup)
+	fmt.Fprintf(os.Stderr, "*   TCP connect:      %s\n", m.TCPConnect)
+	optional("Proxy tunnel", m.ProxyConnect)
+	fmt.Fprintf(os.Stderr, "*   TLS handshake:    %s\n", m.TLSHandshake)
+	optional("SETTINGS exchange", m.SettingsExchange)
+	optional("Request write", m.RequestWrite)
+	fmt.Fprintf(os.Stderr, "*   TTFB:             %s\n", m.TTFB)
+	optional("First body byte", m.FirstBodyByte)
+	optional("Body transfer", m.BodyTransfer)
+	fmt.Fprintf(os.Stderr, "*   Total:            %s\n", m.TotalTime)
+
+	if len(m.Phases) == 0 {
+		return
+	}
+	fmt.Fprintln(os.Stderr, "* Timeline:")
+	for _, p := range m.Phases {
+		fmt.Fprintf(os.Stderr, "*   %-10s +%-12s %s\n", p.Name, p.Start.Sub(m.Start), p.Duration())
+	}
 }
 
 func normalizeHTTPVersion(v string) string {
@@ -4,6 +4,15 @@ go 1.24.0
 
 toolchain go1.24.2
 
-require golang.org/x/net v0.47.0
+require (
+	github.com/andybalholm/brotli v1.2.1
+	github.com/klauspost/compress v1.18.0
+	github.com/refraction-networking/utls v1.8.2
+	golang.org/x/crypto v0.44.0
+	golang.org/x/net v0.47.0
+)
 
-require golang.org/x/text v0.31.0 // indirect
+require (
+	golang.org/x/sys v0.38.0 // indirect
+	golang.org/x/text v0.31.0 // indirect
+)
@@ -0,0 +1,97 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/WhileEndless/go-rawhttp/pkg/errors"
+)
+
+// ParseMode selects how HTTP/1.1 responses that deviate from RFC 9112 are handled.
+type ParseMode string
+
+const (
+	// ParseLenient parses responses on a best-effort basis, as a raw client must
+	// to observe misbehaving servers. Deviations are recorded in Response.Anomalies.
+	ParseLenient ParseMode = "lenient"
+
+	// ParseStrict fails the request with a protocol error at the first RFC 9112
+	// violation. The anomalies observed up to that point are still recorded.
+	ParseStrict ParseMode = "strict"
+)
+
+// AnomalyType identifies a kind of deviation observed while parsing a response.
+type AnomalyType string
+
+// Anomaly types. All are RFC 9112 violations except AnomalyChunkExtension,
+// which is legal but rarely used and therefore worth reporting.
+const (
+	AnomalyBareLF                 AnomalyType = "bare_lf"                  // Line terminated by LF without CR
+	AnomalyInvalidStatusLine      AnomalyType = "invalid_status_line"      // Unexpected version, status code or separator
+	AnomalyObsFold                AnomalyType = "obs_fold"                 // Header continuation line (obsolete line folding)
+	AnomalyMalformedHeader        AnomalyType = "malformed_header"         // Header line without a colon
+	AnomalyWhitespaceBeforeColon  AnomalyType = "whitespace_before_colon"  // Whitespace between field name and colon
+	AnomalyDuplicateContentLength AnomalyType = "duplicate_content_length" // More than one Content-Length value
+	AnomalyInvalidContentLength   AnomalyType = "invalid_content_length"   // Content-Length not made of digits only (e.g. "+2")
+	AnomalyConflictingFraming     AnomalyType = "conflicting_framing"      // Both Content-Length and Transfer-Encoding
+	AnomalyUnexpectedBody         AnomalyType = "unexpected_body"          // Body sent for HEAD, 204 or 304
+	AnomalyChunkExtension         AnomalyType = "chunk_extension"          // Chunk size followed by ";ext"
+	AnomalyInvalidChunkSize       AnomalyType = "invalid_chunk_size"       // Chunk size padded with whitespace or not hex digits only
+	AnomalyOversizeChunkLine      AnomalyType = "oversize_chunk_line"      // Chunk size line longer than maxChunkLineBytes
+	AnomalyMissingChunkCRLF       AnomalyType = "missing_chunk_crlf"       // Chunk data not followed by CRLF
+	AnomalyBodyTooShort           AnomalyType = "body_too_short"           // Connection ended before the declared body did
+	AnomalyBodyTooLong            AnomalyType = "body_too_long"            // Bytes followed the declared body
+)
+
+// maxChunkLineBytes is the longest chunk size line (with extensions) that is not
+// reported as AnomalyOversizeChunkLine.
+const maxChunkLineBytes = 4096
+
+// Anomaly is a deviation from RFC 9112 observed while parsing an HTTP/1.1 response.
+type Anomaly struct {
+	Type   AnomalyType
+	Detail string // What was observed, e.g. the offending line
+}
+
+// String returns the anomaly as "type: detail".
+func (a Anomaly) String() string {
+	return fmt.Sprintf("%s: %s", a.Type, a.Detail)
+}
+
+// checker records the anomalies of a response and, in strict mode, turns
+// violations into errors. It also carries the response size limits.
+type checker struct {
+	strict   bool
+	response *Response
+	limits   limits
+	head     headBudget // budget of the head or trailer section being read
+}
+
+func newChecker(opts Options, response *Response) *checker {
+	c := &checker{strict: opts.ParseMode == ParseStrict, response: response, limits: newLimits(opts)}
+	c.startHead()
+	return c
+}
+
+// startHead starts charging a new head or trailer section to the header limits.
+func (c *checker) startHead() {
+	c.head = headBudget{limits: &c.limits}
+}
+
+// note records an anomaly. In strict mode it returns the protocol error that
+// must end the parse, unless the anomaly is not a violation.
+func (c *checker) note(t AnomalyType, format string, args ...interface{}) error {
+	a := Anomaly{Type: t, Detail: fmt.Sprintf(format, args...)}
+	c.response.Anomalies = append(c.response.Anomalies, a)
+	if c.strict && t != AnomalyChunkExtension {
+		return errors.NewProtocolError("strict parse mode: "+a.String(), nil)
+	}
+	return nil
+}
+
+// lineEnding records a line terminated by a bare LF. line includes its terminator.
+func (c *checker) lineEnding(line, where string) error {
+	if len(line) > 0 && line[len(line)-1] == '\n' && (len(line) < 2 || line[len(line)-2] != '\r') {
+		return c.note(AnomalyBareLF, "%s %q", where, line)
+	}
+	return nil
+}
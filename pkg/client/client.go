@@ -153,6 +153,17 @@ type Options struct {
 	ReadTimeout  time.Duration
 	WriteTimeout time.Duration
 
+	// ExpectContinueTimeout enables the Expect: 100-continue handshake.
+	//
+	// When > 0 and the raw request carries an "Expect: 100-continue" header and a
+	// body, only the request head is written first. The body follows once the
+	// server answers 100 Continue, or when no response has arrived within this
+	// timeout (RFC 9110 Section 10.1.1). If the server answers with a final status
+	// instead, the body is never sent and the connection is not reused.
+	//
+	// Default: 0 (the request is written in one go, as before).
+	ExpectContinueTimeout time.Duration
+
 	// DisableReadDeadlineFallback controls the read-deadline safety net (v2.2.0+).
 	//
 	// When ReadTimeout == 0, the client normally applies a fallback deadline to the
@@ -164,6 +175,40 @@ type Options struct {
 	// (including the response head). Default: false (fallback active).
 	DisableReadDeadlineFallback bool
 
+	// PipelineSinglePacket makes DoPipelined write all requests with a single write
+	// call, so that small requests typically leave in one TCP segment. By default
+	// each request is written with its own call, back to back, without waiting for
+	// responses.
+	PipelineSinglePacket bool
+
+	// ParseMode selects how HTTP/1.1 responses that violate RFC 9112 are handled:
+	// ParseLenient (the default; any value but ParseStrict) parses them on a
+	// best-effort basis, ParseStrict fails the request at the first violation.
+	// Both record what was observed in Response.Anomalies.
+	ParseMode ParseMode
+
+	// DecodeContent removes the Content-Encoding (gzip, x-gzip, deflate, br and
+	// zstd, possibly stacked) of every response body. Body and Raw keep the bytes
+	// as received; the decoded body goes to Response.DecodedBody, or BodyStream
+	// yields it with DoStream. See DecodeResponse.
+	DecodeContent bool
+
+	// MaxDecodedBytes bounds a decoded body, guarding against decompression
+	// bombs (default: 100MB). Decoding stops there with a limit error in
+	// Response.DecodeError. It also bounds the zstd window (at least 8MB).
+	MaxDecodedBytes int64
+
+	// Response size limits, enforced on HTTP/1.1 and HTTP/2. A response exceeding
+	// one fails with an error of type ErrorTypeLimit; the Response is returned along
+	// with it, filled with what was read up to the limit. 0 selects the default.
+	// Trailer sections are subject to the header limits as well. On HTTP/2 each
+	// header field counts as name + value + 32 bytes and MaxHeaderBytes has no
+	// default (HTTP2Settings.MaxHeaderListSize bounds the head instead).
+	MaxHeaderBytes     int   // Bytes in a response head, status line included (default: 64KB)
+	MaxHeaderCount     int   // Header fields in a response head (default: unlimited)
+	MaxBodyBytes       int64 // Body bytes as received, de-chunked (default: unlimited)
+	MaxChunkLineLength int   // Bytes in an HTTP/1.1 chunk size line, extensions included (default: unlimited)
+
 	// Body memory limit before spilling to disk (default: 4MB)
 	BodyMemLimit int64
 
@@ -206,6 +251,39 @@ type Options struct {
 	//   }
 	Proxy *ProxyConfig
 
+	// DialContext, when set, opens every connection instead of net.Dialer: direct
+	// connections to the target, connections to proxies, over HTTP/1.1 and HTTP/2.
+	// It can route through in-memory pipes (net.Pipe), network namespaces or a
+	// custom network stack. For direct HTTP/1.1 connections addr is the resolved
+	// "ip:port" (or ConnectIP).
+	//
+	// The hooks are part of the pool key: pooled connections are only shared by
+	// requests using the same DialContext and Resolver.
+	DialContext transport.DialContextFunc `json:"-"`
+
+	// Resolver, when set, replaces the system resolver for target and proxy host
+	// names (and SOCKS4 lookups), e.g. to use a DNS cache. *net.Resolver
+	// implements it.
+	Resolver transport.Resolver `json:"-"`
+
+	// Network forces direct connections to one address family: "tcp4" (IPv4
+	// only) or "tcp6" (IPv6 only). Empty or "tcp" allows both.
+	Network string
+
+	// HappyEyeballs enables dual-stack dialing (RFC 8305): A and AAAA records are
+	// resolved in parallel and connection attempts are raced across the addresses,
+	// IPv6 first, so a broken IPv6 path no longer costs a full ConnTimeout. The
+	// next attempt starts after FallbackDelay (default 250ms) or as soon as the
+	// previous one fails; the first connection wins. Every attempt is reported in
+	// Response.DialAttempts. Applies to direct connections only.
+	HappyEyeballs bool
+	FallbackDelay time.Duration
+
+	// Trace receives lifecycle callbacks for the request and its connection
+	// (DNS, connect, proxy, TLS, GotConn, writes, first byte, 1xx responses and,
+	// over HTTP/2, frames), e.g. to feed live dashboards. See transport.ClientTrace.
+	Trace *transport.ClientTrace `json:"-"`
+
 	// Custom TLS configuration
 	CustomCACerts [][]byte // Custom root CA certificates in PEM format
 
@@ -242,6 +320,12 @@ type Options struct {
 	// If nil, Go's default secure cipher suites are used
 	// Use CipherSuites field in TLSConfig for more control
 	CipherSuites []uint16
+
+	// TLSFingerprint replaces crypto/tls's ClientHello with a browser's, from a
+	// built-in profile or a JA3 string (see transport.ParseTLSFingerprint), for
+	// HTTP/1.1 and HTTP/2, direct or through a proxy. The JA3 and JA4 of the
+	// ClientHello sent are reported in Response.JA3 and Response.JA4.
+	TLSFingerprint *transport.TLSFingerprint `json:"-"`
 }
 
 // Response represents a parsed HTTP response.
@@ -250,6 +334,8 @@ type Response struct {
 	StatusCode  int
 	Method      string // HTTP method from the request (e.g., "GET", "POST", "HEAD")
 	Headers     map[string][]string
+	HeaderList  []HeaderField       // Header lines in wire order, duplicates and malformed lines included
+	Trailers    map[string][]string // Chunked trailer fields (canonical keys), kept apart from Headers
 	Body        *buffer.Buffer
 	Raw         *buffer.Buffer
 	Timings     timing.Metrics
@@ -278,10 +364,97 @@ type Response struct {
 	TLSSessionID string // TLS session ID (hex-encoded)
 	TLSResumed   bool   // Whether TLS session was resumed
 
+	// ClientHello fingerprints of the connection's TLS handshake
+	JA3     string // JA3 string ("version,ciphers,extensions,groups,point formats")
+	JA3Hash string // MD5 of JA3, hex-encoded
+	JA4     string // JA4 fingerprint
+
+	// Content decoding (Options.DecodeContent). DecodedBody is Body without its
+	// Content-Encoding, nil when the body is not encoded or could not be decoded
+	// at all. ContentDecoded lists the codings removed (as named by the header,
+	// in the order they were applied); DecodeError reports why decoding failed or
+	// stopped early. With DoStream, BodyStream yields the decoded body and read
+	// errors take the place of DecodeError.
+	DecodedBody    *buffer.Buffer
+	ContentDecoded []string
+	DecodeError    error
+
+	// Anomalies lists the deviations from RFC 9112 observed while parsing an
+	// HTTP/1.1 response (bare LF line endings, obs-fold, conflicting framing,
+	// bodies shorter or longer than declared, ...), in the order they were found.
+	// With DoStream only the response head and framing are checked.
+	Anomalies []Anomaly
+
+	// Informational holds the interim 1xx responses (e.g. 100 Continue) received
+	// before the final response, in arrival order. Their raw bytes precede the
+	// final response in Raw.
+	Informational []InterimResponse
+
+	// ServerPush holds the responses pushed by an HTTP/2 server (PUSH_PROMISE)
+	// while answering the request. Only populated by Do when server push is
+	// enabled (HTTP2Settings.DisableServerPush = false).
+	ServerPush []PushedResponse
+
 	// Proxy metadata (v2.0.0+)
 	ProxyUsed bool   // Whether the request was routed through an upstream proxy
 	ProxyType string // Proxy protocol type: "http", "https", "socks4", "socks5" (only if ProxyUsed=true)
 	ProxyAddr string // Proxy server address "host:port" (only if ProxyUsed=true)
+
+	// DialAttempts lists every address tried when the connection was opened and
+	// its outcome, in start order (see Options.HappyEyeballs). Empty for proxied
+	// connections; reused connections report how they were originally dialed.
+	DialAttempts []transport.DialAttempt
+
+	// BodyStream exposes the response body as a stream (only set by DoStream).
+	// Chunked bodies are de-chunked on the fly; Body stays empty and Raw holds
+	// only the response head. The connection is returned to the pool when the
+	// stream has been read to EOF and closed; closing it early discards the
+	// connection. Callers MUST Close it.
+	BodyStream io.ReadCloser
+}
+
+// InterimResponse is an informational 1xx response received before the final one.
+type InterimResponse struct {
+	StatusLine string
+	StatusCode int
+	Headers    map[string][]string
+	HeaderList []HeaderField // Header lines in wire order
+	ReceivedAt time.Time     // When the interim status line was read
+}
+
+// HeaderField is a single response header line as it appeared on the wire.
+//
+//   - Regular line: Name is the field name exactly as sent (original casing, before
+//     the first colon) and Value is the field value with surrounding whitespace
+//     trimmed.
+//   - obs-fold continuation line (leading SP/HTAB): Name is empty and Value is the
+//     trimmed continuation text; the map folds it into the previous header.
+//   - Line without a colon: Name is empty and Value is the line as sent; the map
+//     ignores it.
+//
+// RawLine holds the exact bytes including the line terminator (CRLF or bare LF).
+// For HTTP/2 responses RawLine is the formatted "name: value\r\n" line.
+type HeaderField struct {
+	Name    string
+	Value   string
+	RawLine string
+}
+
+// PushedResponse is a response pushed by an HTTP/2 server along with the response
+// to a request.
+type PushedResponse struct {
+	PromisedStreamID uint32
+	RequestHeaders   []HeaderField // Promised request fields in wire order, pseudo-headers included
+	ReceivedAt       time.Time     // When the PUSH_PROMISE was received
+	Response         *Response     // Pushed response (nil if Err is set)
+	Err              error         // Error reading the pushed response
+}
+
+// isInterimStatus reports whether code is an interim response that is followed by
+// another response on the same request. 101 Switching Protocols is final: HTTP/1.1
+// ends on the connection after it.
+func isInterimStatus(code int) bool {
+	return code >= 100 && code < 200 && code != 101
 }
 
 // HTTP2Settings contains HTTP/2 specific configuration.
@@ -315,6 +488,27 @@ type HTTP2Settings struct {
 	// Default: true. Disable only for debugging.
 	EnableCompression bool
 
+	// SinglePacket makes Sender.DoBatch withhold the last frame of every request
+	// and release them all in one TCP write (single-packet attack timing).
+	SinglePacket bool
+
+	// PingTimeout is how long an idle pooled connection may take to acknowledge
+	// the health-check PING before it is evicted as dead. Default: 15s.
+	PingTimeout time.Duration
+
+	// MaxConnsPerHost caps the pooled HTTP/2 connections per origin. A new
+	// connection is dialed only when every connection has reached the server's
+	// SETTINGS_MAX_CONCURRENT_STREAMS; at the cap, requests wait for a free
+	// stream. Default: 0 (unlimited).
+	MaxConnsPerHost int
+
+	// Fingerprint makes the connection preface and request HEADERS match a
+	// browser: a built-in profile name ("chrome", "firefox", "safari") or an
+	// Akamai fingerprint string such as
+	// "1:65536;2:0;4:6291456;6:262144|15663105|0|m,a,s,p". A profile replaces
+	// the SETTINGS above. Default: "" (the library's own preface).
+	Fingerprint string
+
 	// Debug contains HTTP/2 debugging flags (optional, all default to false).
 	// These flags enable detailed logging of HTTP/2 protocol operations.
 	// Production safe - explicit opt-in with zero overhead when disabled.
@@ -397,6 +591,24 @@ func parseMethod(req []byte) string {
 // Do executes the HTTP request using raw sockets.
 // v2.1.1+: Automatically retries on stale connection errors (broken pipe, connection reset).
 func (c *Client) Do(ctx context.Context, req []byte, opts Options) (*Response, error) {
+	return c.do(ctx, req, opts, false)
+}
+
+// DoStream executes the HTTP request and returns as soon as the response head
+// (status line and headers) has been parsed. The body is exposed through
+// Response.BodyStream and is read from the socket on demand, so arbitrarily large
+// or never-ending bodies (downloads, server-sent events, long-polls) are never
+// buffered. ReadTimeout, when set, bounds each read of the stream instead of the
+// whole response. Response.Timings gains the body timings at EOF.
+//
+// The connection stays checked out of the pool until BodyStream is closed; it is
+// only returned for reuse when the body was read to EOF with unambiguous framing.
+func (c *Client) DoStream(ctx context.Context, req []byte, opts Options) (*Response, error) {
+	return c.do(ctx, req, opts, true)
+}
+
+// do implements Do and DoStream, including the stale-connection retry loop.
+func (c *Client) do(ctx context.Context, req []byte, opts Options, stream bool) (*Response, error) {
 	if c.transport == nil {
 		return nil, errors.NewValidationError("client transport is nil")
 	}
@@ -405,28 +617,7 @@ func (c *Client) Do(ctx context.Context, req []byte, opts Options) (*Response, e
 		return nil, errors.NewValidationError("request cannot be empty")
 	}
 
-	// Create transport config
-	transportConfig := transport.Config{
-		Scheme:          opts.Scheme,
-		Host:            opts.Host,
-		Port:            opts.Port,
-		ConnectIP:       opts.ConnectIP,
-		SNI:             opts.SNI,
-		DisableSNI:      opts.DisableSNI,
-		InsecureTLS:     opts.InsecureTLS,
-		ConnTimeout:     opts.ConnTimeout,
-		DNSTimeout:      opts.DNSTimeout,
-		ReadTimeout:     opts.ReadTimeout,
-		WriteTimeout:    opts.WriteTimeout,
-		ReuseConnection: opts.ReuseConnection,
-		Proxy:           convertProxyConfig(opts.Proxy),
-		CustomCACerts:   opts.CustomCACerts,
-		ClientCertPEM:   opts.ClientCertPEM,
-		ClientKeyPEM:    opts.ClientKeyPEM,
-		ClientCertFile:  opts.ClientCertFile,
-		ClientKeyFile:   opts.ClientKeyFile,
-		TLSConfig:       opts.TLSConfig,
-	}
+	transportConfig := newTransportConfig(opts)
 
 	// v2.1.1+: Retry loop for stale connection handling
 	// Maximum 1 retry on stale connection error (broken pipe, connection reset)
@@ -434,8 +625,9 @@ func (c *Client) Do(ctx context.Context, req []byte, opts Options) (*Response, e
 	var lastErr error
 
 	for attempt := 0; attempt <= maxRetries; attempt++ {
-		resp, err, shouldRetry := c.doRequest(ctx, req, opts, transportConfig, attempt > 0)
+		resp, err, shouldRetry := c.doRequest(ctx, req, opts, transportConfig, attempt > 0, stream)
 		if err == nil {
+			DecodeResponse(resp, opts)
 			return resp, nil
 		}
 
@@ -457,9 +649,46 @@ func (c *Client) Do(ctx context.Context, req []byte, opts Options) (*Response, e
 	return nil, lastErr
 }
 
-// doRequest performs the actual HTTP request.
+// newTransportConfig describes the connection a request needs.
+func newTransportConfig(opts Options) transport.Config {
+	return transport.Config{
+		Scheme:           opts.Scheme,
+		Host:             opts.Host,
+		Port:             opts.Port,
+		ConnectIP:        opts.ConnectIP,
+		SNI:              opts.SNI,
+		DisableSNI:       opts.DisableSNI,
+		InsecureTLS:      opts.InsecureTLS,
+		ConnTimeout:      opts.ConnTimeout,
+		DNSTimeout:       opts.DNSTimeout,
+		ReadTimeout:      opts.ReadTimeout,
+		WriteTimeout:     opts.WriteTimeout,
+		ReuseConnection:  opts.ReuseConnection,
+		Proxy:            convertProxyConfig(opts.Proxy),
+		CustomCACerts:    opts.CustomCACerts,
+		ClientCertPEM:    opts.ClientCertPEM,
+		ClientKeyPEM:     opts.ClientKeyPEM,
+		ClientCertFile:   opts.ClientCertFile,
+		ClientKeyFile:    opts.ClientKeyFile,
+		TLSConfig:        opts.TLSConfig,
+		MinTLSVersion:    opts.MinTLSVersion,
+		MaxTLSVersion:    opts.MaxTLSVersion,
+		TLSRenegotiation: opts.TLSRenegotiation,
+		CipherSuites:     opts.CipherSuites,
+		TLSFingerprint:   opts.TLSFingerprint,
+		DialContext:      opts.DialContext,
+		Resolver:         opts.Resolver,
+		Network:          opts.Network,
+		HappyEyeballs:    opts.HappyEyeballs,
+		FallbackDelay:    opts.FallbackDelay,
+		Trace:            opts.Trace,
+	}
+}
+
+// doRequest performs the actual HTTP request. In stream mode, ownership of the
+// connection passes to Response.BodyStream once the response head was read.
 // Returns (response, error, shouldRetry).
-func (c *Client) doRequest(ctx context.Context, req []byte, opts Options, transportConfig transport.Config, isRetry bool) (*Response, error, bool) {
+func (c *Client) doRequest(ctx context.Context, req []byte, opts Options, transportConfig transport.Config, isRetry bool, stream bool) (*Response, error, bool) {
 	// Create timer for performance measurement
 	timer := timing.NewTimer()
 
@@ -485,8 +714,15 @@ func (c *Client) doRequest(ctx context.Context, req []byte, opts Options, transp
 	// read, or read/write error flips this to false so the socket is closed, not pooled.
 	reusable := true
 
+	// handedOff is set once a body stream owns the connection; from then on the
+	// stream's Close decides whether the connection is pooled or closed.
+	handedOff := false
+
 	// Handle connection cleanup based on pooling settings and errors
 	defer func() {
+		if handedOff {
+			return
+		}
 		if staleError || !opts.ReuseConnection || !reusable {
 			// Close connection on error, ambiguous framing, or when pooling is disabled
 			c.transport.CloseConnectionWithMetadata(opts.Host, opts.Port, conn, connMetadata)
@@ -499,40 +735,52 @@ func (c *Client) doRequest(ctx context.Context, req []byte, opts Options, transp
 	// Parse HTTP method from request for RFC 9110 compliance
 	method := parseMethod(req)
 
-	// Initialize response
-	rawBufferSize := opts.BodyMemLimit
-	if rawBufferSize == 0 {
-		rawBufferSize = 4 * 1024 * 1024 // Default 4MB
-	}
-	rawBufferSize += 1024 * 1024 // Add 1MB overhead for headers
-	if rawBufferSize > 100*1024*1024 {
-		rawBufferSize = 100 * 1024 * 1024 // Cap at 100MB
+	response := newResponse(method, opts, connMetadata)
+
+	// Expect: 100-continue handshake: only the request head is sent up front and the
+	// body is held back until the server answers with 100 Continue (or stays silent
+	// for ExpectContinueTimeout).
+	payload := req
+	var ec *expectContinue
+	if opts.ExpectContinueTimeout > 0 {
+		if head, body, ok := splitExpectContinue(req); ok {
+			payload = head
+			ec = newExpectContinue(body, opts.ExpectContinueTimeout, opts.WriteTimeout)
+			ec.trace = opts.Trace
+		}
 	}
 
-	response := &Response{
-		Method:             method,
-		Headers:            make(map[string][]string),
-		Body:               buffer.New(opts.BodyMemLimit),
-		Raw:                buffer.New(rawBufferSize),
-		ConnectedIP:        connMetadata.ConnectedIP,
-		ConnectedPort:      connMetadata.ConnectedPort,
-		NegotiatedProtocol: connMetadata.NegotiatedProtocol,
-		ConnectionReused:   connMetadata.ConnectionReused,
-		LocalAddr:          connMetadata.LocalAddr,
-		RemoteAddr:         connMetadata.RemoteAddr,
-		ConnectionID:       connMetadata.ConnectionID,
-		TLSVersion:         connMetadata.TLSVersion,
-		TLSCipherSuite:     connMetadata.TLSCipherSuite,
-		TLSServerName:      connMetadata.TLSServerName,
-		TLSSessionID:       connMetadata.TLSSessionID,
-		TLSResumed:         connMetadata.TLSResumed,
-		ProxyUsed:          connMetadata.ProxyUsed,
-		ProxyType:          connMetadata.ProxyType,
-		ProxyAddr:          connMetadata.ProxyAddr,
+	// read reads the response (or, in stream mode, its head) from the connection.
+	reader := bufio.NewReader(conn)
+	read := func() error {
+		if !stream {
+			return c.readResponse(conn, reader, response, opts, timer, &reusable, ec)
+		}
+		if err := c.readResponseHead(conn, reader, response, opts, timer, ec); err != nil {
+			return err
+		}
+		// As in readResponse: a final interim status means the server closed.
+		if ec.withheld() || isInterimStatus(response.StatusCode) {
+			reusable = false
+		}
+		if err := c.attachBodyStream(conn, connMetadata, reader, response, opts, timer, reusable); err != nil {
+			return err
+		}
+		handedOff = true
+		return nil
 	}
 
-	// Send request
-	if err := c.sendRequest(conn, req, opts.WriteTimeout); err != nil {
+	// Send request (with Expect: 100-continue the body follows later, see ec.send)
+	timer.StartWrite()
+	err = c.sendRequest(conn, payload, opts.WriteTimeout)
+	timer.EndWrite()
+	if err == nil {
+		opts.Trace.TraceWroteHeaders()
+	}
+	if ec == nil || err != nil {
+		opts.Trace.TraceWroteRequest(err)
+	}
+	if err != nil {
 		// The server may have rejected the request early (e.g. a WAF/load balancer)
 		// and already written a complete response, then closed/RST the socket while
 		// we were still writing the request body. A broken-pipe / connection-reset
@@ -540,9 +788,10 @@ func (c *Client) doRequest(ctx context.Context, req []byte, opts Options, transp
 		// the receive buffer. Try to read it (exactly like curl) before treating the
 		// write error as fatal.
 		if isStaleConnectionError(err) {
-			if rerr := c.readResponse(conn, response, opts, timer, &reusable); rerr == nil {
+			reusable = false // half-written/closing socket: never pool it
+			ec.abandon()     // never follow a failed head with the held-back body
+			if rerr := read(); rerr == nil {
 				// A complete, well-framed response arrived despite the failed write.
-				reusable = false // half-written/closing socket: never pool it
 				response.Timings = timer.GetMetrics()
 				response.BodyBytes = response.Body.Size()
 				response.RawBytes = response.Raw.Size()
@@ -560,7 +809,7 @@ func (c *Client) doRequest(ctx context.Context, req []byte, opts Options, transp
 	}
 
 	// Read response
-	if err := c.readResponse(conn, response, opts, timer, &reusable); err != nil {
+	if err := read(); err != nil {
 		// EOF/timeout on the very first read of a reused connection means the server
 		// closed the pooled keep-alive connection; retry transparently on a fresh one.
 		if stderrors.Is(err, errStaleFirstRead) {
@@ -603,6 +852,45 @@ func (c *Client) doRequest(ctx context.Context, req []byte, opts Options, transp
 	return response, nil, false
 }
 
+// newResponse creates an empty response for a request sent on a connection
+// described by connMetadata.
+func newResponse(method string, opts Options, connMetadata *transport.ConnectionMetadata) *Response {
+	rawBufferSize := opts.BodyMemLimit
+	if rawBufferSize == 0 {
+		rawBufferSize = 4 * 1024 * 1024 // Default 4MB
+	}
+	rawBufferSize += 1024 * 1024 // Add 1MB overhead for headers
+	if rawBufferSize > 100*1024*1024 {
+		rawBufferSize = 100 * 1024 * 1024 // Cap at 100MB
+	}
+
+	return &Response{
+		Method:             method,
+		Headers:            make(map[string][]string),
+		Body:               buffer.New(opts.BodyMemLimit),
+		Raw:                buffer.New(rawBufferSize),
+		ConnectedIP:        connMetadata.ConnectedIP,
+		ConnectedPort:      connMetadata.ConnectedPort,
+		NegotiatedProtocol: connMetadata.NegotiatedProtocol,
+		ConnectionReused:   connMetadata.ConnectionReused,
+		LocalAddr:          connMetadata.LocalAddr,
+		RemoteAddr:         connMetadata.RemoteAddr,
+		ConnectionID:       connMetadata.ConnectionID,
+		TLSVersion:         connMetadata.TLSVersion,
+		TLSCipherSuite:     connMetadata.TLSCipherSuite,
+		TLSServerName:      connMetadata.TLSServerName,
+		TLSSessionID:       connMetadata.TLSSessionID,
+		TLSResumed:         connMetadata.TLSResumed,
+		JA3:                connMetadata.JA3,
+		JA3Hash:            connMetadata.JA3Hash,
+		JA4:                connMetadata.JA4,
+		ProxyUsed:          connMetadata.ProxyUsed,
+		ProxyType:          connMetadata.ProxyType,
+		ProxyAddr:          connMetadata.ProxyAddr,
+		DialAttempts:       connMetadata.DialAttempts,
+	}
+}
+
 func (c *Client) sendRequest(conn net.Conn, req []byte, writeTimeout time.Duration) error {
 	if writeTimeout > 0 {
 		if err := conn.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
@@ -624,84 +912,177 @@ func (c *Client) sendRequest(conn net.Conn, req []byte, writeTimeout time.Durati
 	return nil
 }
 
-func (c *Client) readResponse(conn net.Conn, response *Response, opts Options, timer *timing.Timer, reusable *bool) error {
-	readTimeout := opts.ReadTimeout
+func (c *Client) readResponse(conn net.Conn, reader *bufio.Reader, response *Response, opts Options, timer *timing.Timer, reusable *bool, ec *expectContinue) error {
+	if err := c.readResponseHead(conn, reader, response, opts, timer, ec); err != nil {
+		return err
+	}
+
+	// The server answered with a final status before we sent the body: the
+	// request was aborted mid-message, so the connection cannot be reused. An
+	// interim status is only final when the server closed the connection.
+	if ec.withheld() || isInterimStatus(response.StatusCode) {
+		*reusable = false
+	}
 
-	// Apply a read deadline. When ReadTimeout is set, it bounds the whole response.
-	// When ReadTimeout is unset (0), apply a fallback deadline to JUST the response
-	// head (status line + headers) so a dead/half-open reused keep-alive connection
-	// fails fast instead of blocking forever; the body read is left unbounded so
-	// streaming / long-poll responses are unaffected (v2.2.0+, opt-out available).
-	headFallback := false
-	switch {
-	case readTimeout > 0:
-		if err := conn.SetReadDeadline(time.Now().Add(readTimeout)); err != nil {
+	// Read body based on headers
+	return c.readBody(reader, response, response.Headers, reusable, timer, newChecker(opts, response))
+}
+
+// readResponseHead reads the status line and headers into response, leaving reader
+// positioned at the first body byte. Interim 1xx responses that
+// precede the final one are recorded in response.Informational (their bytes stay
+// in Raw). When ec is non-nil the held-back request body is sent once the server
+// answers 100 Continue or the expect timeout elapses.
+func (c *Client) readResponseHead(conn net.Conn, reader *bufio.Reader, response *Response, opts Options, timer *timing.Timer, ec *expectContinue) error {
+	headDeadline, headFallback := readDeadline(opts)
+	if !headDeadline.IsZero() {
+		if err := conn.SetReadDeadline(headDeadline); err != nil {
 			return errors.NewIOError("setting read deadline", err)
 		}
+	}
+
+	timer.StartTTFB()
+	if err := c.readHead(conn, reader, response, opts, timer, ec, headDeadline); err != nil {
+		return err
+	}
+
+	// Response head fully read: lift the fallback deadline so the body read is unbounded.
+	if headFallback {
+		conn.SetReadDeadline(time.Time{})
+	}
+
+	return nil
+}
+
+// readDeadline returns the read deadline for a response. When ReadTimeout is set,
+// it bounds the whole response. When ReadTimeout is unset (0), a fallback deadline
+// applies to JUST the response head (status line + headers), reported by fallback,
+// so a dead/half-open reused keep-alive connection fails fast instead of blocking
+// forever; the body read is left unbounded so streaming / long-poll responses are
+// unaffected (v2.2.0+, opt-out available).
+func readDeadline(opts Options) (deadline time.Time, fallback bool) {
+	switch {
+	case opts.ReadTimeout > 0:
+		return time.Now().Add(opts.ReadTimeout), false
 	case !opts.DisableReadDeadlineFallback:
 		fb := opts.ConnTimeout
 		if fb <= 0 {
 			fb = 10 * time.Second
 		}
-		if err := conn.SetReadDeadline(time.Now().Add(fb)); err != nil {
-			return errors.NewIOError("setting read deadline", err)
-		}
-		headFallback = true
+		return time.Now().Add(fb), true
 	}
+	return time.Time{}, false
+}
 
-	reader := bufio.NewReader(conn)
+// readHead parses the status line and headers of the next response from reader,
+// recording interim 1xx responses on the way (see readResponseHead). timer's TTFB
+// phase ends at the first byte.
+func (c *Client) readHead(conn net.Conn, reader *bufio.Reader, response *Response, opts Options, timer *timing.Timer, ec *expectContinue, headDeadline time.Time) error {
+	ck := newChecker(opts, response)
+	for first := true; ; first = false {
+		if ec.awaiting() {
+			if err := ec.await(c, conn, reader, headDeadline); err != nil {
+				return err
+			}
+		}
 
-	// Read status line
-	timer.StartTTFB()
-	statusLine, err := c.readLine(reader)
-	timer.EndTTFB()
-	if err != nil {
-		// EOF/timeout on the first read of a reused pooled connection means the server
-		// closed the keep-alive connection; mark it for transparent retry on a fresh one.
-		if response.ConnectionReused && response.Raw.Size() == 0 && isFirstReadStale(err) {
-			return fmt.Errorf("%w: %w", errStaleFirstRead, err)
+		// Read status line
+		ck.startHead()
+		statusLine, rawLine, err := c.readLine(reader, ck)
+		receivedAt := time.Now()
+		if first {
+			timer.EndTTFB()
+			if err == nil {
+				opts.Trace.TraceGotFirstResponseByte()
+			}
+		}
+		if errors.IsLimitError(err) {
+			response.Raw.Write([]byte(rawLine))
+			return err
+		}
+		if err != nil && !first && rawLine == "" && stderrors.Is(err, io.EOF) {
+			// The server closed the connection after an interim response: that
+			// response is the last one, so it stands as the final response.
+			response.Informational = response.Informational[:len(response.Informational)-1]
+			return nil
+		}
+		if err != nil {
+			// EOF/timeout on the first read of a reused pooled connection means the server
+			// closed the keep-alive connection; mark it for transparent retry on a fresh one.
+			if response.ConnectionReused && response.Raw.Size() == 0 && isFirstReadStale(err) {
+				return fmt.Errorf("%w: %w", errStaleFirstRead, err)
+			}
+			return errors.NewProtocolError("reading status line", err)
 		}
-		return errors.NewProtocolError("reading status line", err)
-	}
 
-	response.StatusLine = statusLine
-	if _, err := response.Raw.Write([]byte(statusLine + "\r\n")); err != nil {
-		return err
-	}
+		response.StatusLine = statusLine
+		if _, err := response.Raw.Write([]byte(rawLine)); err != nil {
+			return err
+		}
+		if err := ck.lineEnding(rawLine, "status line"); err != nil {
+			return err
+		}
 
-	// Parse status code and HTTP version
-	if err := c.parseStatusLine(statusLine, response); err != nil {
-		return err
-	}
+		// Parse status code and HTTP version
+		if err := c.parseStatusLine(statusLine, response, ck); err != nil {
+			return err
+		}
 
-	// Read headers
-	headers, err := c.readHeaders(reader, response.Raw)
-	if err != nil {
-		return err
-	}
-	response.Headers = headers
+		// Read headers
+		headers, headerList, err := c.readHeaders(reader, response.Raw, ck)
+		if errors.IsLimitError(err) {
+			response.Headers = headers
+			response.HeaderList = headerList
+		}
+		if err != nil {
+			return err
+		}
+		response.Headers = headers
+		response.HeaderList = headerList
 
-	// Response head fully read: lift the fallback deadline so the body read is unbounded.
-	if headFallback {
-		conn.SetReadDeadline(time.Time{})
+		if !isInterimStatus(response.StatusCode) {
+			break
+		}
+
+		// Interim response: record it and keep reading until the final status.
+		response.Informational = append(response.Informational, InterimResponse{
+			StatusLine: statusLine,
+			StatusCode: response.StatusCode,
+			Headers:    headers,
+			HeaderList: headerList,
+			ReceivedAt: receivedAt,
+		})
+		opts.Trace.TraceGot1xxResponse(response.StatusCode, headers)
+		if response.StatusCode == 100 && ec.awaiting() {
+			if err := ec.send(c, conn); err != nil {
+				return err
+			}
+		}
 	}
 
-	// Read body based on headers
-	return c.readBody(reader, response, headers, reusable)
+	return nil
 }
 
-func (c *Client) readLine(r *bufio.Reader) (string, error) {
-	line, err := r.ReadString('\n')
+// readLine reads a line of the response head and returns it without its
+// terminator (CRLF or bare LF), along with the line exactly as received. On a
+// limit error raw holds the bytes read so far.
+func (c *Client) readLine(r *bufio.Reader, ck *checker) (line, raw string, err error) {
+	raw, err = ck.head.line(r)
 	if err != nil {
-		return "", err
+		return "", raw, err
 	}
-	if len(line) >= 2 && line[len(line)-2:] == "\r\n" {
-		return line[:len(line)-2], nil
+	return trimEOL(raw), raw, nil
+}
+
+// trimEOL strips the line terminator (CRLF or bare LF) from line.
+func trimEOL(line string) string {
+	if strings.HasSuffix(line, "\r\n") {
+		return line[:len(line)-2]
 	}
-	return strings.TrimRight(line, "\n"), nil
+	return strings.TrimSuffix(line, "\n")
 }
 
-func (c *Client) parseStatusLine(statusLine string, response *Response) error {
+func (c *Client) parseStatusLine(statusLine string, response *Response, ck *checker) error {
 	parts := strings.SplitN(statusLine, " ", 3)
 	if len(parts) < 2 {
 		return errors.NewProtocolError("invalid status line format", nil)
@@ -718,30 +1099,68 @@ func (c *Client) parseStatusLine(statusLine string, response *Response) error {
 	}
 
 	response.StatusCode = code
+
+	// RFC 9112 Section 4: HTTP-version SP 3DIGIT SP [ reason-phrase ]
+	switch {
+	case !validHTTPVersion(parts[0]):
+		return ck.note(AnomalyInvalidStatusLine, "version %q", parts[0])
+	case len(parts[1]) != 3 || parts[1][0] == '+' || parts[1][0] == '-':
+		return ck.note(AnomalyInvalidStatusLine, "status code %q", parts[1])
+	case len(parts) == 2:
+		return ck.note(AnomalyInvalidStatusLine, "no space after the status code in %q", statusLine)
+	}
 	return nil
 }
 
-func (c *Client) readHeaders(reader *bufio.Reader, raw *buffer.Buffer) (map[string][]string, error) {
+// validHTTPVersion reports whether v is "HTTP/" DIGIT "." DIGIT.
+func validHTTPVersion(v string) bool {
+	return len(v) == 8 && strings.HasPrefix(v, "HTTP/") &&
+		v[5] >= '0' && v[5] <= '9' && v[6] == '.' && v[7] >= '0' && v[7] <= '9'
+}
+
+// isDigits reports whether s is a non-empty run of DIGIT (base 10) or HEXDIG
+// (base 16), without the sign strconv.ParseInt would accept.
+func isDigits(s string, base int) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= '0' && c <= '9':
+		case base == 16 && (c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// readHeaders reads header lines up to the blank line. It returns the canonical
+// header map and the exact header lines in wire order (see HeaderField).
+func (c *Client) readHeaders(reader *bufio.Reader, raw *buffer.Buffer, ck *checker) (map[string][]string, []HeaderField, error) {
 	headers := make(map[string][]string)
-	total := 0
+	var list []HeaderField
 	var lastKey string
 
 	for {
-		line, err := reader.ReadString('\n')
-		if err != nil {
-			return nil, errors.NewProtocolError("reading headers", err)
+		line, err := ck.head.line(reader)
+		if errors.IsLimitError(err) {
+			raw.Write([]byte(line))
+			return headers, list, err
 		}
-
-		total += len(line)
-		if total > maxHeaderBytes {
-			return nil, errors.NewProtocolError("headers exceed maximum size", nil)
+		if err != nil {
+			return nil, nil, errors.NewProtocolError("reading headers", err)
 		}
 
 		if _, err := raw.Write([]byte(line)); err != nil {
-			return nil, err
+			return nil, nil, err
+		}
+		if err := ck.lineEnding(line, "header line"); err != nil {
+			return nil, nil, err
 		}
 
-		if line == "\r\n" {
+		if line == "\r\n" || line == "\n" {
 			break
 		}
 
@@ -749,6 +1168,10 @@ func (c *Client) readHeaders(reader *bufio.Reader, raw *buffer.Buffer) (map[stri
 
 		// Handle header continuation (RFC 7230 Section 3.2.4)
 		if strings.HasPrefix(trimmed, " ") || strings.HasPrefix(trimmed, "\t") {
+			if err := ck.note(AnomalyObsFold, "%q", trimmed); err != nil {
+				return nil, nil, err
+			}
+			list = append(list, HeaderField{Value: strings.TrimSpace(trimmed), RawLine: line})
 			if lastKey == "" {
 				continue
 			}
@@ -758,27 +1181,52 @@ func (c *Client) readHeaders(reader *bufio.Reader, raw *buffer.Buffer) (map[stri
 			continue
 		}
 
+		if err := ck.head.field(); err != nil {
+			return headers, list, err
+		}
+
 		// Parse header
 		parts := strings.SplitN(trimmed, ":", 2)
 		if len(parts) != 2 {
+			if err := ck.note(AnomalyMalformedHeader, "%q", trimmed); err != nil {
+				return nil, nil, err
+			}
+			list = append(list, HeaderField{Value: trimmed, RawLine: line})
 			continue
 		}
+		if strings.TrimRight(parts[0], " \t") != parts[0] {
+			if err := ck.note(AnomalyWhitespaceBeforeColon, "%q", trimmed); err != nil {
+				return nil, nil, err
+			}
+		}
 
+		list = append(list, HeaderField{Name: parts[0], Value: strings.TrimSpace(parts[1]), RawLine: line})
 		key := textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(parts[0]))
 		value := strings.TrimSpace(parts[1])
 		headers[key] = append(headers[key], value)
 		lastKey = key
 	}
 
-	return headers, nil
+	return headers, list, nil
 }
 
-func (c *Client) readBody(reader *bufio.Reader, response *Response, headers map[string][]string, reusable *bool) error {
+// bodyFraming describes how the end of a response body is determined.
+type bodyFraming int
+
+const (
+	framingNone    bodyFraming = iota // no body follows the head
+	framingChunked                    // Transfer-Encoding: chunked
+	framingLength                     // Content-Length delimited
+	framingClose                      // delimited by connection close
+)
+
+// determineFraming decides how the body following the response head is framed.
+// For framingLength the declared length is returned as well.
+func (c *Client) determineFraming(reader *bufio.Reader, response *Response, headers map[string][]string, ck *checker) (bodyFraming, int64, error) {
 	statusCode := response.StatusCode
 	method := response.Method
 	transferEncoding := c.getHeaderValue(headers, "Transfer-Encoding")
 	contentLength := c.getHeaderValue(headers, "Content-Length")
-	connectionHeader := c.getHeaderValue(headers, "Connection")
 
 	// RFC 9110 Section 6.4.1: Responses that MUST NOT have a message body
 	// "All 1xx (Informational), 204 (No Content), and 304 (Not Modified) responses
@@ -796,38 +1244,116 @@ func (c *Client) readBody(reader *bufio.Reader, response *Response, headers map[
 		statusCode == 204 || // No Content
 		statusCode == 304 { // Not Modified
 
-		// Check if there's actually buffered data available (peek without consuming)
-		if buffered := reader.Buffered(); buffered > 0 {
+		// Check if there's actually buffered data available (peek without consuming).
+		// Buffered data that begins like a status line is the next pipelined
+		// response (see DoPipelined), not a body.
+		if buffered := reader.Buffered(); buffered > 0 && !startsStatusLine(reader, buffered) {
 			// Server sent data despite RFC saying not to
 			// This is an RFC violation, but we capture it anyway (raw HTTP library behavior)
 			// Fall through to normal body reading logic
+			what := fmt.Sprintf("status %d", statusCode)
+			if method == "HEAD" {
+				what = "a HEAD request"
+			}
+			if err := ck.note(AnomalyUnexpectedBody, "%d bytes followed the head of a response to %s", buffered, what); err != nil {
+				return framingNone, 0, err
+			}
 		} else {
 			// No buffered data = RFC-compliant server
 			// Skip body reading to prevent timeout on keep-alive connections
 			// (Server sent Content-Length for informational purposes only)
-			return nil
+			return framingNone, 0, nil
+		}
+	}
+
+	// RFC 9112 Section 6.3: Transfer-Encoding overrides Content-Length, and a
+	// list of Content-Length values is only acceptable if they are all the same.
+	if transferEncoding != "" && contentLength != "" {
+		if err := ck.note(AnomalyConflictingFraming, "Transfer-Encoding %q with Content-Length %q", transferEncoding, contentLength); err != nil {
+			return framingNone, 0, err
 		}
 	}
+	if values := contentLengthValues(headers); len(values) > 1 {
+		if err := ck.note(AnomalyDuplicateContentLength, "%q", values); err != nil {
+			return framingNone, 0, err
+		}
+		contentLength = values[0]
+	}
 
 	switch {
 	case strings.Contains(strings.ToLower(transferEncoding), "chunked"):
-		return c.readChunkedBody(reader, response.Body, response.Raw, response.Headers, reusable)
+		return framingChunked, 0, nil
 	case contentLength != "":
-		length, err := strconv.ParseInt(strings.TrimSpace(contentLength), 10, 64)
+		// Content-Length = 1*DIGIT; ParseInt alone would also accept a sign
+		value := strings.TrimSpace(contentLength)
+		if !isDigits(value, 10) {
+			if err := ck.note(AnomalyInvalidContentLength, "%q", value); err != nil {
+				return framingNone, 0, err
+			}
+		}
+		length, err := strconv.ParseInt(value, 10, 64)
 		if err != nil {
-			return errors.NewProtocolError("invalid content-length", err)
+			return framingNone, 0, errors.NewProtocolError("invalid content-length", err)
 		}
 		// Protect against negative or excessively large Content-Length
 		if length < 0 {
-			return errors.NewProtocolError("negative content-length not allowed", nil)
+			return framingNone, 0, errors.NewProtocolError("negative content-length not allowed", nil)
 		}
 		// Reasonable limit: 1TB (can be adjusted based on needs)
 		if length > 1024*1024*1024*1024 {
-			return errors.NewProtocolError("content-length too large", nil)
+			return framingNone, 0, errors.NewProtocolError("content-length too large", nil)
 		}
-		return c.readFixedBody(reader, length, response.Body, response.Raw, reusable)
+		return framingLength, length, nil
 	default:
-		return c.readUntilClose(reader, connectionHeader, response.Body, response.Raw, reusable)
+		return framingClose, 0, nil
+	}
+}
+
+// startsStatusLine reports whether the buffered bytes begin (or could begin, when
+// fewer than five are buffered) with an HTTP/1.x status line.
+func startsStatusLine(reader *bufio.Reader, buffered int) bool {
+	const prefix = "HTTP/"
+	b, _ := reader.Peek(min(buffered, len(prefix)))
+	return strings.HasPrefix(prefix, string(b))
+}
+
+// contentLengthValues lists every Content-Length value, splitting comma-separated
+// lists (RFC 9110 Section 8.6).
+func contentLengthValues(headers map[string][]string) []string {
+	var values []string
+	for _, v := range headers["Content-Length"] {
+		for _, part := range strings.Split(v, ",") {
+			values = append(values, strings.TrimSpace(part))
+		}
+	}
+	return values
+}
+
+func (c *Client) readBody(reader *bufio.Reader, response *Response, headers map[string][]string, reusable *bool, timer *timing.Timer, ck *checker) error {
+	framing, length, err := c.determineFraming(reader, response, headers, ck)
+	if err != nil {
+		return err
+	}
+
+	// Time the body from its first byte on the wire (a chunk size line for chunked
+	// bodies). A failed peek is left to the body readers to report.
+	if framing == framingChunked || framing == framingClose || (framing == framingLength && length > 0) {
+		if _, err := reader.Peek(1); err == nil {
+			timer.StartBody()
+			defer timer.EndBody()
+		}
+	}
+
+	switch framing {
+	case framingChunked:
+		response.Trailers = make(map[string][]string)
+		return c.readChunkedBody(reader, response.Body, response.Raw, response.Trailers, reusable, ck)
+	case framingLength:
+		return c.readFixedBody(reader, length, response.Body, response.Raw, reusable, ck)
+	case framingClose:
+		return c.readUntilClose(reader, c.getHeaderValue(headers, "Connection"), response.Body, response.Raw, reusable, ck)
+	default:
+		return nil
 	}
 }
 
@@ -841,8 +1367,42 @@ func (c *Client) getHeaderValue(headers map[string][]string, key string) string
 	return ""
 }
 
-func (c *Client) readChunkedBody(r *bufio.Reader, dst, raw *buffer.Buffer, headers map[string][]string, reusable *bool) error {
-	tp := textproto.NewReader(r)
+// chunkSize checks a chunk size line, including its terminator, and returns the
+// chunk size. The streaming chunkedReader reports the same anomalies.
+func (c *checker) chunkSize(rawLine string) (int64, error) {
+	if err := c.lineEnding(rawLine, "chunk size line"); err != nil {
+		return 0, err
+	}
+	if len(rawLine) > maxChunkLineBytes {
+		if err := c.note(AnomalyOversizeChunkLine, "%d bytes", len(rawLine)); err != nil {
+			return 0, err
+		}
+	}
+
+	// chunk = chunk-size [ chunk-ext ] CRLF; BWS is only allowed before an extension
+	line := trimEOL(rawLine)
+	sizeField, ext, hasExt := strings.Cut(line, ";")
+	if hasExt {
+		if err := c.note(AnomalyChunkExtension, "%q", ";"+ext); err != nil {
+			return 0, err
+		}
+	}
+	sizeText := strings.TrimSpace(sizeField)
+	padded := sizeText != sizeField && (!hasExt || strings.TrimLeft(sizeField, " \t") != sizeField)
+	if padded || !isDigits(sizeText, 16) {
+		if err := c.note(AnomalyInvalidChunkSize, "%q", line); err != nil {
+			return 0, err
+		}
+	}
+
+	size, err := strconv.ParseInt(sizeText, 16, 64)
+	if err != nil || size < 0 {
+		return 0, errors.NewProtocolError("invalid chunk size", err)
+	}
+	return size, nil
+}
+
+func (c *Client) readChunkedBody(r *bufio.Reader, dst, raw *buffer.Buffer, trailers map[string][]string, reusable *bool, ck *checker) error {
 	// acceptTruncated reports whether a read error after we already received chunk
 	// data should be treated as a truncated-but-usable body rather than a hard
 	// failure. Servers/proxies that RST right after the last data chunk (before the
@@ -854,83 +1414,133 @@ func (c *Client) readChunkedBody(r *bufio.Reader, dst, raw *buffer.Buffer, heade
 		}
 		return err == io.EOF || err == io.ErrUnexpectedEOF || isStaleConnectionError(err)
 	}
+	truncated := func() error {
+		*reusable = false
+		return ck.note(AnomalyBodyTooShort, "chunked body ended without the last chunk after %d bytes", dst.Size())
+	}
+	body := ck.limits.bodyWriter(dst, raw)
 	for {
-		line, err := tp.ReadLine()
+		rawLine, err := ck.limits.readChunkLine(r)
+		if errors.IsLimitError(err) {
+			*reusable = false
+			raw.Write([]byte(rawLine))
+			return err
+		}
 		if err != nil {
 			if acceptTruncated(err) {
-				*reusable = false
-				return nil
+				return truncated()
 			}
 			return errors.NewProtocolError("reading chunk size", err)
 		}
 
-		if _, err := raw.Write([]byte(line + "\r\n")); err != nil {
+		if _, err := raw.Write([]byte(rawLine)); err != nil {
 			return err
 		}
-
-		size, err := strconv.ParseInt(strings.TrimSpace(strings.Split(line, ";")[0]), 16, 64)
+		size, err := ck.chunkSize(rawLine)
 		if err != nil {
-			return errors.NewProtocolError("invalid chunk size", err)
+			return err
 		}
 
 		if size == 0 {
 			break
 		}
 
-		if _, err := io.CopyN(io.MultiWriter(dst, raw), tp.R, size); err != nil {
-			if acceptTruncated(err) {
+		if _, err := io.CopyN(body, r, size); err != nil {
+			if errors.IsLimitError(err) {
 				*reusable = false
-				return nil
+				return err
+			}
+			if acceptTruncated(err) {
+				return truncated()
 			}
 			return errors.NewIOError("reading chunk body", err)
 		}
 
-		crlf := make([]byte, 2)
-		if _, err := io.ReadFull(tp.R, crlf); err != nil {
+		// The chunk data must be followed by CRLF. Anything else is left in place
+		// to be read as the next chunk size line.
+		next, err := r.Peek(2)
+		switch {
+		case len(next) > 0 && next[0] == '\n':
+			if _, err := raw.Write([]byte("\n")); err != nil {
+				return err
+			}
+			r.Discard(1)
+			if err := ck.note(AnomalyBareLF, "after chunk data"); err != nil {
+				return err
+			}
+		case err != nil:
 			if acceptTruncated(err) {
-				*reusable = false
-				return nil
+				return truncated()
 			}
 			return errors.NewIOError("reading chunk CRLF", err)
-		}
-
-		if _, err := raw.Write(crlf); err != nil {
-			return err
+		case string(next) == "\r\n":
+			if _, err := raw.Write(next); err != nil {
+				return err
+			}
+			r.Discard(2)
+		default:
+			if err := ck.note(AnomalyMissingChunkCRLF, "chunk data followed by %q", next); err != nil {
+				return err
+			}
 		}
 	}
 
-	// Read trailers
+	// Read trailers, charged to the header limits like a response head
+	ck.startHead()
 	for {
-		line, err := tp.ReadLine()
+		rawLine, err := ck.head.line(r)
+		if errors.IsLimitError(err) {
+			*reusable = false
+			raw.Write([]byte(rawLine))
+			return err
+		}
 		if err != nil {
 			return errors.NewProtocolError("reading chunk trailer", err)
 		}
 
-		if _, err := raw.Write([]byte(line + "\r\n")); err != nil {
+		if _, err := raw.Write([]byte(rawLine)); err != nil {
+			return err
+		}
+		if err := ck.lineEnding(rawLine, "trailer line"); err != nil {
 			return err
 		}
 
+		line := trimEOL(rawLine)
 		if line == "" {
 			break
 		}
 
-		// Parse trailer header and add to headers map
-		if parts := strings.SplitN(line, ":", 2); len(parts) == 2 {
-			key := textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(parts[0]))
-			value := strings.TrimSpace(parts[1])
-			headers[key] = append(headers[key], value)
+		if err := ck.head.field(); err != nil {
+			*reusable = false
+			return err
 		}
+
+		// Parse trailer field into the trailers map
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			if err := ck.note(AnomalyMalformedHeader, "trailer %q", line); err != nil {
+				return err
+			}
+			continue
+		}
+		key := textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(parts[0]))
+		value := strings.TrimSpace(parts[1])
+		trailers[key] = append(trailers[key], value)
 	}
 
 	return nil
 }
 
-func (c *Client) readFixedBody(r *bufio.Reader, length int64, dst, raw *buffer.Buffer, reusable *bool) error {
+func (c *Client) readFixedBody(r *bufio.Reader, length int64, dst, raw *buffer.Buffer, reusable *bool, ck *checker) error {
 	if length <= 0 {
 		return nil
 	}
 
-	_, err := io.CopyN(io.MultiWriter(dst, raw), r, length)
+	n, err := io.CopyN(ck.limits.bodyWriter(dst, raw), r, length)
+	if errors.IsLimitError(err) {
+		*reusable = false
+		return err
+	}
 	if err != nil {
 		// As a RAW HTTP library, we need to handle Content-Length mismatches gracefully
 		// Some servers send incorrect Content-Length headers (RFC violation)
@@ -940,7 +1550,7 @@ func (c *Client) readFixedBody(r *bufio.Reader, length int64, dst, raw *buffer.B
 			// in an ambiguous/half-drained state: it MUST NOT be returned to the pool,
 			// or the next request would read leftover bytes as its status line.
 			*reusable = false
-			return nil
+			return ck.note(AnomalyBodyTooShort, "%d of %d bytes", n, length)
 		}
 		return errors.NewIOError("reading fixed body", err)
 	}
@@ -952,18 +1562,24 @@ func (c *Client) readFixedBody(r *bufio.Reader, length int64, dst, raw *buffer.B
 		// cases the framing is ambiguous for a raw client, so do not reuse the socket;
 		// we cannot guarantee the read position aligns with the next response.
 		*reusable = false
+		if !startsStatusLine(r, buffered) {
+			return ck.note(AnomalyBodyTooLong, "at least %d bytes followed the %d-byte body", buffered, length)
+		}
 	}
 
 	return nil
 }
 
-func (c *Client) readUntilClose(r *bufio.Reader, connectionHeader string, dst, raw *buffer.Buffer, reusable *bool) error {
+func (c *Client) readUntilClose(r *bufio.Reader, connectionHeader string, dst, raw *buffer.Buffer, reusable *bool, ck *checker) error {
 	// A response framed by connection close consumes the connection: once we read to
 	// EOF the socket is dead by definition, so it must never be returned to the pool.
 	_ = connectionHeader
 	*reusable = false
 
-	_, err := io.Copy(io.MultiWriter(dst, raw), r)
+	_, err := io.Copy(ck.limits.bodyWriter(dst, raw), r)
+	if errors.IsLimitError(err) {
+		return err
+	}
 	if err != nil && err != io.EOF {
 		// A close-delimited body is complete precisely when the connection ends, so
 		// an RST/ECONNRESET at the end is equivalent to a clean FIN for framing: keep
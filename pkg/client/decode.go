@@ -0,0 +1,246 @@
+package client
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/WhileEndless/go-rawhttp/pkg/buffer"
+	"github.com/WhileEndless/go-rawhttp/pkg/errors"
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// defaultMaxDecodedBytes bounds a decoded body when Options.MaxDecodedBytes is unset.
+const defaultMaxDecodedBytes = 100 * 1024 * 1024
+
+// DecodeResponse removes the Content-Encoding of a response body when
+// opts.DecodeContent is set; Do and DoStream call it for every response. Stacked
+// codings (e.g. "gzip, br") are removed in reverse order of application. For a
+// buffered body the result goes to DecodedBody; a BodyStream is replaced by a
+// stream that decodes lazily as it is read. Body and Raw are never modified.
+//
+// Decoding problems do not fail the request: they are reported in DecodeError
+// (an unsupported coding or corrupt data), and output beyond MaxDecodedBytes is
+// cut off with a limit error.
+func DecodeResponse(response *Response, opts Options) {
+	if !opts.DecodeContent || response == nil {
+		return
+	}
+	codings := contentCodings(response)
+	if len(codings) == 0 {
+		return
+	}
+	max := opts.MaxDecodedBytes
+	if max <= 0 {
+		max = defaultMaxDecodedBytes
+	}
+
+	if response.BodyStream != nil {
+		response.BodyStream = &decodingStream{src: response.BodyStream, codings: codings, max: max}
+		response.ContentDecoded = codings
+		return
+	}
+	if response.Body == nil || response.Body.Size() == 0 {
+		return
+	}
+
+	body, err := response.Body.Reader()
+	if err != nil {
+		response.DecodeError = err
+		return
+	}
+	defer body.Close()
+	dec, err := newDecoder(body, codings, max)
+	if err != nil {
+		response.DecodeError = err
+		return
+	}
+	defer dec.Close()
+
+	out := buffer.New(opts.BodyMemLimit)
+	if _, err := io.Copy(out, &limitReader{r: dec, n: max, max: max, limit: "MaxDecodedBytes"}); err != nil {
+		response.DecodeError = err
+	}
+	response.DecodedBody = out
+	response.ContentDecoded = codings
+}
+
+// contentCodings lists the codings named by the Content-Encoding header(s) in
+// the order they were applied, identity excluded. The lines are taken from
+// HeaderList, so repeated headers keep their wire order whatever their case;
+// a response built without one falls back to Headers, names in sorted order.
+func contentCodings(response *Response) []string {
+	var values []string
+	if len(response.HeaderList) > 0 {
+		for _, f := range response.HeaderList {
+			if strings.EqualFold(f.Name, "Content-Encoding") {
+				values = append(values, f.Value)
+			}
+		}
+	} else {
+		names := make([]string, 0, len(response.Headers))
+		for name := range response.Headers {
+			if strings.EqualFold(name, "Content-Encoding") {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			values = append(values, response.Headers[name]...)
+		}
+	}
+
+	var codings []string
+	for _, v := range values {
+		for _, c := range strings.Split(v, ",") {
+			if c = strings.ToLower(strings.TrimSpace(c)); c != "" && c != "identity" {
+				codings = append(codings, c)
+			}
+		}
+	}
+	return codings
+}
+
+// decoder reads through a chain of decompressors and closes them all.
+type decoder struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (d *decoder) Close() error {
+	for _, c := range d.closers {
+		c.Close()
+	}
+	return nil
+}
+
+// newDecoder returns a reader that removes codings, applied in order, from r.
+// max is the decoded size limit, which also bounds decompressor memory.
+func newDecoder(r io.Reader, codings []string, max int64) (*decoder, error) {
+	d := &decoder{Reader: r}
+	for i := len(codings) - 1; i >= 0; i-- {
+		next, err := newCodingReader(d.Reader, codings[i], max)
+		if err != nil {
+			d.Close()
+			return nil, errors.NewProtocolError(fmt.Sprintf("decoding %s content", codings[i]), err)
+		}
+		d.Reader = next
+		if c, ok := next.(io.Closer); ok {
+			d.closers = append(d.closers, c)
+		}
+	}
+	return d, nil
+}
+
+func newCodingReader(r io.Reader, coding string, max int64) (io.Reader, error) {
+	switch coding {
+	case "gzip", "x-gzip":
+		return gzip.NewReader(r)
+	case "deflate":
+		return newDeflateReader(r)
+	case "br":
+		return brotli.NewReader(r), nil
+	case "zstd":
+		// A frame's window is allocated up front and may be declared up to 512MB.
+		// Nothing beyond max is delivered, so max (plus a block, for the limit
+		// reader's probe past it) bounds the window, but never below the 8MB any
+		// HTTP zstd decoder must accept (RFC 9659).
+		mem := uint64(max) + zstdMaxBlockSize
+		if mem < zstdHTTPWindow {
+			mem = zstdHTTPWindow
+		}
+		window := min(mem, zstd.MaxWindowSize)
+		zr, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1),
+			zstd.WithDecoderMaxWindow(window), zstd.WithDecoderMaxMemory(mem))
+		if err != nil {
+			return nil, err
+		}
+		return &zstdReader{ReadCloser: zr.IOReadCloser(), max: max}, nil
+	}
+	return nil, fmt.Errorf("unsupported content coding %q", coding)
+}
+
+// zstdMaxBlockSize is the largest block a zstd frame can contain, and
+// zstdHTTPWindow the largest window an HTTP zstd encoder may use (RFC 9659).
+const (
+	zstdMaxBlockSize = 128 << 10
+	zstdHTTPWindow   = 8 << 20
+)
+
+// zstdReader reports a frame that needs more memory than the decoder allows as
+// the MaxDecodedBytes limit error.
+type zstdReader struct {
+	io.ReadCloser
+	max int64
+}
+
+func (z *zstdReader) Read(p []byte) (int, error) {
+	n, err := z.ReadCloser.Read(p)
+	if stderrors.Is(err, zstd.ErrWindowSizeExceeded) || stderrors.Is(err, zstd.ErrDecoderSizeExceeded) {
+		err = errors.NewLimitError("MaxDecodedBytes", z.max)
+	}
+	return n, err
+}
+
+// newDeflateReader reads "deflate" as specified (zlib, RFC 9110 Section 8.4.1.2)
+// and as some servers send it instead (raw DEFLATE without the zlib header).
+func newDeflateReader(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	if h, err := br.Peek(2); err == nil && h[0]&0x0f == 8 && (uint16(h[0])<<8|uint16(h[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
+
+// decodingStream decodes a streamed body. The decompressors are set up on the
+// first Read, so DoStream still returns as soon as the response head is read.
+type decodingStream struct {
+	src     io.ReadCloser
+	codings []string
+	max     int64
+
+	mu  sync.Mutex
+	dec *decoder
+	r   io.Reader
+	err error
+}
+
+func (d *decodingStream) Read(p []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.err != nil {
+		return 0, d.err
+	}
+	if d.r == nil {
+		dec, err := newDecoder(d.src, d.codings, d.max)
+		if stderrors.Is(err, io.EOF) {
+			err = io.EOF // no body at all (e.g. a HEAD response)
+		}
+		if err != nil {
+			d.err = err
+			return 0, err
+		}
+		d.dec = dec
+		d.r = &limitReader{r: dec, n: d.max, max: d.max, limit: "MaxDecodedBytes"}
+	}
+	return d.r.Read(p)
+}
+
+// Close closes the decompressors and the underlying body stream.
+func (d *decodingStream) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.dec != nil {
+		d.dec.Close()
+	}
+	return d.src.Close()
+}
@@ -0,0 +1,103 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	stderrors "errors"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/WhileEndless/go-rawhttp/pkg/errors"
+	"github.com/WhileEndless/go-rawhttp/pkg/transport"
+)
+
+// expectContinue tracks a request body held back by the Expect: 100-continue
+// handshake. All methods are safe on a nil receiver (no handshake in progress).
+type expectContinue struct {
+	body         []byte
+	deadline     time.Time // stop waiting for 100 Continue and send the body anyway
+	writeTimeout time.Duration
+	pending      bool // body still held back
+	sent         bool // body was written
+	trace        *transport.ClientTrace
+}
+
+func newExpectContinue(body []byte, timeout, writeTimeout time.Duration) *expectContinue {
+	return &expectContinue{
+		body:         body,
+		deadline:     time.Now().Add(timeout),
+		writeTimeout: writeTimeout,
+		pending:      true,
+	}
+}
+
+// splitExpectContinue splits req into head and body when its headers carry
+// "Expect: 100-continue" and a body follows. ok is false otherwise.
+func splitExpectContinue(req []byte) (head, body []byte, ok bool) {
+	end := bytes.Index(req, []byte("\r\n\r\n"))
+	if end < 0 || end+4 == len(req) {
+		return nil, nil, false
+	}
+
+	lines := strings.Split(string(req[:end]), "\r\n")
+	for _, line := range lines[1:] {
+		name, value, found := strings.Cut(line, ":")
+		if found && strings.EqualFold(strings.TrimSpace(name), "Expect") &&
+			strings.EqualFold(strings.TrimSpace(value), "100-continue") {
+			return req[:end+4], req[end+4:], true
+		}
+	}
+	return nil, nil, false
+}
+
+// awaiting reports whether the body is still held back.
+func (ec *expectContinue) awaiting() bool {
+	return ec != nil && ec.pending
+}
+
+// withheld reports whether a handshake ended without the body being sent.
+func (ec *expectContinue) withheld() bool {
+	return ec != nil && !ec.sent
+}
+
+// abandon gives up on sending the body (e.g. the head could not be written).
+func (ec *expectContinue) abandon() {
+	if ec != nil {
+		ec.pending = false
+	}
+}
+
+// send writes the held-back body.
+func (ec *expectContinue) send(c *Client, conn net.Conn) error {
+	ec.pending = false
+	ec.sent = true
+	err := c.sendRequest(conn, ec.body, ec.writeTimeout)
+	ec.trace.TraceWroteRequest(err)
+	return err
+}
+
+// await blocks until response bytes are available or the expect timeout elapses,
+// in which case the body is sent without waiting any longer. Other read errors
+// (EOF, reset) recur on the caller's next read and are reported there. headDeadline is the regular
+// read deadline of the response head and is restored afterwards.
+func (ec *expectContinue) await(c *Client, conn net.Conn, reader *bufio.Reader, headDeadline time.Time) error {
+	deadline := ec.deadline
+	if !headDeadline.IsZero() && headDeadline.Before(deadline) {
+		deadline = headDeadline
+	}
+	if err := conn.SetReadDeadline(deadline); err != nil {
+		return errors.NewIOError("setting read deadline", err)
+	}
+	_, peekErr := reader.Peek(1)
+	if err := conn.SetReadDeadline(headDeadline); err != nil {
+		return errors.NewIOError("setting read deadline", err)
+	}
+
+	var netErr net.Error
+	if peekErr != nil && stderrors.As(peekErr, &netErr) && netErr.Timeout() && deadline.Equal(ec.deadline) {
+		// The server stayed silent: proceed with the body.
+		return ec.send(c, conn)
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package client
+
+import (
+	"bufio"
+	stderrors "errors"
+	"io"
+
+	"github.com/WhileEndless/go-rawhttp/pkg/errors"
+)
+
+// limits are the response size limits of Options with defaults applied. A
+// negative value means unlimited.
+type limits struct {
+	headerBytes int
+	headerCount int
+	bodyBytes   int64
+	chunkLine   int
+}
+
+func newLimits(opts Options) limits {
+	l := limits{headerBytes: maxHeaderBytes, headerCount: -1, bodyBytes: -1, chunkLine: -1}
+	if opts.MaxHeaderBytes > 0 {
+		l.headerBytes = opts.MaxHeaderBytes
+	}
+	if opts.MaxHeaderCount > 0 {
+		l.headerCount = opts.MaxHeaderCount
+	}
+	if opts.MaxBodyBytes > 0 {
+		l.bodyBytes = opts.MaxBodyBytes
+	}
+	if opts.MaxChunkLineLength > 0 {
+		l.chunkLine = opts.MaxChunkLineLength
+	}
+	return l
+}
+
+// errLineTooLong is returned by readLimitedLine for a line exceeding its bound.
+var errLineTooLong = stderrors.New("line too long")
+
+// readLimitedLine reads a line including its terminator, like ReadString('\n'),
+// without buffering more than max bytes of it (unbounded when max < 0). A longer
+// line fails with errLineTooLong; the bytes consumed so far are returned with it.
+func readLimitedLine(r *bufio.Reader, max int) (string, error) {
+	var line []byte
+	for {
+		frag, err := r.ReadSlice('\n')
+		line = append(line, frag...)
+		if max >= 0 && len(line) > max {
+			return string(line), errLineTooLong
+		}
+		if err != bufio.ErrBufferFull {
+			return string(line), err
+		}
+	}
+}
+
+// headBudget charges the lines and fields of one response head (or trailer
+// section) to MaxHeaderBytes and MaxHeaderCount.
+type headBudget struct {
+	limits *limits
+	bytes  int
+	fields int
+}
+
+// line reads the next line of the head. On a limit error the line read so far
+// is returned with it.
+func (h *headBudget) line(r *bufio.Reader) (string, error) {
+	line, err := readLimitedLine(r, h.limits.headerBytes-h.bytes)
+	h.bytes += len(line)
+	if err == errLineTooLong {
+		err = errors.NewLimitError("MaxHeaderBytes", int64(h.limits.headerBytes))
+	}
+	return line, err
+}
+
+// field counts a header field.
+func (h *headBudget) field() error {
+	h.fields++
+	if h.limits.headerCount >= 0 && h.fields > h.limits.headerCount {
+		return errors.NewLimitError("MaxHeaderCount", int64(h.limits.headerCount))
+	}
+	return nil
+}
+
+// readChunkLine reads a chunk size line bounded by MaxChunkLineLength.
+func (l *limits) readChunkLine(r *bufio.Reader) (string, error) {
+	line, err := readLimitedLine(r, l.chunkLine)
+	if err == errLineTooLong {
+		err = errors.NewLimitError("MaxChunkLineLength", int64(l.chunkLine))
+	}
+	return line, err
+}
+
+// bodyWriter returns the writer for the decoded body bytes of one response:
+// dst and raw, cut off at MaxBodyBytes.
+func (l *limits) bodyWriter(dst, raw io.Writer) io.Writer {
+	w := io.MultiWriter(dst, raw)
+	if l.bodyBytes < 0 {
+		return w
+	}
+	return &limitWriter{w: w, n: l.bodyBytes, max: l.bodyBytes}
+}
+
+// limitWriter passes the first n bytes written to w and fails the write that
+// goes beyond them with a MaxBodyBytes limit error.
+type limitWriter struct {
+	w      io.Writer
+	n, max int64
+}
+
+func (l *limitWriter) Write(p []byte) (int, error) {
+	if int64(len(p)) <= l.n {
+		n, err := l.w.Write(p)
+		l.n -= int64(n)
+		return n, err
+	}
+	n, err := l.w.Write(p[:l.n])
+	l.n -= int64(n)
+	if err == nil {
+		err = errors.NewLimitError("MaxBodyBytes", l.max)
+	}
+	return n, err
+}
+
+// limitReader delivers the first n bytes of a streamed body and then fails
+// with a limit error for the named limit if the body goes on.
+type limitReader struct {
+	r      io.Reader
+	n, max int64
+	limit  string
+}
+
+func (l *limitReader) Read(p []byte) (int, error) {
+	if l.n <= 0 {
+		// Probe for one more byte to tell a body of exactly max bytes from a longer one.
+		var b [1]byte
+		n, err := l.r.Read(b[:])
+		if n > 0 {
+			return 0, errors.NewLimitError(l.limit, l.max)
+		}
+		return 0, err
+	}
+	if int64(len(p)) > l.n {
+		p = p[:l.n]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	return n, err
+}
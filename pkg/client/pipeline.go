@@ -0,0 +1,168 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"time"
+
+	"github.com/WhileEndless/go-rawhttp/pkg/errors"
+	"github.com/WhileEndless/go-rawhttp/pkg/timing"
+)
+
+// PipelineResult is the outcome of DoPipelined.
+type PipelineResult struct {
+	// Responses holds the parsed responses in request order. It is shorter than
+	// the request list when reading stopped early (see Err); the last entry may
+	// then be a response whose body could not be read completely.
+	Responses []*Response
+
+	// Unattributed holds received bytes that do not belong to any response in
+	// Responses: a response head that could not be parsed, or data received after
+	// the last expected response (e.g. an extra response from a server that
+	// split a request in two), read until the connection closes or
+	// pipelineDrainTimeout elapses.
+	Unattributed []byte
+
+	// Err is why reading stopped before every request was answered (nil when
+	// all responses were read)
+	Err error
+}
+
+// After the last expected response DoPipelined keeps reading for up to
+// pipelineDrainTimeout (within the read deadline), and at most
+// pipelineDrainBytes, so that data the server sends separately is attributed.
+const (
+	pipelineDrainTimeout = 250 * time.Millisecond
+	pipelineDrainBytes   = 1 << 20
+)
+
+// DoPipelined sends all requests on a single new HTTP/1.1 connection without
+// waiting for responses (pipelining), then parses the responses sequentially.
+// Each request is written verbatim, one Write per request, or all with a single
+// Write when Options.PipelineSinglePacket is set.
+//
+// Every response carries its own Raw bytes and Timings; TTFB is measured from the
+// end of the request write to the first byte of that response. The connection is
+// never pooled. The returned error is only set when the requests are invalid or
+// the connection cannot be established; failures after that are reported in
+// PipelineResult.Err along with the responses read so far.
+func (c *Client) DoPipelined(ctx context.Context, reqs [][]byte, opts Options) (*PipelineResult, error) {
+	if c.transport == nil {
+		return nil, errors.NewValidationError("client transport is nil")
+	}
+	if len(reqs) == 0 {
+		return nil, errors.NewValidationError("no requests to pipeline")
+	}
+	for _, req := range reqs {
+		if len(req) == 0 {
+			return nil, errors.NewValidationError("request cannot be empty")
+		}
+	}
+
+	timer := timing.NewTimer()
+	cfg := newTransportConfig(opts)
+	cfg.ReuseConnection = false
+
+	conn, connMetadata, err := c.transport.Connect(ctx, cfg, timer)
+	if err != nil {
+		return nil, err
+	}
+	defer c.transport.CloseConnectionWithMetadata(opts.Host, opts.Port, conn, connMetadata)
+
+	// Write every request before reading anything. A failed write does not end
+	// the exchange: the server may already have answered the requests it got.
+	timer.StartWrite()
+	var werr error
+	if opts.PipelineSinglePacket {
+		var payload []byte
+		for _, req := range reqs {
+			payload = append(payload, req...)
+		}
+		werr = c.sendRequest(conn, payload, opts.WriteTimeout)
+	} else {
+		for _, req := range reqs {
+			if werr = c.sendRequest(conn, req, opts.WriteTimeout); werr != nil {
+				break
+			}
+		}
+	}
+	timer.EndWrite()
+	if werr == nil {
+		opts.Trace.TraceWroteHeaders()
+	}
+	opts.Trace.TraceWroteRequest(werr)
+
+	result := &PipelineResult{}
+
+	// One deadline bounds the whole exchange; the responses are read back to back.
+	deadline, _ := readDeadline(opts)
+	if !deadline.IsZero() {
+		if err := conn.SetReadDeadline(deadline); err != nil {
+			result.Err = errors.NewIOError("setting read deadline", err)
+			return result, nil
+		}
+	}
+
+	reader := bufio.NewReader(conn)
+	timer.StartTTFB()
+	for _, req := range reqs {
+		// Each response shares the connection phases and gets its own TTFB and body.
+		rt := *timer
+		response := newResponse(parseMethod(req), opts, connMetadata)
+
+		if err := c.readHead(conn, reader, response, opts, &rt, nil, time.Time{}); err != nil {
+			// Head bytes never exceed the memory limit, so Raw holds them all.
+			result.Unattributed = append(append([]byte(nil), response.Raw.Bytes()...), unread(reader)...)
+			response.Body.Close()
+			response.Raw.Close()
+			result.Err = err
+			return result, nil
+		}
+
+		reusable := true
+		err := c.readBody(reader, response, response.Headers, &reusable, &rt, newChecker(opts, response))
+		response.Timings = rt.GetMetrics()
+		response.BodyBytes = response.Body.Size()
+		response.RawBytes = response.Raw.Size()
+		result.Responses = append(result.Responses, response)
+		if err != nil {
+			result.Unattributed = unread(reader)
+			result.Err = err
+			return result, nil
+		}
+		DecodeResponse(response, opts)
+	}
+
+	result.Unattributed = drain(conn, reader, deadline)
+	return result, nil
+}
+
+// drain returns the bytes buffered in reader plus whatever arrives until the
+// connection closes or pipelineDrainTimeout elapses, bounded by deadline.
+func drain(conn net.Conn, reader *bufio.Reader, deadline time.Time) []byte {
+	until := time.Now().Add(pipelineDrainTimeout)
+	if !deadline.IsZero() && deadline.Before(until) {
+		until = deadline
+	}
+	if err := conn.SetReadDeadline(until); err != nil {
+		return unread(reader)
+	}
+	// The drain ends on EOF, the deadline or a reset alike; what arrived is kept.
+	b, _ := io.ReadAll(io.LimitReader(reader, pipelineDrainBytes))
+	if len(b) == 0 {
+		return nil
+	}
+	return b
+}
+
+// unread returns a copy of the bytes buffered in reader but not consumed.
+func unread(reader *bufio.Reader) []byte {
+	n := reader.Buffered()
+	if n == 0 {
+		return nil
+	}
+	b, _ := reader.Peek(n)
+	return append([]byte(nil), b...)
+}
@@ -0,0 +1,198 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/WhileEndless/go-rawhttp/pkg/errors"
+	"github.com/WhileEndless/go-rawhttp/pkg/timing"
+	"github.com/WhileEndless/go-rawhttp/pkg/transport"
+)
+
+// Session is a raw HTTP/1.1 exchange on exactly one connection, opened with
+// OpenSession. Requests and responses are matched in order: ReadResponse returns
+// the response to the oldest request that has not been answered yet. The
+// connection is never pooled and is closed by Close.
+//
+// WriteRaw and ReadResponse may be called from different goroutines (a writer and
+// a reader); Do runs a complete exchange and excludes concurrent reads.
+type Session struct {
+	client   *Client
+	conn     net.Conn
+	metadata *transport.ConnectionMetadata
+	opts     Options
+	reader   *bufio.Reader
+
+	wmu sync.Mutex // serializes writes
+	rmu sync.Mutex // serializes response reads
+
+	mu        sync.Mutex
+	methods   []string // methods of written requests not answered yet
+	exchanges int      // responses read so far
+	closed    bool
+}
+
+// OpenSession connects to the target described by opts (including TLS and any
+// proxy) and returns a Session bound to the new connection. The connection is
+// always new, whatever ReuseConnection says; ctx bounds the connect only.
+func (c *Client) OpenSession(ctx context.Context, opts Options) (*Session, error) {
+	if c.transport == nil {
+		return nil, errors.NewValidationError("client transport is nil")
+	}
+
+	cfg := newTransportConfig(opts)
+	cfg.ReuseConnection = false
+	conn, metadata, err := c.transport.Connect(ctx, cfg, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Session{
+		client:   c,
+		conn:     conn,
+		metadata: metadata,
+		opts:     opts,
+		reader:   bufio.NewReader(conn),
+	}, nil
+}
+
+// Do writes req and reads the next response: the response to req, unless requests
+// written earlier with WriteRaw are still unanswered (responses come in order).
+func (s *Session) Do(req []byte) (*Response, error) {
+	if len(req) == 0 {
+		return nil, errors.NewValidationError("request cannot be empty")
+	}
+
+	s.rmu.Lock()
+	defer s.rmu.Unlock()
+
+	timer := timing.NewTimer()
+	timer.StartWrite()
+	err := s.write(req)
+	timer.EndWrite()
+	if err != nil {
+		return nil, err
+	}
+	return s.readResponse(timer)
+}
+
+// WriteRaw writes p to the connection verbatim. p may hold one or more requests or
+// just part of one. When p starts with a request line, its method is remembered
+// so that the matching response (e.g. to HEAD) is read without a body.
+func (s *Session) WriteRaw(p []byte) error {
+	return s.write(p)
+}
+
+// ReadResponse reads the next response from the connection. Its timings start when
+// ReadResponse is called. When the server has closed the connection, the error
+// wraps io.EOF. If the body could not be read completely, or the response exceeds
+// a size limit, the partial response is returned along with the error.
+func (s *Session) ReadResponse() (*Response, error) {
+	s.rmu.Lock()
+	defer s.rmu.Unlock()
+	return s.readResponse(timing.NewTimer())
+}
+
+// ConnectionMetadata describes the session's connection (addresses, TLS, proxy).
+func (s *Session) ConnectionMetadata() *transport.ConnectionMetadata {
+	return s.metadata
+}
+
+// Close closes the connection. It is safe to call more than once.
+func (s *Session) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	s.client.transport.CloseConnectionWithMetadata(s.opts.Host, s.opts.Port, s.conn, s.metadata)
+	return nil
+}
+
+// write sends p and remembers the method of the request it starts with.
+func (s *Session) write(p []byte) error {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+
+	if err := s.checkOpen(); err != nil {
+		return err
+	}
+	if method := requestMethod(p); method != "" {
+		s.mu.Lock()
+		s.methods = append(s.methods, method)
+		s.mu.Unlock()
+	}
+
+	err := s.client.sendRequest(s.conn, p, s.opts.WriteTimeout)
+	if err == nil {
+		s.opts.Trace.TraceWroteHeaders()
+	}
+	s.opts.Trace.TraceWroteRequest(err)
+	return err
+}
+
+// readResponse reads the next response; the caller holds rmu.
+func (s *Session) readResponse(timer *timing.Timer) (*Response, error) {
+	if err := s.checkOpen(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	var method string
+	if len(s.methods) > 0 {
+		method = s.methods[0]
+		s.methods = s.methods[1:]
+	}
+	reused := s.exchanges > 0
+	s.exchanges++
+	s.mu.Unlock()
+
+	response := newResponse(method, s.opts, s.metadata)
+	if err := s.client.readResponseHead(s.conn, s.reader, response, s.opts, timer, nil); err != nil {
+		if errors.IsLimitError(err) {
+			response.Timings = timer.GetMetrics()
+			response.RawBytes = response.Raw.Size()
+			return response, err
+		}
+		response.Body.Close()
+		response.Raw.Close()
+		return nil, err
+	}
+	response.ConnectionReused = reused
+
+	// The connection is closed by the session, whatever the framing.
+	reusable := true
+	err := s.client.readBody(s.reader, response, response.Headers, &reusable, timer, newChecker(s.opts, response))
+	response.Timings = timer.GetMetrics()
+	response.BodyBytes = response.Body.Size()
+	response.RawBytes = response.Raw.Size()
+	if err == nil {
+		DecodeResponse(response, s.opts)
+	}
+	return response, err
+}
+
+func (s *Session) checkOpen() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return errors.NewIOError("using closed session", net.ErrClosed)
+	}
+	return nil
+}
+
+// requestMethod returns the method of the request line p starts with, or "" when
+// p does not start with one (e.g. a request body written separately).
+func requestMethod(p []byte) string {
+	line, _, _ := bytes.Cut(p, []byte("\n"))
+	fields := strings.Fields(string(line))
+	if len(fields) != 3 || !strings.HasPrefix(fields[2], "HTTP/") {
+		return ""
+	}
+	return parseMethod(p)
+}
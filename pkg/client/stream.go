@@ -0,0 +1,349 @@
+package client
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/WhileEndless/go-rawhttp/pkg/errors"
+	"github.com/WhileEndless/go-rawhttp/pkg/timing"
+	"github.com/WhileEndless/go-rawhttp/pkg/transport"
+)
+
+// bodyStream is the io.ReadCloser behind Response.BodyStream. It owns the
+// connection from the moment the response head was parsed until Close, and only
+// hands the connection back to the pool when the body was fully consumed per its
+// framing (the same invariant the buffered readers enforce via *reusable).
+type bodyStream struct {
+	client      *Client
+	conn        net.Conn
+	metadata    *transport.ConnectionMetadata
+	opts        Options
+	reader      *bufio.Reader
+	body        io.Reader // framing-aware reader over reader
+	readTimeout time.Duration
+
+	// The body is timed as it is read: response.Timings is refreshed at EOF.
+	response *Response
+	timer    *timing.Timer
+	started  bool
+
+	mu       sync.Mutex
+	reusable bool
+	eof      bool
+	closed   bool
+}
+
+// attachBodyStream wires up Response.BodyStream for the framing announced by the
+// response head. reusable carries any earlier decision that the connection must
+// not be pooled (e.g. a failed request write).
+func (c *Client) attachBodyStream(conn net.Conn, metadata *transport.ConnectionMetadata, reader *bufio.Reader, response *Response, opts Options, timer *timing.Timer, reusable bool) error {
+	ck := newChecker(opts, response)
+	framing, length, err := c.determineFraming(reader, response, response.Headers, ck)
+	if err != nil {
+		return err
+	}
+
+	bs := &bodyStream{
+		client:      c,
+		conn:        conn,
+		metadata:    metadata,
+		opts:        opts,
+		reader:      reader,
+		readTimeout: opts.ReadTimeout,
+		response:    response,
+		timer:       timer,
+		reusable:    reusable,
+	}
+
+	switch framing {
+	case framingChunked:
+		response.Trailers = make(map[string][]string)
+		bs.body = &chunkedReader{r: reader, trailers: response.Trailers, ck: ck}
+	case framingLength:
+		bs.body = &fixedReader{r: reader, remaining: length}
+	case framingClose:
+		// A close-delimited body consumes the connection by definition.
+		bs.reusable = false
+		bs.body = &closeReader{r: reader}
+	default:
+		bs.body = eofReader{}
+	}
+	if max := ck.limits.bodyBytes; max >= 0 {
+		bs.body = &limitReader{r: bs.body, n: max, max: max, limit: "MaxBodyBytes"}
+	}
+
+	response.BodyStream = bs
+	return nil
+}
+
+// Read reads de-framed body bytes. When ReadTimeout is set it bounds each
+// individual read (rolling deadline) rather than the whole body.
+func (b *bodyStream) Read(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return 0, errors.NewIOError("reading closed body stream", net.ErrClosed)
+	}
+	if b.eof {
+		return 0, io.EOF
+	}
+
+	if b.readTimeout > 0 && b.reader.Buffered() == 0 {
+		if err := b.conn.SetReadDeadline(time.Now().Add(b.readTimeout)); err != nil {
+			b.reusable = false
+			return 0, errors.NewIOError("setting read deadline", err)
+		}
+	}
+
+	n, err := b.body.Read(p)
+	if n > 0 && !b.started {
+		b.started = true
+		b.timer.StartBody()
+	}
+	if err == io.EOF {
+		b.eof = true
+		if b.started {
+			b.timer.EndBody()
+		}
+		b.response.Timings = b.timer.GetMetrics()
+		if t, ok := b.body.(interface{ truncated() bool }); ok && t.truncated() {
+			b.reusable = false
+		}
+		// Bytes left over after a complete body mean the framing is ambiguous
+		// (pipelined data or a lying Content-Length): never pool the socket.
+		if b.reader.Buffered() > 0 {
+			b.reusable = false
+		}
+		return n, io.EOF
+	}
+	if err != nil {
+		b.reusable = false
+	}
+	return n, err
+}
+
+// Close releases the connection: back to the pool if the body was fully and
+// cleanly consumed and pooling is enabled, otherwise it is closed. Idempotent.
+func (b *bodyStream) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+
+	if b.eof && b.reusable && b.opts.ReuseConnection {
+		if b.readTimeout > 0 {
+			b.conn.SetReadDeadline(time.Time{})
+		}
+		b.client.transport.ReleaseConnectionWithMetadata(b.opts.Host, b.opts.Port, b.conn, b.metadata)
+	} else {
+		b.client.transport.CloseConnectionWithMetadata(b.opts.Host, b.opts.Port, b.conn, b.metadata)
+	}
+	return nil
+}
+
+// isTruncation reports whether err marks a connection that ended mid-body. Like
+// the buffered readers, such bodies are delivered as-is (raw HTTP behavior) but
+// the connection is never pooled.
+func isTruncation(err error) bool {
+	return err == io.EOF || err == io.ErrUnexpectedEOF || isStaleConnectionError(err)
+}
+
+// eofReader is the body of responses that carry no content.
+type eofReader struct{}
+
+func (eofReader) Read([]byte) (int, error) { return 0, io.EOF }
+
+// fixedReader reads a Content-Length delimited body.
+type fixedReader struct {
+	r         io.Reader
+	remaining int64
+	short     bool
+}
+
+func (f *fixedReader) Read(p []byte) (int, error) {
+	if f.remaining <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > f.remaining {
+		p = p[:f.remaining]
+	}
+	n, err := f.r.Read(p)
+	f.remaining -= int64(n)
+	if err != nil {
+		if isTruncation(err) {
+			// Server sent less data than Content-Length indicated.
+			f.short = true
+			f.remaining = 0
+			return n, io.EOF
+		}
+		return n, errors.NewIOError("reading fixed body", err)
+	}
+	if f.remaining == 0 {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (f *fixedReader) truncated() bool { return f.short }
+
+// closeReader reads a body delimited by connection close.
+type closeReader struct {
+	r io.Reader
+}
+
+func (c *closeReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	if err != nil {
+		// An RST at the end of a close-delimited body is equivalent to a clean FIN.
+		if isTruncation(err) {
+			return n, io.EOF
+		}
+		return n, errors.NewIOError("reading until close", err)
+	}
+	return n, nil
+}
+
+// chunkedReader de-chunks a Transfer-Encoding: chunked body on the fly. Trailer
+// fields are recorded in Response.Trailers once the terminating chunk has been
+// read, and anomalies in Response.Anomalies, the same way the buffered reader
+// does.
+type chunkedReader struct {
+	r           *bufio.Reader
+	trailers    map[string][]string
+	ck          *checker
+	remaining   int64 // bytes left in the current chunk
+	pendingCRLF bool  // a chunk was fully read and its CRLF is still unread
+	received    int64 // total de-chunked bytes delivered
+	short       bool
+	done        bool
+}
+
+func (cr *chunkedReader) Read(p []byte) (int, error) {
+	if cr.done {
+		return 0, io.EOF
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	if cr.remaining == 0 {
+		if cr.pendingCRLF {
+			if err := cr.chunkEnd(); err != nil {
+				return 0, err
+			}
+			cr.pendingCRLF = false
+		}
+
+		line, err := cr.ck.limits.readChunkLine(cr.r)
+		if err != nil {
+			return 0, cr.fail("reading chunk size", err)
+		}
+		size, err := cr.ck.chunkSize(line)
+		if err != nil {
+			return 0, err
+		}
+		if size == 0 {
+			if err := cr.readTrailers(); err != nil {
+				return 0, err
+			}
+			cr.done = true
+			return 0, io.EOF
+		}
+		cr.remaining = size
+	}
+
+	if int64(len(p)) > cr.remaining {
+		p = p[:cr.remaining]
+	}
+	n, err := cr.r.Read(p)
+	cr.remaining -= int64(n)
+	cr.received += int64(n)
+	if cr.remaining == 0 {
+		cr.pendingCRLF = true
+	}
+	if err != nil {
+		return n, cr.fail("reading chunk body", err)
+	}
+	return n, nil
+}
+
+// fail maps a read error inside the chunked framing. A connection that ends after
+// some data was delivered is accepted as a truncated body, mirroring the buffered
+// reader; anything else is reported.
+func (cr *chunkedReader) fail(op string, err error) error {
+	if errors.IsLimitError(err) {
+		return err
+	}
+	if cr.received > 0 && isTruncation(err) {
+		cr.short = true
+		cr.done = true
+		return io.EOF
+	}
+	if op == "reading chunk size" {
+		return errors.NewProtocolError(op, err)
+	}
+	return errors.NewIOError(op, err)
+}
+
+// chunkEnd consumes the CRLF that must follow chunk data. Anything else is left
+// in place to be read as the next chunk size line.
+func (cr *chunkedReader) chunkEnd() error {
+	next, err := cr.r.Peek(2)
+	switch {
+	case len(next) > 0 && next[0] == '\n':
+		cr.r.Discard(1)
+		return cr.ck.note(AnomalyBareLF, "after chunk data")
+	case err != nil:
+		return cr.fail("reading chunk CRLF", err)
+	case string(next) == "\r\n":
+		cr.r.Discard(2)
+		return nil
+	default:
+		return cr.ck.note(AnomalyMissingChunkCRLF, "chunk data followed by %q", next)
+	}
+}
+
+func (cr *chunkedReader) readTrailers() error {
+	ck := cr.ck
+	ck.startHead()
+	for {
+		rawLine, err := ck.head.line(cr.r)
+		if errors.IsLimitError(err) {
+			return err
+		}
+		if err != nil {
+			return errors.NewProtocolError("reading chunk trailer", err)
+		}
+		if err := ck.lineEnding(rawLine, "trailer line"); err != nil {
+			return err
+		}
+		line := trimEOL(rawLine)
+		if line == "" {
+			return nil
+		}
+		if err := ck.head.field(); err != nil {
+			return err
+		}
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			if err := ck.note(AnomalyMalformedHeader, "trailer %q", line); err != nil {
+				return err
+			}
+			continue
+		}
+		key := textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(parts[0]))
+		value := strings.TrimSpace(parts[1])
+		cr.trailers[key] = append(cr.trailers[key], value)
+	}
+}
+
+func (cr *chunkedReader) truncated() bool { return cr.short }
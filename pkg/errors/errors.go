@@ -30,6 +30,8 @@ const (
 	ErrorTypeValidation ErrorType = "validation"
 	// ErrorTypeProxy represents proxy-specific errors (v2.0.0+)
 	ErrorTypeProxy ErrorType = "proxy"
+	// ErrorTypeLimit represents a response exceeding a configured size limit
+	ErrorTypeLimit ErrorType = "limit"
 )
 
 // Error represents a structured error with context information.
@@ -191,6 +193,17 @@ func NewValidationError(message string) *Error {
 	}
 }
 
+// NewLimitError creates an error for a response exceeding the named limit
+// (e.g. "MaxBodyBytes") of max bytes or fields.
+func NewLimitError(limit string, max int64) *Error {
+	return &Error{
+		Type:      ErrorTypeLimit,
+		Op:        "read",
+		Message:   fmt.Sprintf("response exceeds %s (%d)", limit, max),
+		Timestamp: time.Now(),
+	}
+}
+
 // IsTimeoutError checks if an error is a timeout error.
 func IsTimeoutError(err error) bool {
 	if e, ok := err.(*Error); ok {
@@ -223,6 +236,12 @@ func GetErrorType(err error) ErrorType {
 	return ""
 }
 
+// IsLimitError checks if an error is, or wraps, a limit error.
+func IsLimitError(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && e.Type == ErrorTypeLimit
+}
+
 // IsContextCanceled checks if an error is due to context cancellation.
 func IsContextCanceled(err error) bool {
 	return errors.Is(err, context.Canceled)
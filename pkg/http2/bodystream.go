@@ -0,0 +1,198 @@
+package http2
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/WhileEndless/go-rawhttp/pkg/errors"
+)
+
+// readResponseHead consumes frame events until the response HEADERS arrive and
+// attaches a streaming body reader to the response. The stream stays registered
+// until the body stream is closed.
+func (c *Client) readResponseHead(ctx context.Context, conn *Connection, stream *Stream, opts *Options) (*Response, error) {
+	response := newStreamResponse(stream)
+
+	w := newInboxWaiter(c, conn, stream, opts)
+	pushes := newPushReceiver(ctx, c, conn, opts)
+	for {
+		ev, err := w.next(ctx)
+		if err != nil {
+			w.stop()
+			pushes.abort()
+			return nil, err
+		}
+		if ev.kind == fkPush {
+			pushes.receive(response, ev)
+			continue
+		}
+		if ev.kind != fkHeaders {
+			w.stop()
+			pushes.abort()
+			c.cancelStream(conn, stream)
+			return nil, errors.NewProtocolError("reading response head",
+				fmt.Errorf("DATA frame received before HEADERS on stream %d", stream.ID))
+		}
+		limitErr := ev.limitErr
+		if !recordInterim(response, stream, ev) {
+			applyHeadersEvent(response, stream, ev)
+		} else if limitErr == nil {
+			continue
+		}
+		if limitErr != nil {
+			w.stop()
+			return response, c.abortOverLimit(conn, stream, pushes, ev, limitErr)
+		}
+
+		response.BodyStream = &streamBody{
+			client:    c,
+			conn:      conn,
+			stream:    stream,
+			response:  response,
+			waiter:    w,
+			pushes:    pushes,
+			ctx:       ctx,
+			opts:      opts,
+			closeConn: opts == nil || !opts.ReuseConnection,
+			eof:       ev.endStream,
+		}
+		if ev.endStream {
+			pushes.wait()
+		}
+		return response, nil
+	}
+}
+
+// streamBody is the io.ReadCloser behind Response.BodyStream on HTTP/2. Each Read
+// pulls the next DATA frame from the stream inbox. Flow-control credit is returned
+// as the caller reads the bytes, so a slow reader makes the server wait instead
+// of buffering the body in memory.
+type streamBody struct {
+	client    *Client
+	conn      *Connection
+	stream    *Stream
+	response  *Response
+	waiter    *inboxWaiter
+	pushes    *pushReceiver
+	ctx       context.Context
+	opts      *Options
+	closeConn bool
+
+	mu       sync.Mutex
+	pending  []byte
+	received int64 // body bytes accepted so far
+	credit   int   // bytes read whose flow-control credit is not returned yet
+	eof      bool
+	err      error
+	closed   bool
+}
+
+// streamCreditBatch is how many read bytes streamBody collects before returning
+// their credit within a DATA frame; a drained frame returns its credit at once.
+const streamCreditBatch = 16384
+
+// Read returns body bytes as DATA frames arrive. A trailing HEADERS block is
+// recorded in Response.Trailers before io.EOF is returned. A body exceeding
+// MaxBodyBytes ends with a limit error once the bytes within the limit are read.
+func (b *streamBody) Read(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return 0, errors.NewIOError("reading closed body stream", io.ErrClosedPipe)
+	}
+
+	for len(b.pending) == 0 {
+		if b.eof {
+			return 0, io.EOF
+		}
+		if b.err != nil {
+			return 0, b.err
+		}
+
+		ev, err := b.waiter.next(b.ctx)
+		if err != nil {
+			b.err = err
+			b.pushes.abort()
+			return 0, err
+		}
+		var limitErr error
+		switch ev.kind {
+		case fkPush:
+			b.pushes.receive(b.response, ev)
+		case fkData:
+			if b.received == 0 && len(ev.data) > 0 {
+				b.stream.timer.StartBody()
+			}
+			b.pending, limitErr = limitBody(b.opts, b.received, ev.data)
+			b.received += int64(len(b.pending))
+			// The payload is not kept: a streamed body must not accumulate in memory.
+			b.response.Frames = append(b.response.Frames, &DataFrame{
+				StreamId:  b.stream.ID,
+				EndStream: ev.endStream,
+				Length:    len(ev.data),
+			})
+		case fkHeaders:
+			limitErr = ev.limitErr
+			applyHeadersEvent(b.response, b.stream, ev)
+		}
+		if limitErr != nil {
+			// The bytes within the limit are still delivered before the error.
+			b.err = b.client.abortOverLimit(b.conn, b.stream, b.pushes, ev, limitErr)
+			continue
+		}
+		if ev.endStream {
+			b.eof = true
+			b.finishTimings()
+			b.pushes.wait()
+		}
+	}
+
+	n := copy(p, b.pending)
+	b.pending = b.pending[n:]
+	b.credit += n
+	if len(b.pending) == 0 || b.credit >= streamCreditBatch {
+		_ = b.conn.consumed(b.stream, b.credit) // best effort, like cancelStream
+		b.credit = 0
+	}
+	return n, nil
+}
+
+// finishTimings ends the body span and refreshes the response metrics, which
+// were taken when the head arrived.
+func (b *streamBody) finishTimings() {
+	if b.received > 0 {
+		b.stream.timer.EndBody()
+	}
+	metrics := b.stream.timer.GetMetrics()
+	if b.response.Metrics != nil {
+		*b.response.Metrics = metrics
+	}
+	b.response.TotalTime = metrics.TotalTime
+}
+
+// Close releases the stream. If the server has not finished the stream yet it is
+// cancelled with RST_STREAM(CANCEL); without pooling the connection is closed as
+// well. Idempotent.
+func (b *streamBody) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	b.waiter.stop()
+
+	if !b.eof && b.err == nil {
+		b.client.cancelStream(b.conn, b.stream)
+		b.pushes.abort()
+	}
+	b.client.unregisterStream(b.conn, b.stream)
+	if b.closeConn {
+		return b.conn.Close()
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"crypto/tls"
+	stderrors "errors"
 	"fmt"
 	"net"
 	"strconv"
@@ -12,6 +13,7 @@ import (
 
 	"github.com/WhileEndless/go-rawhttp/pkg/errors"
 	"github.com/WhileEndless/go-rawhttp/pkg/timing"
+	"github.com/WhileEndless/go-rawhttp/pkg/transport"
 	"golang.org/x/net/http2"
 	"golang.org/x/net/http2/hpack"
 )
@@ -50,6 +52,21 @@ func (c *Client) Do(ctx context.Context, rawRequest []byte, host string, port in
 
 // DoWithOptions performs an HTTP/2 request with custom options
 func (c *Client) DoWithOptions(ctx context.Context, rawRequest []byte, host string, port int, scheme string, opts *Options) (*Response, error) {
+	return c.do(ctx, rawRequest, host, port, scheme, opts, false)
+}
+
+// DoStreamWithOptions performs an HTTP/2 request and returns as soon as the
+// response HEADERS have been received. The body is exposed through
+// Response.BodyStream, which pulls DATA frames from the stream on demand instead
+// of collecting them into Response.Body. The stream (and, without pooling, the
+// connection) is released when BodyStream is closed; closing it before END_STREAM
+// cancels the stream with RST_STREAM(CANCEL).
+func (c *Client) DoStreamWithOptions(ctx context.Context, rawRequest []byte, host string, port int, scheme string, opts *Options) (*Response, error) {
+	return c.do(ctx, rawRequest, host, port, scheme, opts, true)
+}
+
+// do implements DoWithOptions and DoStreamWithOptions.
+func (c *Client) do(ctx context.Context, rawRequest []byte, host string, port int, scheme string, opts *Options, streamBody bool) (*Response, error) {
 	if opts == nil {
 		opts = c.options
 	}
@@ -73,17 +90,24 @@ func (c *Client) DoWithOptions(ctx context.Context, rawRequest []byte, host stri
 	}
 
 	// Connect to server (connection will be reused if pooling enabled)
-	timer.StartTCP()
-	conn, err := c.transport.Connect(ctx, host, port, scheme, opts)
+	conn, err := c.transport.connect(ctx, host, port, scheme, opts, timer)
 	if err != nil {
 		return nil, errors.NewConnectionError(host, port, err)
 	}
-	timer.EndTCP()
+	opts.Trace.TraceGotConn(conn.Conn, conn.wasReused(), conn.idleTime(), 0)
+
+	// handedOff is set once a body stream owns the stream and connection; its
+	// Close then performs the cleanup deferred below.
+	handedOff := false
 
 	// Without pooling, the connection is single-use: close it (and its read loop)
 	// when we're done.
 	if !opts.ReuseConnection {
-		defer conn.Close()
+		defer func() {
+			if !handedOff {
+				conn.Close()
+			}
+		}()
 	}
 
 	// Atomically allocate the stream ID, register it, and write the request frames.
@@ -91,19 +115,40 @@ func (c *Client) DoWithOptions(ctx context.Context, rawRequest []byte, host stri
 	// so ID allocation and the HEADERS write must happen under the same writeMu
 	// critical section; otherwise concurrent requests could interleave (lower ID after
 	// higher), which the server rejects with PROTOCOL_ERROR.
-	timer.StartTTFB()
-	stream, err := c.openStream(conn, rawRequest, request)
+	timer.StartWrite()
+	stream, err := c.openStream(conn, rawRequest, request, false, opts)
+	conn.unreserve()
 	if err != nil {
 		return nil, err
 	}
-	defer c.unregisterStream(conn, stream)
+	stream.timer = timer
+	defer func() {
+		if !handedOff {
+			c.unregisterStream(conn, stream)
+		}
+	}()
+
+	// Write the request body as the peer grants flow-control credit.
+	if err := c.sendPending(ctx, conn, stream, opts); err != nil {
+		return nil, err
+	}
+	timer.EndWrite()
+	timer.StartTTFB()
 
 	// Read response by consuming dispatched frames for this stream.
-	response, err := c.readResponse(ctx, conn, stream, opts)
-	if err != nil {
+	var response *Response
+	if streamBody {
+		response, err = c.readResponseHead(ctx, conn, stream, opts)
+	} else {
+		response, err = c.readResponse(ctx, conn, stream, opts)
+	}
+	if err != nil && response == nil {
 		return nil, err
 	}
-	timer.EndTTFB()
+	// A response over a size limit is returned along with the error, as read so far.
+	if streamBody && err == nil {
+		handedOff = true
+	}
 
 	// Calculate total time
 	totalTime := time.Since(startTime)
@@ -120,8 +165,13 @@ func (c *Client) DoWithOptions(ctx context.Context, rawRequest []byte, host stri
 
 	// Add connection metadata
 	c.fillConnectionMetadata(response, conn, host, port, scheme, opts)
+	for _, pp := range response.ServerPush {
+		if pp.Response != nil {
+			c.fillConnectionMetadata(pp.Response, conn, host, port, scheme, opts)
+		}
+	}
 
-	return response, nil
+	return response, err
 }
 
 // DoFrames sends raw frames directly (advanced usage)
@@ -144,10 +194,15 @@ func (c *Client) DoFrames(ctx context.Context, frames []Frame, host string, port
 	// route its frames here.
 	streamID := frames[0].StreamID()
 	stream := &Stream{
-		ID:    streamID,
-		State: StateOpen,
-		inbox: make(chan frameEvent, streamInboxSize),
-		done:  make(chan struct{}),
+		ID:     streamID,
+		State:  StateOpen,
+		ready:  make(chan struct{}, 1),
+		done:   make(chan struct{}),
+		trace:  c.options.Trace,
+		limits: newHeaderLimits(c.options),
+	}
+	if stream.trace != nil {
+		conn.tracing.Store(true)
 	}
 	conn.mu.Lock()
 	conn.Streams[streamID] = stream
@@ -167,10 +222,6 @@ func (c *Client) DoFrames(ctx context.Context, frames []Frame, host string, port
 	return c.readResponse(ctx, conn, stream, c.options)
 }
 
-// streamInboxSize is the per-stream frame inbox buffer. It absorbs bursts of DATA
-// frames between the request goroutine's reads without blocking the shared read loop.
-const streamInboxSize = 64
-
 // maxClientStreamID is the largest valid client-initiated (odd) stream ID (2^31-1).
 const maxClientStreamID = 1<<31 - 1
 
@@ -179,9 +230,19 @@ const maxClientStreamID = 1<<31 - 1
 // HEADERS that opens the stream is written in strictly increasing stream-ID order
 // (an HTTP/2 requirement). It returns a stale-classified error if the connection is
 // already dead, its stream IDs are exhausted, or a frame write fails (so the caller
-// can retry on a fresh connection).
-func (c *Client) openStream(conn *Connection, rawRequest []byte, request *Request) (*Stream, error) {
+// can retry on a fresh connection). With holdFinal the frame that would end the
+// request is withheld in stream.final (see releaseFinal). opts.Trace receives the
+// stream's frames and request hooks, and the response header limits of opts apply
+// to the stream (opts may be nil).
+func (c *Client) openStream(conn *Connection, rawRequest []byte, request *Request, holdFinal bool, opts *Options) (*Stream, error) {
+	var trace *transport.ClientTrace
+	if opts != nil {
+		trace = opts.Trace
+	}
 	conn.touch()
+	if trace != nil {
+		conn.tracing.Store(true)
+	}
 
 	conn.writeMu.Lock()
 
@@ -210,21 +271,32 @@ func (c *Client) openStream(conn *Connection, rawRequest []byte, request *Reques
 		State:          StateOpen,
 		Request:        request,
 		WindowSize:     65535,
-		PeerWindowSize:  65535,
-		inbox:          make(chan frameEvent, streamInboxSize),
+		PeerWindowSize: conn.peerInitialWindow,
+		ready:          make(chan struct{}, 1),
 		done:           make(chan struct{}),
+		trace:          trace,
+		limits:         newHeaderLimits(opts),
 	}
 	conn.Streams[streamID] = stream
 	conn.mu.Unlock()
 
-	// Convert and write the request frames while still holding writeMu.
-	frames, err := c.converter.TextToFrames(rawRequest, streamID)
+	// Convert and write the request HEADERS while still holding writeMu. Frames
+	// after the first DATA frame are left to sendPending, which honors the peer's
+	// flow-control windows.
+	frames, err := c.converter.textToFrames(rawRequest, streamID, conn.fingerprint)
 	if err != nil {
 		conn.writeMu.Unlock()
 		c.unregisterStream(conn, stream)
 		return nil, errors.NewProtocolError("converting to frames", err)
 	}
-	for _, frame := range frames {
+	if holdFinal {
+		frames, stream.final = splitFinalFrame(frames)
+	}
+	for i, frame := range frames {
+		if _, isData := frame.(*DataFrame); isData {
+			stream.pending = frames[i:]
+			break
+		}
 		if werr := c.sendFrameLocked(conn, frame); werr != nil {
 			conn.writeMu.Unlock()
 			c.unregisterStream(conn, stream)
@@ -235,23 +307,71 @@ func (c *Client) openStream(conn *Connection, rawRequest []byte, request *Reques
 	}
 
 	conn.writeMu.Unlock()
+	stream.openedAt = time.Now()
+	trace.TraceWroteHeaders()
 	return stream, nil
 }
 
+// sendPending writes the request frames queued by openStream. DATA frames are split
+// and paced by the peer's flow-control windows; a writer waiting for credit gives
+// up after ReadTimeout without a WINDOW_UPDATE. If the peer ends or resets the
+// stream first (e.g. an early error response), the rest of the body is dropped and
+// the response is read as usual. WroteRequest fires when the request is complete
+// (for a single-packet batch, once releaseFinal has written the final frame).
+func (c *Client) sendPending(ctx context.Context, conn *Connection, stream *Stream, opts *Options) (err error) {
+	frames := stream.pending
+	stream.pending = nil
+	defer func() {
+		if err != nil || stream.final == nil {
+			stream.trace.TraceWroteRequest(err)
+		}
+	}()
+
+	for _, frame := range frames {
+		var err error
+		if df, ok := frame.(*DataFrame); ok {
+			err = c.writeData(ctx, conn, stream, df.Data, df.EndStream, opts.ReadTimeout)
+		} else {
+			err = c.sendFrame(conn, frame)
+		}
+		if stderrors.Is(err, errPeerDone) {
+			// Our half of the stream is still open; close it without an error.
+			conn.writeMu.Lock()
+			_ = conn.Framer.WriteRSTStream(stream.ID, http2.ErrCodeNo)
+			conn.writeMu.Unlock()
+			return nil
+		}
+		if err != nil {
+			if !IsStaleConnError(err) {
+				c.cancelStream(conn, stream)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
 // unregisterStream releases a stream: it closes done (so the read loop never blocks
 // routing to a finished stream) and removes it from the connection's stream table
-// (preventing unbounded growth on long-lived reused connections).
+// (preventing unbounded growth on long-lived reused connections). The freed slot
+// is offered to requests waiting for one.
 func (c *Client) unregisterStream(conn *Connection, stream *Stream) {
+	stream.finish()
 	close(stream.done)
 	conn.mu.Lock()
 	delete(conn.Streams, stream.ID)
 	conn.mu.Unlock()
+	conn.releaseRetired()
+	c.transport.notifyCapacity()
 }
 
 // cancelStream sends a best-effort RST_STREAM(CANCEL) so the server stops sending
 // frames for an abandoned stream (timeout / context cancel) without tearing down the
 // whole connection.
 func (c *Client) cancelStream(conn *Connection, stream *Stream) {
+	conn.mu.Lock()
+	stream.cancelled = true
+	conn.mu.Unlock()
 	conn.writeMu.Lock()
 	_ = conn.Framer.WriteRSTStream(stream.ID, http2.ErrCodeCancel)
 	conn.writeMu.Unlock()
@@ -269,6 +389,13 @@ func (c *Client) sendFrame(conn *Connection, frame Frame) error {
 
 // sendFrameLocked writes a single frame. The caller MUST already hold conn.writeMu.
 func (c *Client) sendFrameLocked(conn *Connection, frame Frame) error {
+	return c.writeFrame(conn, conn.Framer, frame)
+}
+
+// writeFrame encodes a frame with the connection's HPACK context and writes it to
+// fr, which is conn.Framer or a framer buffering a coalesced write. The caller
+// MUST already hold conn.writeMu.
+func (c *Client) writeFrame(conn *Connection, fr *http2.Framer, frame Frame) error {
 	switch f := frame.(type) {
 	case *HeadersFrame:
 		// Encode headers using connection's encoder directly
@@ -280,41 +407,53 @@ func (c *Client) sendFrameLocked(conn *Connection, frame Frame) error {
 		// Get the connection's encoder buffer and reset it for this frame
 		conn.EncoderBuf.Reset()
 
-		// Encode pseudo-headers first (in order)
-		pseudoOrder := []string{":method", ":path", ":scheme", ":authority", ":status"}
-		for _, name := range pseudoOrder {
-			if value, ok := f.Headers[name]; ok {
-				err := conn.Encoder.WriteField(hpack.HeaderField{Name: name, Value: value})
-				if err != nil {
-					return fmt.Errorf("failed to encode pseudo-header %s: %w", name, err)
+		// Encode the fields in wire order when the frame fixes it; otherwise
+		// pseudo-headers first (in order), then regular headers
+		if len(f.Fields) > 0 {
+			for _, field := range f.Fields {
+				if err := conn.Encoder.WriteField(field); err != nil {
+					return fmt.Errorf("failed to encode header %s: %w", field.Name, err)
+				}
+			}
+		} else {
+			pseudoOrder := []string{":method", ":path", ":scheme", ":authority", ":status"}
+			for _, name := range pseudoOrder {
+				if value, ok := f.Headers[name]; ok {
+					err := conn.Encoder.WriteField(hpack.HeaderField{Name: name, Value: value})
+					if err != nil {
+						return fmt.Errorf("failed to encode pseudo-header %s: %w", name, err)
+					}
 				}
 			}
-		}
 
-		// Encode regular headers
-		for name, value := range f.Headers {
-			if !strings.HasPrefix(name, ":") {
-				err := conn.Encoder.WriteField(hpack.HeaderField{Name: strings.ToLower(name), Value: value})
-				if err != nil {
-					return fmt.Errorf("failed to encode header %s: %w", name, err)
+			for name, value := range f.Headers {
+				if !strings.HasPrefix(name, ":") {
+					err := conn.Encoder.WriteField(hpack.HeaderField{Name: strings.ToLower(name), Value: value})
+					if err != nil {
+						return fmt.Errorf("failed to encode header %s: %w", name, err)
+					}
 				}
 			}
 		}
 
 		encoded := conn.EncoderBuf.Bytes()
 
-		// Send HEADERS frame
-		return conn.Framer.WriteHeaders(http2.HeadersFrameParam{
+		conn.mu.RLock()
+		maxFrameSize := int(conn.peerMaxFrameSize)
+		conn.mu.RUnlock()
+
+		// Send HEADERS frame (plus CONTINUATION frames for a large block)
+		return writeHeaderBlock(fr, http2.HeadersFrameParam{
 			StreamID:      f.StreamId,
 			BlockFragment: encoded,
 			EndStream:     f.EndStream,
 			EndHeaders:    f.EndHeaders,
 			Priority:      convertPriority(f.Priority),
-		})
+		}, maxFrameSize)
 
 	case *DataFrame:
 		// Send DATA frame
-		return conn.Framer.WriteData(f.StreamId, f.EndStream, f.Data)
+		return fr.WriteData(f.StreamId, f.EndStream, f.Data)
 
 	default:
 		return fmt.Errorf("unsupported frame type: %T", frame)
@@ -326,115 +465,266 @@ func (c *Client) sendFrameLocked(conn *Connection, frame Frame) error {
 // timeout is enforced here (rolling: reset on each received frame), so an idle
 // pooled connection is never torn down for lack of a request.
 func (c *Client) readResponse(ctx context.Context, conn *Connection, stream *Stream, opts *Options) (*Response, error) {
-	response := &Response{
+	response := newStreamResponse(stream)
+
+	w := newInboxWaiter(c, conn, stream, opts)
+	defer w.stop()
+	pushes := newPushReceiver(ctx, c, conn, opts)
+
+	for {
+		ev, err := w.next(ctx)
+		if err != nil {
+			pushes.abort()
+			return nil, err
+		}
+
+		switch ev.kind {
+		case fkPush:
+			pushes.receive(response, ev)
+
+		case fkHeaders:
+			limitErr := ev.limitErr
+			if !recordInterim(response, stream, ev) {
+				applyHeadersEvent(response, stream, ev)
+			} else if limitErr == nil {
+				continue
+			}
+			if limitErr != nil {
+				return response, c.abortOverLimit(conn, stream, pushes, ev, limitErr)
+			}
+			if ev.endStream {
+				stream.timer.EndBody()
+				pushes.wait()
+				return response, nil
+			}
+
+		case fkData:
+			if len(response.Body) == 0 && len(ev.data) > 0 {
+				stream.timer.StartBody()
+			}
+			data, limitErr := limitBody(opts, int64(len(response.Body)), ev.data)
+			response.Body = append(response.Body, data...)
+			_ = conn.consumed(stream, len(data)) // best effort, like cancelStream
+			response.Frames = append(response.Frames, &DataFrame{
+				StreamId:  stream.ID,
+				Data:      data,
+				EndStream: ev.endStream,
+				Length:    len(ev.data),
+			})
+			if limitErr != nil {
+				stream.timer.EndBody()
+				return response, c.abortOverLimit(conn, stream, pushes, ev, limitErr)
+			}
+			if ev.endStream {
+				stream.timer.EndBody()
+				pushes.wait()
+				return response, nil
+			}
+		}
+	}
+}
+
+// abortOverLimit gives up on a response that exceeded a size limit with the
+// event ev: the stream is cancelled unless ev ended it. It returns err.
+func (c *Client) abortOverLimit(conn *Connection, stream *Stream, pushes *pushReceiver, ev frameEvent, err error) error {
+	pushes.abort()
+	if !ev.endStream {
+		c.cancelStream(conn, stream)
+	}
+	return err
+}
+
+// newStreamResponse creates an empty response for a stream.
+func newStreamResponse(stream *Stream) *Response {
+	return &Response{
 		StreamID:    stream.ID,
 		Headers:     make(map[string][]string),
+		Trailers:    make(map[string][]string),
 		Frames:      []Frame{},
 		HTTPVersion: "HTTP/2",
 	}
+}
+
+// recordInterim records a HEADERS block carrying a 1xx status that arrives before
+// the final response (RFC 9113 Section 8.1) and reports whether it did so. Any
+// other block (the final response or trailers) is left to applyHeadersEvent.
+func recordInterim(response *Response, stream *Stream, ev frameEvent) bool {
+	if response.Status != 0 {
+		return false
+	}
+	code, err := strconv.Atoi(ev.headers[":status"])
+	if err != nil || code < 100 || code >= 200 {
+		return false
+	}
 
-	var timer *time.Timer
-	var timeoutC <-chan time.Time
-	if opts != nil && opts.ReadTimeout > 0 {
-		timer = time.NewTimer(opts.ReadTimeout)
-		defer timer.Stop()
-		timeoutC = timer.C
+	interim := InterimResponse{
+		Status:     code,
+		StatusText: getStatusText(code),
+		Headers:    make(map[string][]string),
+		HeaderList: ev.fields,
+		ReceivedAt: time.Now(),
 	}
-	resetTimer := func() {
-		if timer == nil {
-			return
+	for _, f := range ev.fields {
+		if !strings.HasPrefix(f.Name, ":") {
+			interim.Headers[f.Name] = append(interim.Headers[f.Name], f.Value)
 		}
-		if !timer.Stop() {
-			select {
-			case <-timer.C:
-			default:
+	}
+	response.Informational = append(response.Informational, interim)
+	stream.trace.TraceGot1xxResponse(code, interim.Headers)
+	response.Frames = append(response.Frames, headersFrameFromEvent(stream, ev))
+	return true
+}
+
+// applyHeadersEvent merges a decoded HEADERS event into the response. Fields are
+// applied in wire order, so repeated fields (e.g. set-cookie) keep every value. A
+// block received after the final response head is a trailer section and goes to
+// Response.Trailers instead.
+func applyHeadersEvent(response *Response, stream *Stream, ev frameEvent) {
+	response.Frames = append(response.Frames, headersFrameFromEvent(stream, ev))
+	if response.Status != 0 {
+		for _, f := range ev.fields {
+			if !strings.HasPrefix(f.Name, ":") {
+				response.Trailers[f.Name] = append(response.Trailers[f.Name], f.Value)
 			}
 		}
-		timer.Reset(opts.ReadTimeout)
+		return
+	}
+	for _, f := range ev.fields {
+		if f.Name == ":status" {
+			response.Status, _ = strconv.Atoi(f.Value)
+			response.StatusText = getStatusText(response.Status)
+		} else if !strings.HasPrefix(f.Name, ":") {
+			response.Headers[f.Name] = append(response.Headers[f.Name], f.Value)
+		}
+	}
+	response.HeaderList = append(response.HeaderList, ev.fields...)
+}
+
+// headersFrameFromEvent records a received HEADERS event as a frame.
+func headersFrameFromEvent(stream *Stream, ev frameEvent) *HeadersFrame {
+	return &HeadersFrame{
+		StreamId:   stream.ID,
+		Headers:    ev.headers,
+		Fields:     ev.fields,
+		EndStream:  ev.endStream,
+		EndHeaders: true,
+	}
+}
+
+// inboxWaiter waits for the next frame event of a stream while enforcing context
+// cancellation, the rolling per-request read timeout and connection teardown.
+type inboxWaiter struct {
+	client   *Client
+	conn     *Connection
+	stream   *Stream
+	timeout  time.Duration
+	timer    *time.Timer
+	gotFrame bool
+}
+
+// newInboxWaiter creates a waiter for stream. The read timeout starts immediately.
+func newInboxWaiter(c *Client, conn *Connection, stream *Stream, opts *Options) *inboxWaiter {
+	w := &inboxWaiter{client: c, conn: conn, stream: stream}
+	if opts != nil && opts.ReadTimeout > 0 {
+		w.timeout = opts.ReadTimeout
+		w.timer = time.NewTimer(opts.ReadTimeout)
+	}
+	return w
+}
+
+// stop releases the waiter's timer.
+func (w *inboxWaiter) stop() {
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+}
+
+// resetTimer restarts the rolling read timeout.
+func (w *inboxWaiter) resetTimer() {
+	if w.timer == nil {
+		return
+	}
+	if !w.timer.Stop() {
+		select {
+		case <-w.timer.C:
+		default:
+		}
 	}
+	w.timer.Reset(w.timeout)
+}
 
-	gotFrame := false
+// next returns the next HEADERS or DATA event for the stream. RST_STREAM and
+// connection-level errors are returned as errors.
+func (w *inboxWaiter) next(ctx context.Context) (frameEvent, error) {
+	var timeoutC <-chan time.Time
+	if w.timer != nil {
+		timeoutC = w.timer.C
+	}
+	conn, stream := w.conn, w.stream
 
 	for {
+		if ev, ok := stream.dequeue(); ok {
+			return w.received(ev)
+		}
+
 		select {
 		case <-ctx.Done():
-			c.cancelStream(conn, stream)
+			w.client.cancelStream(conn, stream)
 			if ctx.Err() == context.DeadlineExceeded {
-				return nil, errors.NewTimeoutError("reading response", 0)
+				return frameEvent{}, errors.NewTimeoutError("reading response", 0)
 			}
-			return nil, errors.NewProtocolError("context cancelled", ctx.Err())
+			return frameEvent{}, errors.NewProtocolError("context cancelled", ctx.Err())
 
 		case <-timeoutC:
 			// No response frame within ReadTimeout. If this is a reused connection and
 			// we never saw a single frame, the pooled connection is almost certainly
 			// dead: evict + tear it down so the request-level retry recovers on a fresh
 			// connection. Otherwise just cancel this stream.
-			if !gotFrame && conn.wasReused() {
-				c.transport.removeConnection(conn)
+			if !w.gotFrame && conn.wasReused() {
+				w.client.transport.removeConnection(conn)
 				deadErr := wrapStaleHTTP2Error("read timeout", errConnClosed)
 				conn.fail(deadErr)
-				return nil, deadErr
+				return frameEvent{}, deadErr
 			}
-			c.cancelStream(conn, stream)
-			return nil, errors.NewTimeoutError("reading response", opts.ReadTimeout)
+			w.client.cancelStream(conn, stream)
+			return frameEvent{}, errors.NewTimeoutError("reading response", w.timeout)
 
 		case <-conn.closedCh:
-			// Connection died (EOF / reset / GOAWAY drain). Surface the stale error so
-			// the request is retried on a fresh connection.
-			conn.mu.RLock()
-			err := conn.connErr
-			conn.mu.RUnlock()
-			if err == nil {
-				err = wrapStaleHTTP2Error("connection closed", errConnClosed)
+			// Connection died (EOF / reset / GOAWAY drain). Events queued before
+			// that are still delivered; then surface the stale error so the request
+			// is retried on a fresh connection.
+			if ev, ok := stream.dequeue(); ok {
+				return w.received(ev)
 			}
-			return nil, err
-
-		case ev := <-stream.inbox:
-			gotFrame = true
-			resetTimer()
-
-			switch ev.kind {
-			case fkHeaders:
-				for name, value := range ev.headers {
-					if name == ":status" {
-						response.Status, _ = strconv.Atoi(value)
-						response.StatusText = getStatusText(response.Status)
-					} else if !strings.HasPrefix(name, ":") {
-						response.Headers[name] = append(response.Headers[name], value)
-					}
-				}
-				response.Frames = append(response.Frames, &HeadersFrame{
-					StreamId:   stream.ID,
-					Headers:    ev.headers,
-					EndStream:  ev.endStream,
-					EndHeaders: true,
-				})
-				if ev.endStream {
-					return response, nil
-				}
-
-			case fkData:
-				response.Body = append(response.Body, ev.data...)
-				response.Frames = append(response.Frames, &DataFrame{
-					StreamId:  stream.ID,
-					Data:      ev.data,
-					EndStream: ev.endStream,
-				})
-				if ev.endStream {
-					return response, nil
-				}
+			return frameEvent{}, conn.closedErr()
 
-			case fkRST:
-				return nil, errors.NewProtocolError("stream reset",
-					fmt.Errorf("error code: %v", ev.errCode))
-
-			case fkConnErr:
-				return nil, ev.err
-			}
+		case <-stream.ready:
 		}
 	}
 }
 
+// received accounts for an event taken from the stream inbox. RST_STREAM and
+// connection-level errors are returned as errors.
+func (w *inboxWaiter) received(ev frameEvent) (frameEvent, error) {
+	stream := w.stream
+	if !w.gotFrame {
+		stream.firstEventAt = time.Now()
+		stream.timer.EndTTFB()
+		stream.trace.TraceGotFirstResponseByte()
+	}
+	w.gotFrame = true
+	w.resetTimer()
+
+	switch ev.kind {
+	case fkRST:
+		return frameEvent{}, errors.NewProtocolError("stream reset",
+			fmt.Errorf("error code: %v", ev.errCode))
+	case fkConnErr:
+		return frameEvent{}, ev.err
+	}
+	return ev, nil
+}
+
 // fillConnectionMetadata populates connection metadata in the response
 func (c *Client) fillConnectionMetadata(response *Response, conn *Connection, host string, port int, scheme string, opts *Options) {
 	// Get remote address
@@ -449,8 +739,7 @@ func (c *Client) fillConnectionMetadata(response *Response, conn *Connection, ho
 
 	// Get TLS information if this is an HTTPS connection
 	if scheme == "https" {
-		if tlsConn, ok := conn.Conn.(*tls.Conn); ok {
-			state := tlsConn.ConnectionState()
+		if state, ok := transport.TLSConnectionState(conn.Conn); ok {
 
 			// TLS Version
 			response.TLSVersion = getTLSVersionString(state.Version)
@@ -464,10 +753,17 @@ func (c *Client) fillConnectionMetadata(response *Response, conn *Connection, ho
 			// Server Name (SNI)
 			response.TLSServerName = state.ServerName
 		}
+		response.JA3 = conn.hello.JA3
+		response.JA3Hash = conn.hello.JA3Hash
+		response.JA4 = conn.hello.JA4
 	}
 
 	// Connection reuse (v2.0.3+: use actual reuse status from connection)
 	response.ConnectionReused = conn.wasReused()
+	response.DialAttempts = conn.dialAttempts
+	response.RTT = conn.RTT()
+	response.PeerPreface = conn.preface
+	response.PeerSettings = conn.peerSettings()
 
 	// Proxy information
 	if opts != nil && opts.Proxy != nil {
@@ -542,17 +838,19 @@ func convertPriority(p *PriorityParam) http2.PriorityParam {
 func (c *Client) FormatResponse(resp *Response) []byte {
 	var buf bytes.Buffer
 
+	// Interim 1xx responses precede the final one, as they would on the wire.
+	for _, ir := range resp.Informational {
+		buf.WriteString(fmt.Sprintf("HTTP/2 %d %s\r\n", ir.Status, ir.StatusText))
+		c.writeHeaderLines(&buf, ir.HeaderList, ir.Headers)
+		buf.WriteString("\r\n")
+	}
+
 	// Status line
 	statusText := getStatusText(resp.Status)
 	buf.WriteString(fmt.Sprintf("HTTP/2 %d %s\r\n", resp.Status, statusText))
 
 	// Headers
-	for name, values := range resp.Headers {
-		for _, value := range values {
-			buf.WriteString(fmt.Sprintf("%s: %s\r\n",
-				c.converter.normalizeHeaderName(name), value))
-		}
-	}
+	c.writeHeaderLines(&buf, resp.HeaderList, resp.Headers)
 
 	// Empty line
 	buf.WriteString("\r\n")
@@ -565,10 +863,33 @@ func (c *Client) FormatResponse(resp *Response) []byte {
 	return buf.Bytes()
 }
 
+// writeHeaderLines writes header lines in wire order from the decoded field list,
+// falling back to the header map (unordered) for responses built without one.
+func (c *Client) writeHeaderLines(buf *bytes.Buffer, fields []hpack.HeaderField, headers map[string][]string) {
+	if len(fields) == 0 {
+		for name, values := range headers {
+			for _, value := range values {
+				buf.WriteString(fmt.Sprintf("%s: %s\r\n",
+					c.converter.normalizeHeaderName(name), value))
+			}
+		}
+		return
+	}
+	for _, f := range fields {
+		if strings.HasPrefix(f.Name, ":") {
+			continue
+		}
+		buf.WriteString(fmt.Sprintf("%s: %s\r\n",
+			c.converter.normalizeHeaderName(f.Name), f.Value))
+	}
+}
+
 func getStatusText(code int) string {
 	texts := map[int]string{
 		100: "Continue",
 		101: "Switching Protocols",
+		102: "Processing",
+		103: "Early Hints",
 		200: "OK",
 		201: "Created",
 		202: "Accepted",
@@ -32,26 +32,42 @@ func NewConverter() *Converter {
 
 // TextToFrames converts HTTP/1.1-style raw request to HTTP/2 frames
 func (c *Converter) TextToFrames(rawRequest []byte, streamID uint32) ([]Frame, error) {
+	return c.textToFrames(rawRequest, streamID, nil)
+}
+
+// textToFrames converts a raw request to frames whose HEADERS follow the
+// fingerprint's layout (fp may be nil). Regular headers keep the order of the
+// raw request unless the fingerprint reorders them.
+func (c *Converter) textToFrames(rawRequest []byte, streamID uint32, fp *Fingerprint) ([]Frame, error) {
 	req, err := c.parseHTTP11Request(rawRequest)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse HTTP/1.1 request: %w", err)
 	}
 
 	// Create pseudo-headers
-	pseudoHeaders := []hpack.HeaderField{
-		{Name: ":method", Value: req.Method},
-		{Name: ":path", Value: req.Path},
-		{Name: ":scheme", Value: req.Scheme},
-		{Name: ":authority", Value: req.Authority},
+	pseudoValues := map[string]string{
+		":method":    req.Method,
+		":path":      req.Path,
+		":scheme":    req.Scheme,
+		":authority": req.Authority,
+	}
+	var pseudoHeaders []hpack.HeaderField
+	for _, name := range fp.pseudoHeaderOrder() {
+		pseudoHeaders = append(pseudoHeaders, hpack.HeaderField{Name: name, Value: pseudoValues[name]})
 	}
 
 	// Add regular headers (convert to lowercase)
 	var regularHeaders []hpack.HeaderField
-	for name, value := range req.Headers {
+	for _, name := range req.HeaderOrder {
+		value, ok := req.Headers[name]
+		if !ok {
+			continue
+		}
 		lowerName := strings.ToLower(name)
 
-		// Skip connection-specific headers
-		if c.isConnectionSpecificHeader(lowerName) {
+		// Skip connection-specific headers ("te: trailers" is the one allowed TE value)
+		if c.isConnectionSpecificHeader(lowerName) &&
+			!(lowerName == "te" && strings.EqualFold(strings.TrimSpace(value), "trailers")) {
 			continue
 		}
 
@@ -70,6 +86,7 @@ func (c *Converter) TextToFrames(rawRequest []byte, streamID uint32) ([]Frame, e
 			Value: value,
 		})
 	}
+	fp.orderHeaders(regularHeaders)
 
 	// Combine pseudo-headers and regular headers
 	allHeaders := append(pseudoHeaders, regularHeaders...)
@@ -78,8 +95,12 @@ func (c *Converter) TextToFrames(rawRequest []byte, streamID uint32) ([]Frame, e
 	headerFrame := &HeadersFrame{
 		StreamId:   streamID,
 		Headers:    c.headerFieldsToMap(allHeaders),
+		Fields:     allHeaders,
 		EndHeaders: true,
-		EndStream:  len(req.Body) == 0,
+		EndStream:  len(req.Body) == 0 && len(req.Trailers) == 0,
+	}
+	if fp != nil {
+		headerFrame.Priority = fp.HeaderPriority
 	}
 
 	frames := []Frame{headerFrame}
@@ -89,11 +110,25 @@ func (c *Converter) TextToFrames(rawRequest []byte, streamID uint32) ([]Frame, e
 		dataFrame := &DataFrame{
 			StreamId:  streamID,
 			Data:      req.Body,
-			EndStream: true,
+			EndStream: len(req.Trailers) == 0,
 		}
 		frames = append(frames, dataFrame)
 	}
 
+	// Trailers close the stream with a second HEADERS frame (RFC 9113 Section 8.1)
+	if len(req.Trailers) > 0 {
+		trailers := make(map[string]string, len(req.Trailers))
+		for name, value := range req.Trailers {
+			trailers[strings.ToLower(name)] = value
+		}
+		frames = append(frames, &HeadersFrame{
+			StreamId:   streamID,
+			Headers:    trailers,
+			EndHeaders: true,
+			EndStream:  true,
+		})
+	}
+
 	return frames, nil
 }
 
@@ -224,19 +259,20 @@ func (c *Converter) EncodeHeaders(headers map[string]string) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
-// DecodeHeaders decodes HPACK-encoded headers
+// DecodeHeaders decodes HPACK-encoded headers. Repeated fields collapse to the
+// last value; use DecodeHeaderFields to keep duplicates and wire order.
 func (c *Converter) DecodeHeaders(data []byte) (map[string]string, error) {
-	headers := make(map[string]string)
-	fields, err := c.decoder.DecodeFull(data)
+	fields, err := c.DecodeHeaderFields(data)
 	if err != nil {
 		return nil, err
 	}
+	return c.headerFieldsToMap(fields), nil
+}
 
-	for _, field := range fields {
-		headers[field.Name] = field.Value
-	}
-
-	return headers, nil
+// DecodeHeaderFields decodes HPACK-encoded headers into the exact field list, in
+// wire order and with duplicates preserved.
+func (c *Converter) DecodeHeaderFields(data []byte) ([]hpack.HeaderField, error) {
+	return c.decoder.DecodeFull(data)
 }
 
 // ParseHTTP11Request parses a raw HTTP/1.1 request (public for debugging)
@@ -254,6 +290,7 @@ func (c *Converter) parseHTTP11Request(raw []byte) (*Request, error) {
 		return nil, fmt.Errorf("failed to read request line: %w", err)
 	}
 
+	head := raw[len(requestLine):]
 	requestLine = strings.TrimSpace(requestLine)
 	parts := strings.Fields(requestLine)
 	if len(parts) < 2 {
@@ -263,7 +300,8 @@ func (c *Converter) parseHTTP11Request(raw []byte) (*Request, error) {
 	method := parts[0]
 	path := parts[1]
 
-	// Parse headers
+	// Parse headers, remembering their order
+	order := headerOrder(head)
 	tp := textproto.NewReader(reader)
 	mimeHeaders, err := tp.ReadMIMEHeader()
 	if err != nil && err != io.EOF {
@@ -291,17 +329,110 @@ func (c *Converter) parseHTTP11Request(raw []byte) (*Request, error) {
 	// Read body
 	body, _ := io.ReadAll(reader)
 
+	// HTTP/2 has no chunked coding: a chunked body is sent de-chunked and its
+	// trailer section becomes request trailers. A body that is not valid chunked
+	// coding is sent as-is.
+	var trailers map[string]string
+	if te, ok := headers["Transfer-Encoding"]; ok && strings.Contains(strings.ToLower(te), "chunked") {
+		if data, t, err := dechunk(body); err == nil {
+			body, trailers = data, t
+		}
+	}
+
 	return &Request{
-		Method:    method,
-		Path:      path,
-		Authority: authority,
-		Scheme:    reqScheme,
-		Headers:   headers,
-		Body:      body,
-		RawText:   raw,
+		Method:      method,
+		Path:        path,
+		Authority:   authority,
+		Scheme:      reqScheme,
+		Headers:     headers,
+		HeaderOrder: order,
+		Body:        body,
+		Trailers:    trailers,
+		RawText:     raw,
 	}, nil
 }
 
+// headerOrder returns the canonical names of the header section at the start of
+// head in order of first appearance.
+func headerOrder(head []byte) []string {
+	var order []string
+	seen := make(map[string]bool)
+	for len(head) > 0 {
+		line := head
+		if i := bytes.IndexByte(head, '\n'); i >= 0 {
+			line, head = head[:i], head[i+1:]
+		} else {
+			head = nil
+		}
+		line = bytes.TrimRight(line, "\r")
+		if len(line) == 0 {
+			break
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			continue // obsolete line folding
+		}
+		name, _, ok := bytes.Cut(line, []byte(":"))
+		if !ok {
+			continue
+		}
+		key := textproto.CanonicalMIMEHeaderKey(string(bytes.TrimSpace(name)))
+		if !seen[key] {
+			seen[key] = true
+			order = append(order, key)
+		}
+	}
+	return order
+}
+
+// dechunk decodes a chunked request body, returning the data and the trailer
+// fields (nil if there are none).
+func dechunk(body []byte) ([]byte, map[string]string, error) {
+	reader := bufio.NewReader(bytes.NewReader(body))
+	var data []byte
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, nil, fmt.Errorf("reading chunk size: %w", err)
+		}
+		sizeStr := strings.TrimSpace(strings.SplitN(line, ";", 2)[0])
+		size, err := strconv.ParseInt(sizeStr, 16, 64)
+		if err != nil || size < 0 {
+			return nil, nil, fmt.Errorf("invalid chunk size %q", sizeStr)
+		}
+		if size == 0 {
+			break
+		}
+		chunk := make([]byte, size)
+		if _, err := io.ReadFull(reader, chunk); err != nil {
+			return nil, nil, fmt.Errorf("reading chunk: %w", err)
+		}
+		data = append(data, chunk...)
+		if line, err := reader.ReadString('\n'); err != nil || strings.TrimSpace(line) != "" {
+			return nil, nil, fmt.Errorf("missing CRLF after chunk")
+		}
+	}
+
+	var trailers map[string]string
+	for {
+		line, err := reader.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			// Blank line or end of input terminates the trailer section
+			break
+		}
+		if parts := strings.SplitN(line, ":", 2); len(parts) == 2 {
+			if trailers == nil {
+				trailers = make(map[string]string)
+			}
+			trailers[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+		}
+		if err != nil {
+			break
+		}
+	}
+	return data, trailers, nil
+}
+
 // isConnectionSpecificHeader checks if a header is connection-specific
 func (c *Converter) isConnectionSpecificHeader(name string) bool {
 	connectionHeaders := []string{
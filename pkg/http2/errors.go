@@ -24,6 +24,10 @@ var (
 	// errStreamIDExhausted indicates the connection ran out of client stream IDs
 	// (2^31-1) and must be replaced with a new connection.
 	errStreamIDExhausted = stderrors.New("http2: stream ID exhausted")
+
+	// errNoPingAck indicates the peer did not acknowledge a health-check PING in
+	// time; the connection is presumed dead.
+	errNoPingAck = stderrors.New("http2: no PING ACK from peer")
 )
 
 // wrapStaleHTTP2Error wraps a cause with a stable, stale-classifiable message so
@@ -49,7 +53,8 @@ func IsStaleConnError(err error) bool {
 	// Wrapped sentinels (same-package callers).
 	if stderrors.Is(err, errConnClosed) ||
 		stderrors.Is(err, errGoAway) ||
-		stderrors.Is(err, errStreamIDExhausted) {
+		stderrors.Is(err, errStreamIDExhausted) ||
+		stderrors.Is(err, errNoPingAck) {
 		return true
 	}
 
@@ -68,6 +73,7 @@ func IsStaleConnError(err error) bool {
 		"connection closed before response complete",
 		"server sent goaway",
 		"stream id exhausted",
+		"no ping ack from peer",
 		"broken pipe",
 		"connection reset by peer",
 		"use of closed network connection",
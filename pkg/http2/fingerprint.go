@@ -0,0 +1,296 @@
+package http2
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+// Fingerprint is a client HTTP/2 profile: how the connection preface and the
+// request HEADERS look on the wire. Servers and bot-detection systems identify
+// clients by these details; a profile reproduces those of a given client. The
+// first four fields are what the Akamai fingerprint format captures (see
+// ParseAkamaiFingerprint and String).
+type Fingerprint struct {
+	// Settings are sent in the initial SETTINGS frame, in this order, instead of
+	// the set derived from Options.
+	Settings []http2.Setting
+
+	// WindowUpdate is the connection-level WINDOW_UPDATE increment sent right
+	// after the SETTINGS frame (0 sends none).
+	WindowUpdate uint32
+
+	// Priorities are PRIORITY frames sent after the preface. Request streams
+	// start above the highest stream ID used here.
+	Priorities []StreamPriority
+
+	// PseudoHeaderOrder lists the request pseudo-headers in the order they are
+	// sent (default: :method, :path, :scheme, :authority).
+	PseudoHeaderOrder []string
+
+	// HeaderPriority is sent with every request HEADERS frame (nil: none).
+	HeaderPriority *PriorityParam
+
+	// HeaderOrder lists lowercase header names that are sent first, in this
+	// order. Other headers follow in the order of the raw request.
+	HeaderOrder []string
+}
+
+// StreamPriority is a PRIORITY frame sent for a stream.
+type StreamPriority struct {
+	StreamID uint32
+	PriorityParam
+}
+
+// defaultPseudoHeaderOrder is the pseudo-header order without a fingerprint.
+var defaultPseudoHeaderOrder = []string{":method", ":path", ":scheme", ":authority"}
+
+// pseudoHeaderLetters maps the Akamai pseudo-header abbreviations.
+var pseudoHeaderLetters = map[string]string{
+	"m": ":method",
+	"a": ":authority",
+	"s": ":scheme",
+	"p": ":path",
+}
+
+// profiles are the built-in fingerprints, modeled on recent desktop browsers.
+var profiles = map[string]string{
+	"chrome":  "1:65536;2:0;4:6291456;6:262144|15663105|0|m,a,s,p",
+	"firefox": "1:65536;2:0;4:131072;5:16384|12517377|0|m,p,a,s",
+	"safari":  "2:0;3:100;4:2097152;9:1|10420225|0|m,s,a,p",
+}
+
+// profileHeaders holds the parts of the built-in profiles the Akamai format
+// does not capture.
+var profileHeaders = map[string]struct {
+	priority *PriorityParam
+	order    []string
+}{
+	"chrome": {
+		priority: &PriorityParam{Exclusive: true, Weight: 255},
+		order: []string{"cache-control", "sec-ch-ua", "sec-ch-ua-mobile", "sec-ch-ua-platform",
+			"upgrade-insecure-requests", "user-agent", "accept", "origin", "sec-fetch-site",
+			"sec-fetch-mode", "sec-fetch-user", "sec-fetch-dest", "referer", "accept-encoding",
+			"accept-language", "cookie", "priority"},
+	},
+	"firefox": {
+		priority: &PriorityParam{Weight: 41},
+		order: []string{"user-agent", "accept", "accept-language", "accept-encoding", "referer",
+			"origin", "cookie", "upgrade-insecure-requests", "sec-fetch-dest", "sec-fetch-mode",
+			"sec-fetch-site", "sec-fetch-user", "priority", "te"},
+	},
+	"safari": {
+		priority: &PriorityParam{Weight: 254},
+		order: []string{"accept", "sec-fetch-site", "cookie", "sec-fetch-dest", "accept-language",
+			"sec-fetch-mode", "origin", "user-agent", "referer", "accept-encoding", "priority"},
+	},
+}
+
+// Profile returns a copy of a built-in fingerprint: "chrome", "firefox" or
+// "safari".
+func Profile(name string) (*Fingerprint, bool) {
+	akamai, ok := profiles[strings.ToLower(name)]
+	if !ok {
+		return nil, false
+	}
+	fp, err := ParseAkamaiFingerprint(akamai)
+	if err != nil {
+		panic("http2: invalid built-in profile " + name + ": " + err.Error())
+	}
+	headers := profileHeaders[strings.ToLower(name)]
+	priority := *headers.priority
+	fp.HeaderPriority = &priority
+	fp.HeaderOrder = append([]string(nil), headers.order...)
+	return fp, true
+}
+
+// ParseFingerprint returns the built-in profile named s, or parses s as an
+// Akamai fingerprint.
+func ParseFingerprint(s string) (*Fingerprint, error) {
+	if fp, ok := Profile(s); ok {
+		return fp, nil
+	}
+	return ParseAkamaiFingerprint(s)
+}
+
+// ParseAkamaiFingerprint parses a fingerprint in the Akamai format
+// "SETTINGS|WINDOW_UPDATE|PRIORITY|PSEUDO_HEADER_ORDER", for example
+// "1:65536;2:0;4:6291456;6:262144|15663105|0|m,a,s,p":
+//
+//   - SETTINGS: "id:value" pairs separated by ";", in order
+//   - WINDOW_UPDATE: the connection window increment, 0 for none
+//   - PRIORITY: "stream:exclusive:dependency:weight" frames separated by ",",
+//     0 for none; weight is 1-256
+//   - PSEUDO_HEADER_ORDER: m (:method), a (:authority), s (:scheme) and
+//     p (:path) separated by ","
+func ParseAkamaiFingerprint(s string) (*Fingerprint, error) {
+	parts := strings.Split(strings.TrimSpace(s), "|")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("akamai fingerprint %q: expected 4 parts separated by |", s)
+	}
+	fp := &Fingerprint{}
+
+	if parts[0] != "" {
+		for _, pair := range strings.Split(parts[0], ";") {
+			id, val, ok := strings.Cut(pair, ":")
+			if !ok {
+				return nil, fmt.Errorf("akamai fingerprint: invalid setting %q", pair)
+			}
+			n, err := strconv.ParseUint(id, 10, 16)
+			if err != nil {
+				return nil, fmt.Errorf("akamai fingerprint: invalid setting ID %q", id)
+			}
+			v, err := strconv.ParseUint(val, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("akamai fingerprint: invalid value for setting %s: %q", id, val)
+			}
+			setting := http2.Setting{ID: http2.SettingID(n), Val: uint32(v)}
+			if err := setting.Valid(); err != nil {
+				return nil, fmt.Errorf("akamai fingerprint: %w", err)
+			}
+			fp.Settings = append(fp.Settings, setting)
+		}
+	}
+
+	wu, err := strconv.ParseUint(parts[1], 10, 31)
+	if err != nil {
+		return nil, fmt.Errorf("akamai fingerprint: invalid window update %q", parts[1])
+	}
+	fp.WindowUpdate = uint32(wu)
+
+	if parts[2] != "0" && parts[2] != "" {
+		for _, frame := range strings.Split(parts[2], ",") {
+			p, err := parseAkamaiPriority(frame)
+			if err != nil {
+				return nil, err
+			}
+			fp.Priorities = append(fp.Priorities, p)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, letter := range strings.Split(parts[3], ",") {
+		name, ok := pseudoHeaderLetters[letter]
+		if !ok || seen[name] {
+			return nil, fmt.Errorf("akamai fingerprint: invalid pseudo-header order %q", parts[3])
+		}
+		seen[name] = true
+		fp.PseudoHeaderOrder = append(fp.PseudoHeaderOrder, name)
+	}
+	if len(fp.PseudoHeaderOrder) != len(pseudoHeaderLetters) {
+		return nil, fmt.Errorf("akamai fingerprint: pseudo-header order %q must list m, a, s and p", parts[3])
+	}
+	return fp, nil
+}
+
+func parseAkamaiPriority(frame string) (StreamPriority, error) {
+	fields := strings.Split(frame, ":")
+	if len(fields) != 4 {
+		return StreamPriority{}, fmt.Errorf("akamai fingerprint: invalid priority frame %q", frame)
+	}
+	var n [4]uint64
+	for i, f := range fields {
+		v, err := strconv.ParseUint(f, 10, 31)
+		if err != nil {
+			return StreamPriority{}, fmt.Errorf("akamai fingerprint: invalid priority frame %q", frame)
+		}
+		n[i] = v
+	}
+	if n[0] == 0 || n[1] > 1 || n[3] < 1 || n[3] > 256 {
+		return StreamPriority{}, fmt.Errorf("akamai fingerprint: invalid priority frame %q", frame)
+	}
+	return StreamPriority{
+		StreamID: uint32(n[0]),
+		PriorityParam: PriorityParam{
+			Exclusive:        n[1] == 1,
+			StreamDependency: uint32(n[2]),
+			Weight:           uint8(n[3] - 1), // the wire carries weight-1
+		},
+	}, nil
+}
+
+// String returns the Akamai form of the fingerprint. HeaderPriority and
+// HeaderOrder are not part of it.
+func (f *Fingerprint) String() string {
+	settings := make([]string, len(f.Settings))
+	for i, s := range f.Settings {
+		settings[i] = fmt.Sprintf("%d:%d", s.ID, s.Val)
+	}
+
+	priorities := "0"
+	if len(f.Priorities) > 0 {
+		frames := make([]string, len(f.Priorities))
+		for i, p := range f.Priorities {
+			frames[i] = fmt.Sprintf("%d:%d:%d:%d", p.StreamID, boolToUint32(p.Exclusive), p.StreamDependency, int(p.Weight)+1)
+		}
+		priorities = strings.Join(frames, ",")
+	}
+
+	pseudo := make([]string, len(f.pseudoHeaderOrder()))
+	for i, name := range f.pseudoHeaderOrder() {
+		pseudo[i] = name[1:2]
+	}
+
+	return fmt.Sprintf("%s|%d|%s|%s", strings.Join(settings, ";"), f.WindowUpdate, priorities, strings.Join(pseudo, ","))
+}
+
+// poolKey identifies the profile in a connection pool key: the Akamai form plus
+// the header layout, which the connection applies to every request.
+func (f *Fingerprint) poolKey() string {
+	key := f.String()
+	if p := f.HeaderPriority; p != nil {
+		key += fmt.Sprintf("|hp=%d:%d:%d", boolToUint32(p.Exclusive), p.StreamDependency, int(p.Weight)+1)
+	}
+	if len(f.HeaderOrder) > 0 {
+		key += "|ho=" + strings.Join(f.HeaderOrder, ",")
+	}
+	return key
+}
+
+// pseudoHeaderOrder returns the pseudo-header order to send; f may be nil.
+func (f *Fingerprint) pseudoHeaderOrder() []string {
+	if f == nil || len(f.PseudoHeaderOrder) == 0 {
+		return defaultPseudoHeaderOrder
+	}
+	return f.PseudoHeaderOrder
+}
+
+// firstStreamID returns the first stream ID for requests: above every stream the
+// preface's PRIORITY frames used.
+func (f *Fingerprint) firstStreamID() uint32 {
+	id := uint32(1)
+	if f == nil {
+		return id
+	}
+	for _, p := range f.Priorities {
+		if p.StreamID >= id {
+			id = p.StreamID | 1 + 2
+		}
+	}
+	return id
+}
+
+// orderHeaders sorts regular header fields by the profile's HeaderOrder; fields
+// it does not list keep their relative order after the listed ones.
+func (f *Fingerprint) orderHeaders(fields []hpack.HeaderField) {
+	if f == nil || len(f.HeaderOrder) == 0 {
+		return
+	}
+	rank := make(map[string]int, len(f.HeaderOrder))
+	for i, name := range f.HeaderOrder {
+		rank[strings.ToLower(name)] = i
+	}
+	position := func(name string) int {
+		if i, ok := rank[name]; ok {
+			return i
+		}
+		return len(f.HeaderOrder)
+	}
+	sort.SliceStable(fields, func(i, j int) bool {
+		return position(fields[i].Name) < position(fields[j].Name)
+	})
+}
@@ -0,0 +1,214 @@
+package http2
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"time"
+
+	"github.com/WhileEndless/go-rawhttp/pkg/errors"
+	"golang.org/x/net/http2"
+)
+
+// Send-side flow control (RFC 9113 Section 5.2 and 6.9). Stream.PeerWindowSize and
+// Connection.PeerWindowSize hold the credit the peer granted us; DATA is only
+// written while both are positive. All window fields are guarded by conn.mu.
+
+const (
+	// defaultPeerWindow is the initial send window of the connection and of every
+	// stream until the peer's SETTINGS_INITIAL_WINDOW_SIZE says otherwise.
+	defaultPeerWindow = 65535
+
+	// defaultPeerMaxFrameSize is the largest DATA payload we may send until the
+	// peer announces SETTINGS_MAX_FRAME_SIZE.
+	defaultPeerMaxFrameSize = 16384
+
+	maxWindowSize = 1<<31 - 1
+)
+
+// errPeerDone reports that the peer reset or finished the stream while its request
+// body was still being written; the remaining body is dropped.
+var errPeerDone = stderrors.New("http2: peer closed stream before request body was sent")
+
+// notifyFlowLocked wakes every writer waiting for send credit. The caller MUST
+// hold conn.mu.
+func (c *Connection) notifyFlowLocked() {
+	if c.flowCh != nil {
+		close(c.flowCh)
+	}
+	c.flowCh = make(chan struct{})
+}
+
+// applyPeerSettings records a SETTINGS frame from the peer. A changed
+// SETTINGS_INITIAL_WINDOW_SIZE adjusts the send window of every open stream by
+// the difference (which may make it negative); SETTINGS_HEADER_TABLE_SIZE caps
+// the HPACK encoder's dynamic table.
+func (c *Connection) applyPeerSettings(f *http2.SettingsFrame) error {
+	if err := c.recordPeerSettings(f); err != nil {
+		return err
+	}
+	// The encoder belongs to the writers; writeMu is never taken while holding mu.
+	if size, ok := f.Value(http2.SettingHeaderTableSize); ok && c.Encoder != nil {
+		c.writeMu.Lock()
+		c.Encoder.SetMaxDynamicTableSizeLimit(size)
+		c.writeMu.Unlock()
+	}
+	return nil
+}
+
+// recordPeerSettings applies the settings that live under mu.
+func (c *Connection) recordPeerSettings(f *http2.SettingsFrame) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	err := f.ForeachSetting(func(s http2.Setting) error {
+		if err := s.Valid(); err != nil {
+			return err
+		}
+		c.PeerSettings[s.ID] = s.Val
+		switch s.ID {
+		case http2.SettingInitialWindowSize:
+			delta := int32(s.Val) - c.peerInitialWindow
+			for _, st := range c.Streams {
+				if int64(st.PeerWindowSize)+int64(delta) > maxWindowSize {
+					return http2.ConnectionError(http2.ErrCodeFlowControl)
+				}
+				st.PeerWindowSize += delta
+			}
+			c.peerInitialWindow = int32(s.Val)
+		case http2.SettingMaxFrameSize:
+			c.peerMaxFrameSize = s.Val
+		case http2.SettingMaxConcurrentStreams:
+			c.MaxConcurrent = s.Val
+		}
+		return nil
+	})
+	c.notifyFlowLocked()
+	return err
+}
+
+// addSendWindow applies a WINDOW_UPDATE from the peer. Stream 0 is the
+// connection window. Updates for unknown streams are ignored.
+func (c *Connection) addSendWindow(streamID, increment uint32) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	window := &c.PeerWindowSize
+	if streamID != 0 {
+		st := c.Streams[streamID]
+		if st == nil {
+			return nil
+		}
+		window = &st.PeerWindowSize
+	}
+	if int64(*window)+int64(increment) > maxWindowSize {
+		return fmt.Errorf("window update overflows send window of stream %d: %w",
+			streamID, http2.ConnectionError(http2.ErrCodeFlowControl))
+	}
+	*window += int32(increment)
+	c.notifyFlowLocked()
+	return nil
+}
+
+// markPeerDone records that the peer reset or ended a stream, so a writer still
+// sending its request body stops.
+func (c *Connection) markPeerDone(streamID uint32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if st := c.Streams[streamID]; st != nil && !st.peerDone {
+		st.peerDone = true
+		c.notifyFlowLocked()
+	}
+}
+
+// reserveSend blocks until the peer grants send credit for the stream and
+// reserves up to want bytes, bounded by both windows and the peer's maximum
+// frame size. Each wait for credit is bounded by timeout (0 = unbounded).
+func (c *Connection) reserveSend(ctx context.Context, stream *Stream, want int, timeout time.Duration) (int, error) {
+	for {
+		c.mu.Lock()
+		if c.Closed {
+			err := c.connErr
+			c.mu.Unlock()
+			if err == nil {
+				err = wrapStaleHTTP2Error("sending request body", errConnClosed)
+			}
+			return 0, err
+		}
+		if stream.peerDone {
+			c.mu.Unlock()
+			return 0, errPeerDone
+		}
+
+		n := want
+		maxFrame := int(c.peerMaxFrameSize)
+		if maxFrame == 0 {
+			maxFrame = defaultPeerMaxFrameSize
+		}
+		n = min(n, maxFrame, int(stream.PeerWindowSize), int(c.PeerWindowSize))
+		if n > 0 {
+			stream.PeerWindowSize -= int32(n)
+			c.PeerWindowSize -= int32(n)
+			c.mu.Unlock()
+			return n, nil
+		}
+		if c.flowCh == nil {
+			c.flowCh = make(chan struct{})
+		}
+		wait := c.flowCh
+		c.mu.Unlock()
+
+		if err := c.waitFlow(ctx, wait, timeout); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// waitFlow waits until wait is closed (credit may have grown), the connection
+// dies (reserveSend then reports its error), ctx is done, or timeout elapses.
+func (c *Connection) waitFlow(ctx context.Context, wait <-chan struct{}, timeout time.Duration) error {
+	var expired <-chan time.Time
+	if timeout > 0 {
+		t := time.NewTimer(timeout)
+		defer t.Stop()
+		expired = t.C
+	}
+	select {
+	case <-wait:
+	case <-c.closedCh:
+	case <-ctx.Done():
+		if ctx.Err() == context.DeadlineExceeded {
+			return errors.NewTimeoutError("waiting for HTTP/2 send window", 0)
+		}
+		return errors.NewProtocolError("context cancelled", ctx.Err())
+	case <-expired:
+		return errors.NewTimeoutError("waiting for HTTP/2 send window", timeout)
+	}
+	return nil
+}
+
+// writeData writes a request body as DATA frames, honoring the peer's flow-control
+// windows and maximum frame size. endStream is set on the last frame.
+func (c *Client) writeData(ctx context.Context, conn *Connection, stream *Stream, data []byte, endStream bool, timeout time.Duration) error {
+	if len(data) == 0 {
+		return c.sendFrame(conn, &DataFrame{StreamId: stream.ID, EndStream: endStream})
+	}
+	for len(data) > 0 {
+		n, err := conn.reserveSend(ctx, stream, len(data), timeout)
+		if err != nil {
+			return err
+		}
+		chunk := &DataFrame{
+			StreamId:  stream.ID,
+			Data:      data[:n],
+			EndStream: endStream && n == len(data),
+		}
+		if err := c.sendFrame(conn, chunk); err != nil {
+			c.transport.removeConnection(conn)
+			conn.fail(wrapStaleHTTP2Error("sending frame", err))
+			return wrapStaleHTTP2Error("sending frame", err)
+		}
+		data = data[n:]
+	}
+	return nil
+}
@@ -13,13 +13,19 @@ import (
 type FrameHandler struct {
 	framer    *http2.Framer
 	converter *Converter
+
+	// MaxHeaderListSize bounds a header block reassembled from CONTINUATION
+	// frames by ReadFrame (default 10MB), so an endless sequence cannot exhaust
+	// memory.
+	MaxHeaderListSize uint32
 }
 
 // NewFrameHandler creates a new frame handler
 func NewFrameHandler(rw io.ReadWriter) *FrameHandler {
 	return &FrameHandler{
-		framer:    http2.NewFramer(rw, rw),
-		converter: NewConverter(),
+		framer:            http2.NewFramer(rw, rw),
+		converter:         NewConverter(),
+		MaxHeaderListSize: defaultMaxHeaderBlock,
 	}
 }
 
@@ -54,7 +60,10 @@ func (h *FrameHandler) ReadFrame() (Frame, error) {
 
 	switch f := rawFrame.(type) {
 	case *http2.HeadersFrame:
-		return h.convertHeadersFrame(f)
+		if !f.HeadersEnded() {
+			return h.readContinuations(f)
+		}
+		return h.convertHeadersFrame(f, f.HeaderBlockFragment())
 	case *http2.DataFrame:
 		return h.convertDataFrame(f)
 	default:
@@ -68,7 +77,8 @@ func (h *FrameHandler) ReadFrame() (Frame, error) {
 	}
 }
 
-// sendHeadersFrame sends a HEADERS frame
+// sendHeadersFrame sends a HEADERS frame, followed by CONTINUATION frames when
+// the encoded block exceeds the default SETTINGS_MAX_FRAME_SIZE
 func (h *FrameHandler) sendHeadersFrame(frame *HeadersFrame) error {
 	// Encode headers using HPACK
 	encodedHeaders, err := h.converter.EncodeHeaders(frame.Headers)
@@ -76,20 +86,58 @@ func (h *FrameHandler) sendHeadersFrame(frame *HeadersFrame) error {
 		return fmt.Errorf("failed to encode headers: %w", err)
 	}
 
-	// Calculate frame size
-	frameSize := len(encodedHeaders)
-	if frame.Priority != nil {
-		frameSize += 5 // Priority fields
+	param := http2.HeadersFrameParam{
+		StreamID:      frame.StreamId,
+		BlockFragment: encodedHeaders,
+		EndStream:     frame.EndStream,
+		EndHeaders:    frame.EndHeaders,
+		PadLength:     frame.PadLength,
+		Priority:      convertPriority(frame.Priority),
 	}
-	if frame.PadLength > 0 {
-		frameSize += int(frame.PadLength) + 1
+	if err := writeHeaderBlock(h.framer, param, defaultPeerMaxFrameSize); err != nil {
+		return fmt.Errorf("failed to write headers frame: %w", err)
 	}
 
-	// Write frame using raw frame method
-	if err := h.framer.WriteRawFrame(http2.FrameHeaders, frame.Flags(), frame.StreamId, encodedHeaders); err != nil {
-		return fmt.Errorf("failed to write headers frame: %w", err)
+	return nil
+}
+
+// writeHeaderBlock writes an encoded header block as a HEADERS frame followed by
+// as many CONTINUATION frames as needed to keep every frame payload within
+// maxFrameSize (RFC 9113 Section 4.3). END_HEADERS is set on the last frame only
+// if p.EndHeaders is set.
+func writeHeaderBlock(fr *http2.Framer, p http2.HeadersFrameParam, maxFrameSize int) error {
+	if maxFrameSize <= 0 {
+		maxFrameSize = defaultPeerMaxFrameSize
+	}
+
+	// The first frame also carries the priority fields and padding.
+	first := maxFrameSize
+	if !p.Priority.IsZero() {
+		first -= 5
+	}
+	if p.PadLength > 0 {
+		first -= int(p.PadLength) + 1
+	}
+	first = max(first, 1)
+
+	block := p.BlockFragment
+	endHeaders := p.EndHeaders
+	if len(block) > first {
+		p.BlockFragment = block[:first]
+		p.EndHeaders = false
+	}
+	if err := fr.WriteHeaders(p); err != nil {
+		return err
 	}
 
+	rest := block[len(p.BlockFragment):]
+	for len(rest) > 0 {
+		n := min(len(rest), maxFrameSize)
+		if err := fr.WriteContinuation(p.StreamID, endHeaders && n == len(rest), rest[:n]); err != nil {
+			return err
+		}
+		rest = rest[n:]
+	}
 	return nil
 }
 
@@ -109,17 +157,53 @@ func (h *FrameHandler) sendDataFrame(frame *DataFrame) error {
 	return nil
 }
 
-// convertHeadersFrame converts http2.HeadersFrame to our HeadersFrame
-func (h *FrameHandler) convertHeadersFrame(f *http2.HeadersFrame) (*HeadersFrame, error) {
+// readContinuations reads the CONTINUATION frames that complete a HEADERS frame
+// and returns the reassembled frame. The Framer rejects any other frame in the
+// sequence with a connection error; a block over MaxHeaderListSize is an error.
+func (h *FrameHandler) readContinuations(f *http2.HeadersFrame) (Frame, error) {
+	limit := h.MaxHeaderListSize
+	if limit == 0 {
+		limit = defaultMaxHeaderBlock
+	}
+	// Copy: the Framer reuses its read buffer on the next ReadFrame.
+	block := append([]byte(nil), f.HeaderBlockFragment()...)
+	for {
+		next, err := h.framer.ReadFrame()
+		if err != nil {
+			return nil, err
+		}
+		cf, ok := next.(*http2.ContinuationFrame)
+		if !ok || cf.StreamID != f.StreamID {
+			return nil, fmt.Errorf("expected CONTINUATION for stream %d, got %v", f.StreamID, next.Header())
+		}
+		block = append(block, cf.HeaderBlockFragment()...)
+		if uint32(len(block)) > limit {
+			return nil, fmt.Errorf("header block on stream %d exceeds %d bytes", f.StreamID, limit)
+		}
+		if cf.HeadersEnded() {
+			frame, err := h.convertHeadersFrame(f, block)
+			if err != nil {
+				return nil, err
+			}
+			frame.EndHeaders = true
+			return frame, nil
+		}
+	}
+}
+
+// convertHeadersFrame converts http2.HeadersFrame (with its complete header block)
+// to our HeadersFrame
+func (h *FrameHandler) convertHeadersFrame(f *http2.HeadersFrame, block []byte) (*HeadersFrame, error) {
 	// Decode headers
-	headers, err := h.converter.DecodeHeaders(f.HeaderBlockFragment())
+	fields, err := h.converter.DecodeHeaderFields(block)
 	if err != nil {
 		return nil, fmt.Errorf("failed to decode headers: %w", err)
 	}
 
 	frame := &HeadersFrame{
 		StreamId:   f.StreamID,
-		Headers:    headers,
+		Headers:    h.converter.headerFieldsToMap(fields),
+		Fields:     fields,
 		EndStream:  f.StreamEnded(),
 		EndHeaders: f.HeadersEnded(),
 	}
@@ -145,6 +229,7 @@ func (h *FrameHandler) convertDataFrame(f *http2.DataFrame) (*DataFrame, error)
 		StreamId:  f.StreamID,
 		Data:      data,
 		EndStream: f.StreamEnded(),
+		Length:    len(data),
 	}, nil
 }
 
@@ -250,6 +335,91 @@ func (b *RawFrameBuilder) BuildGoAwayFrame(lastStreamID uint32, errorCode uint32
 	return b.BuildFrame(http2.FrameGoAway, 0, 0, payload.Bytes())
 }
 
+// BuildHeadersFrame builds a HEADERS frame carrying an HPACK-encoded header block
+// fragment
+func (b *RawFrameBuilder) BuildHeadersFrame(streamID uint32, fragment []byte, endStream, endHeaders bool) []byte {
+	flags := http2.Flags(0)
+	if endStream {
+		flags |= http2.FlagHeadersEndStream
+	}
+	if endHeaders {
+		flags |= http2.FlagHeadersEndHeaders
+	}
+
+	return b.BuildFrame(http2.FrameHeaders, flags, streamID, fragment)
+}
+
+// BuildContinuationFrame builds a CONTINUATION frame
+func (b *RawFrameBuilder) BuildContinuationFrame(streamID uint32, fragment []byte, endHeaders bool) []byte {
+	flags := http2.Flags(0)
+	if endHeaders {
+		flags = http2.FlagContinuationEndHeaders
+	}
+
+	return b.BuildFrame(http2.FrameContinuation, flags, streamID, fragment)
+}
+
+// ContinuationOptions controls how BuildHeaderBlock splits a header block into a
+// HEADERS frame and CONTINUATION frames. The zero value produces a valid sequence;
+// the other fields craft malformed ones for testing how servers handle them.
+type ContinuationOptions struct {
+	// FragmentSize is the header block size per frame (default 16384)
+	FragmentSize int
+
+	// EndStream sets END_STREAM on the HEADERS frame
+	EndStream bool
+
+	// OmitEndHeaders never sets END_HEADERS, leaving the sequence unterminated
+	OmitEndHeaders bool
+
+	// ContinuationStreamID sends the CONTINUATION frames on another stream
+	// (0 = same stream as the HEADERS frame)
+	ContinuationStreamID uint32
+
+	// EmptyContinuations appends that many CONTINUATION frames with an empty
+	// payload before the sequence ends (a CONTINUATION flood with a large value)
+	EmptyContinuations int
+
+	// Interleave is raw frame bytes (e.g. a PING or DATA frame) inserted right
+	// after the HEADERS frame, which RFC 9113 forbids
+	Interleave []byte
+}
+
+// BuildHeaderBlock builds a HEADERS frame followed by CONTINUATION frames for an
+// HPACK-encoded header block and returns the concatenated bytes
+func (b *RawFrameBuilder) BuildHeaderBlock(streamID uint32, block []byte, opts ContinuationOptions) []byte {
+	size := opts.FragmentSize
+	if size <= 0 {
+		size = defaultPeerMaxFrameSize
+	}
+	contStreamID := opts.ContinuationStreamID
+	if contStreamID == 0 {
+		contStreamID = streamID
+	}
+
+	// Split the block; the empty continuations go after the last fragment.
+	fragments := [][]byte{block[:min(size, len(block))]}
+	for rest := block[len(fragments[0]):]; len(rest) > 0; rest = rest[min(size, len(rest)):] {
+		fragments = append(fragments, rest[:min(size, len(rest))])
+	}
+	for i := 0; i < opts.EmptyContinuations; i++ {
+		fragments = append(fragments, nil)
+	}
+
+	var out bytes.Buffer
+	for i, fragment := range fragments {
+		endHeaders := i == len(fragments)-1 && !opts.OmitEndHeaders
+		if i == 0 {
+			out.Write(b.BuildHeadersFrame(streamID, fragment, opts.EndStream, endHeaders))
+			out.Write(opts.Interleave)
+			continue
+		}
+		out.Write(b.BuildContinuationFrame(contStreamID, fragment, endHeaders))
+	}
+
+	return out.Bytes()
+}
+
 // ParseFrame parses a raw frame
 func ParseFrame(data []byte) (*http2.FrameHeader, []byte, error) {
 	if len(data) < 9 {
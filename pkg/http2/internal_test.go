@@ -1,11 +1,16 @@
 package http2
 
 import (
+	"context"
 	stderrors "errors"
 	"fmt"
 	"io"
+	"net"
 	"syscall"
 	"testing"
+	"time"
+
+	"golang.org/x/net/http2"
 )
 
 // B4: stream-ID exhaustion on a connection must produce a stale-classified error
@@ -18,7 +23,7 @@ func TestOpenStream_Exhaustion(t *testing.T) {
 		closedCh:     make(chan struct{}),
 	}
 
-	_, err := c.openStream(conn, []byte("GET / HTTP/2\r\nHost: x\r\n\r\n"), &Request{})
+	_, err := c.openStream(conn, []byte("GET / HTTP/2\r\nHost: x\r\n\r\n"), &Request{}, false, nil)
 	if err == nil {
 		t.Fatal("expected stream-ID exhaustion error, got nil")
 	}
@@ -46,6 +51,7 @@ func TestIsStaleConnError(t *testing.T) {
 		{"econnreset", syscall.ECONNRESET, true},
 		{"wrapped conn closed", wrapStaleHTTP2Error("reading frame", errConnClosed), true},
 		{"wrapped goaway", wrapStaleHTTP2Error("goaway", errGoAway), true},
+		{"no ping ack", wrapStaleHTTP2Error("health check", errNoPingAck), true},
 		{"goaway string", fmt.Errorf("http2: server sent GOAWAY: x"), true},
 		{"reset string", fmt.Errorf("read tcp: connection reset by peer"), true},
 		{"unrelated", fmt.Errorf("some validation error"), false},
@@ -58,3 +64,67 @@ func TestIsStaleConnError(t *testing.T) {
 		})
 	}
 }
+
+// A pooled connection whose peer never acknowledges the health checker's PING is
+// evicted from the pool and failed with a stale-classified error.
+func TestProbe_EvictsUnresponsiveConnection(t *testing.T) {
+	client, peer := net.Pipe()
+	defer peer.Close()
+	go io.Copy(io.Discard, peer) // reads the PING, never answers
+
+	conn := &Connection{
+		Conn:        client,
+		PoolKey:     "example.com:443",
+		Streams:     make(map[uint32]*Stream),
+		closedCh:    make(chan struct{}),
+		pingTimeout: 50 * time.Millisecond,
+	}
+	conn.Framer = http2.NewFramer(frameWriter{conn}, client)
+	tr := &Transport{connections: map[string][]*Connection{conn.PoolKey: {conn}}}
+
+	tr.probe(conn)
+	tr.wg.Wait()
+
+	if !conn.isClosed() {
+		t.Fatal("unresponsive connection must be closed")
+	}
+	if _, ok := tr.connections[conn.PoolKey]; ok {
+		t.Fatal("unresponsive connection must be evicted from the pool")
+	}
+	if err := conn.closedErr(); !IsStaleConnError(err) {
+		t.Fatalf("expected a stale-classified error, got %v", err)
+	}
+}
+
+// A pooled connection approaching stream-ID exhaustion takes no new requests:
+// it is retired (and closed once idle) so the request dials a replacement.
+func TestAcquire_RetiresExhaustedConnection(t *testing.T) {
+	client, peer := net.Pipe()
+	defer peer.Close()
+	go io.Copy(io.Discard, peer)
+
+	conn := &Connection{
+		Conn:         client,
+		PoolKey:      "example.com:443",
+		Streams:      make(map[uint32]*Stream),
+		PeerSettings: make(map[http2.SettingID]uint32),
+		NextStreamID: retireStreamID + 2,
+		closedCh:     make(chan struct{}),
+	}
+	conn.Framer = http2.NewFramer(frameWriter{conn}, client)
+	tr := &Transport{
+		connections: map[string][]*Connection{conn.PoolKey: {conn}},
+		dialing:     make(map[string]int),
+	}
+
+	got, err := tr.acquire(context.Background(), conn.PoolKey, 0)
+	if err != nil || got != nil {
+		t.Fatalf("expected a new dial, got %v, %v", got, err)
+	}
+	if tr.dialing[conn.PoolKey] != 1 || len(tr.connections[conn.PoolKey]) != 0 {
+		t.Fatal("the retired connection must leave the pool and the dial be registered")
+	}
+	if !conn.isClosed() {
+		t.Fatal("an idle retired connection must be closed")
+	}
+}
@@ -0,0 +1,71 @@
+package http2
+
+import (
+	"strings"
+
+	"github.com/WhileEndless/go-rawhttp/pkg/errors"
+	"golang.org/x/net/http2/hpack"
+)
+
+// headerLimits are the MaxHeaderBytes and MaxHeaderCount of a stream (0 = no
+// limit).
+type headerLimits struct {
+	bytes int
+	count int
+}
+
+// newHeaderLimits returns the header limits of opts, which may be nil.
+func newHeaderLimits(opts *Options) headerLimits {
+	if opts == nil {
+		return headerLimits{}
+	}
+	return headerLimits{bytes: opts.MaxHeaderBytes, count: opts.MaxHeaderCount}
+}
+
+// decodeHeaderBlock decodes a complete header block, enforcing limits as the
+// fields are emitted. Every field counts its name and value plus 32 bytes toward
+// MaxHeaderBytes (RFC 9113 Section 6.5.2); pseudo-headers are not counted as
+// header fields. Once a limit is exceeded the rest of the block is still decoded,
+// keeping the HPACK state in sync, but its fields are not kept, so a small block
+// of references to a large table entry never materializes. fields holds the
+// fields within the limits and limitErr the limit exceeded; err is a decoding
+// error, fatal to the connection.
+func decodeHeaderBlock(dec *hpack.Decoder, block []byte, limits headerLimits) (fields []hpack.HeaderField, limitErr, err error) {
+	size, count := 0, 0
+	dec.SetEmitFunc(func(f hpack.HeaderField) {
+		if limitErr != nil {
+			return
+		}
+		size += int(f.Size())
+		if !strings.HasPrefix(f.Name, ":") {
+			count++
+		}
+		switch {
+		case limits.bytes > 0 && size > limits.bytes:
+			limitErr = errors.NewLimitError("MaxHeaderBytes", int64(limits.bytes))
+		case limits.count > 0 && count > limits.count:
+			limitErr = errors.NewLimitError("MaxHeaderCount", int64(limits.count))
+		default:
+			fields = append(fields, f)
+		}
+	})
+	defer dec.SetEmitFunc(func(hpack.HeaderField) {})
+
+	if _, err := dec.Write(block); err != nil {
+		return nil, nil, err
+	}
+	if err := dec.Close(); err != nil {
+		return nil, nil, err
+	}
+	return fields, limitErr, nil
+}
+
+// limitBody enforces MaxBodyBytes on a DATA payload that follows received body
+// bytes. It returns the part of data within the limit and, if data goes beyond
+// it, the limit error.
+func limitBody(opts *Options, received int64, data []byte) ([]byte, error) {
+	if opts == nil || opts.MaxBodyBytes <= 0 || received+int64(len(data)) <= opts.MaxBodyBytes {
+		return data, nil
+	}
+	return data[:max(opts.MaxBodyBytes-received, 0)], errors.NewLimitError("MaxBodyBytes", opts.MaxBodyBytes)
+}
@@ -0,0 +1,230 @@
+package http2
+
+import (
+	"bytes"
+	"context"
+	stderrors "errors"
+	"sync"
+	"time"
+
+	"github.com/WhileEndless/go-rawhttp/pkg/errors"
+	"github.com/WhileEndless/go-rawhttp/pkg/timing"
+	"golang.org/x/net/http2"
+)
+
+// StreamResult is the outcome of one request sent with DoMulti.
+type StreamResult struct {
+	Index      int       // Position of the request in the batch
+	StreamID   uint32    // Stream the request was sent on (0 if it was never opened)
+	Response   *Response // Response (nil if Err is set, unless Err is a limit error)
+	Err        error     // Error for this request only
+	SentAt     time.Time // When the last frame of the request was written
+	ReceivedAt time.Time // When the first response frame arrived
+}
+
+// DoMulti sends several requests concurrently as streams of a single HTTP/2
+// connection. All HEADERS frames are written back-to-back before any request body;
+// with opts.SinglePacket the final frame of every request is withheld and then
+// written in one TCP write. Results are returned in request order. The error is
+// only set when the connection cannot be established; per-request failures are
+// reported in StreamResult.Err. Requests are not retried.
+//
+// Each Response carries per-stream timings: Metrics.TTFB is measured from SentAt
+// to the first response frame, TotalTime from opening the stream.
+func (c *Client) DoMulti(ctx context.Context, reqs [][]byte, host string, port int, scheme string, opts *Options) ([]*StreamResult, error) {
+	if opts == nil {
+		opts = c.options
+	}
+	if len(reqs) == 0 {
+		return nil, errors.NewValidationError("no requests provided")
+	}
+
+	results := make([]*StreamResult, len(reqs))
+	requests := make([]*Request, len(reqs))
+	for i, raw := range reqs {
+		results[i] = &StreamResult{Index: i}
+		request, err := c.converter.parseHTTP11Request(raw)
+		if err != nil {
+			results[i].Err = errors.NewProtocolError("parsing request", err)
+			continue
+		}
+		if scheme != "" {
+			request.Scheme = scheme
+		}
+		if host != "" {
+			request.Authority = host
+		}
+		requests[i] = request
+	}
+
+	conn, err := c.transport.Connect(ctx, host, port, scheme, opts)
+	if err != nil {
+		return nil, errors.NewConnectionError(host, port, err)
+	}
+	if !opts.ReuseConnection {
+		defer conn.Close()
+	}
+	opts.Trace.TraceGotConn(conn.Conn, conn.wasReused(), conn.idleTime(), 0)
+
+	// Open every stream back-to-back so the HEADERS frames leave together.
+	streams := make([]*Stream, len(reqs))
+	for i, request := range requests {
+		if request == nil {
+			continue
+		}
+		stream, err := c.openStream(conn, reqs[i], request, opts.SinglePacket, opts)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		streams[i] = stream
+		results[i].StreamID = stream.ID
+	}
+
+	// Each stream writes its body and reads its response on its own goroutine. In
+	// single-packet mode the streams wait for the coalesced final write first.
+	var sent, done sync.WaitGroup
+	sendErrs := make([]error, len(reqs))
+	release := make(chan error, 1)
+	for i, stream := range streams {
+		if stream == nil {
+			continue
+		}
+		sent.Add(1)
+		done.Add(1)
+		go func(r *StreamResult, stream *Stream) {
+			defer done.Done()
+			defer c.unregisterStream(conn, stream)
+
+			err := c.sendPending(ctx, conn, stream, opts)
+			if err == nil && !opts.SinglePacket {
+				r.SentAt = time.Now()
+			}
+			sendErrs[r.Index] = err
+			sent.Done()
+			if err == nil && opts.SinglePacket {
+				err = <-release
+				release <- err // let the next stream observe it too
+				r.SentAt = stream.sentAt
+			}
+			if err != nil {
+				r.Err = err
+				return
+			}
+
+			resp, err := c.readResponse(ctx, conn, stream, opts)
+			r.Err = err
+			if resp == nil {
+				return
+			}
+			r.ReceivedAt = stream.firstEventAt
+			resp.TotalTime = time.Since(stream.openedAt)
+			resp.Metrics = &timing.Metrics{
+				TTFB:      max(r.ReceivedAt.Sub(r.SentAt), 0),
+				TotalTime: resp.TotalTime,
+				Total:     resp.TotalTime,
+			}
+			resp.FrameStats = &FrameStats{FramesReceived: len(resp.Frames)}
+			c.fillConnectionMetadata(resp, conn, host, port, scheme, opts)
+			r.Response = resp
+		}(results[i], stream)
+	}
+
+	if opts.SinglePacket {
+		sent.Wait()
+		var open []*Stream
+		for i, stream := range streams {
+			if stream != nil && sendErrs[i] == nil {
+				open = append(open, stream)
+			}
+		}
+		release <- c.releaseFinal(ctx, conn, open, opts.ReadTimeout)
+	}
+	done.Wait()
+
+	return results, nil
+}
+
+// splitFinalFrame removes the frame that ends a request and returns it separately:
+// a trailer HEADERS frame, or the last byte of the body as its own DATA frame. A
+// request without a body keeps its HEADERS open and ends with an empty DATA frame.
+func splitFinalFrame(frames []Frame) ([]Frame, Frame) {
+	n := len(frames)
+	switch f := frames[n-1].(type) {
+	case *HeadersFrame:
+		if n > 1 {
+			return frames[:n-1], f
+		}
+		open := *f
+		open.EndStream = false
+		return []Frame{&open}, &DataFrame{StreamId: f.StreamId, EndStream: true}
+	case *DataFrame:
+		if len(f.Data) <= 1 {
+			return frames[:n-1], f
+		}
+		last := len(f.Data) - 1
+		head := append(frames[:n-1:n-1], &DataFrame{StreamId: f.StreamId, Data: f.Data[:last]})
+		return head, &DataFrame{StreamId: f.StreamId, Data: f.Data[last:], EndStream: f.EndStream}
+	}
+	return frames, nil
+}
+
+// releaseFinal writes the withheld final frame of every stream in a single write to
+// the socket, after reserving the flow-control credit their DATA needs. Streams the
+// peer already finished are closed with RST_STREAM(NO_ERROR) instead.
+func (c *Client) releaseFinal(ctx context.Context, conn *Connection, streams []*Stream, timeout time.Duration) (err error) {
+	if len(streams) == 0 {
+		return nil
+	}
+	defer func() {
+		for _, stream := range streams {
+			stream.trace.TraceWroteRequest(err)
+		}
+	}()
+	for _, stream := range streams {
+		df, ok := stream.final.(*DataFrame)
+		if !ok || len(df.Data) == 0 {
+			conn.mu.RLock()
+			if stream.peerDone {
+				stream.final = nil
+			}
+			conn.mu.RUnlock()
+			continue
+		}
+		_, err := conn.reserveSend(ctx, stream, len(df.Data), timeout)
+		if stderrors.Is(err, errPeerDone) {
+			stream.final = nil
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	var buf bytes.Buffer
+	fr := http2.NewFramer(&buf, nil)
+
+	conn.writeMu.Lock()
+	defer conn.writeMu.Unlock()
+	for _, stream := range streams {
+		var err error
+		if stream.final != nil {
+			err = c.writeFrame(conn, fr, stream.final)
+		} else {
+			err = fr.WriteRSTStream(stream.ID, http2.ErrCodeNo)
+		}
+		if err != nil {
+			return errors.NewProtocolError("encoding final frames", err)
+		}
+	}
+	if _, err := (frameWriter{conn}).Write(buf.Bytes()); err != nil {
+		c.transport.removeConnection(conn)
+		conn.fail(wrapStaleHTTP2Error("sending frame", err))
+		return wrapStaleHTTP2Error("sending frame", err)
+	}
+	sentAt := time.Now()
+	for _, stream := range streams {
+		stream.sentAt = sentAt
+	}
+	return nil
+}
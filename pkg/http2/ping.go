@@ -0,0 +1,121 @@
+package http2
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/WhileEndless/go-rawhttp/pkg/errors"
+)
+
+// defaultPingTimeout is how long the health checker waits for a PING ACK when
+// Options.PingTimeout is unset.
+const defaultPingTimeout = 15 * time.Second
+
+// Ping sends a PING frame and waits for the peer's ACK. It returns the round-trip
+// time, which is also recorded as the connection's RTT. Concurrent pings are told
+// apart by their payloads. ctx bounds the wait.
+func (c *Connection) Ping(ctx context.Context) (time.Duration, error) {
+	ack := make(chan struct{})
+
+	c.mu.Lock()
+	if c.Closed {
+		c.mu.Unlock()
+		return 0, c.closedErr()
+	}
+	c.pingSeq++
+	var data [8]byte
+	binary.BigEndian.PutUint64(data[:], c.pingSeq)
+	if c.pings == nil {
+		c.pings = make(map[[8]byte]chan struct{})
+	}
+	c.pings[data] = ack
+	c.mu.Unlock()
+
+	c.writeMu.Lock()
+	start := time.Now()
+	err := c.Framer.WritePing(false, data)
+	c.writeMu.Unlock()
+	if err != nil {
+		c.forgetPing(data)
+		return 0, wrapStaleHTTP2Error("ping", err)
+	}
+
+	select {
+	case <-ack:
+		rtt := time.Since(start)
+		c.mu.Lock()
+		c.rtt = rtt
+		c.mu.Unlock()
+		return rtt, nil
+	case <-ctx.Done():
+		c.forgetPing(data)
+		if ctx.Err() == context.DeadlineExceeded {
+			return 0, errors.NewTimeoutError("waiting for PING ACK", time.Since(start))
+		}
+		return 0, ctx.Err()
+	case <-c.closedCh:
+		c.forgetPing(data)
+		return 0, c.closedErr()
+	}
+}
+
+// RTT returns the round-trip time measured by the last acknowledged PING, or 0
+// if no PING has been acknowledged yet.
+func (c *Connection) RTT() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.rtt
+}
+
+// ackPing completes the Ping waiting for the ACK with payload data. ACKs that
+// match no outstanding PING are ignored. Called by the read loop.
+func (c *Connection) ackPing(data [8]byte) {
+	c.mu.Lock()
+	ack := c.pings[data]
+	delete(c.pings, data)
+	c.mu.Unlock()
+	if ack != nil {
+		close(ack)
+	}
+}
+
+func (c *Connection) forgetPing(data [8]byte) {
+	c.mu.Lock()
+	delete(c.pings, data)
+	c.mu.Unlock()
+}
+
+// closedErr returns the error that ended a closed connection, or a stale
+// connection error after a graceful close.
+func (c *Connection) closedErr() error {
+	c.mu.RLock()
+	err := c.connErr
+	c.mu.RUnlock()
+	if err == nil {
+		err = wrapStaleHTTP2Error("connection closed", errConnClosed)
+	}
+	return err
+}
+
+// probe pings an idle pooled connection on behalf of the health checker and
+// evicts it when the ACK does not arrive within its ping timeout. At most one
+// probe per connection runs at a time.
+func (t *Transport) probe(conn *Connection) {
+	if !conn.probing.CompareAndSwap(false, true) {
+		return
+	}
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		defer conn.probing.Store(false)
+
+		ctx, cancel := context.WithTimeout(context.Background(), conn.pingTimeout)
+		defer cancel()
+		if _, err := conn.Ping(ctx); err != nil {
+			t.removeConnection(conn)
+			conn.fail(wrapStaleHTTP2Error("health check", fmt.Errorf("%w: %w", errNoPingAck, err)))
+		}
+	}()
+}
@@ -0,0 +1,180 @@
+package http2
+
+import (
+	"context"
+
+	"golang.org/x/net/http2"
+)
+
+// retireStreamID is the stream ID past which a pooled connection takes no new
+// requests: it is retired and replaced before its stream IDs run out, leaving
+// room for the streams already scheduled on it.
+const retireStreamID = maxClientStreamID - 1<<16
+
+// acquire picks the pooled connection for poolKey that should carry the next
+// request: the ready connection with the fewest streams among those below the
+// peer's SETTINGS_MAX_CONCURRENT_STREAMS. One stream slot is reserved on it until
+// the caller calls unreserve. It returns nil when the caller should dial a new
+// connection; the dial is then registered in t.dialing and must be finished with
+// addConnection or dialFailed. When every connection is saturated and the set is
+// full (or a dial is already in progress), acquire waits for a free slot.
+func (t *Transport) acquire(ctx context.Context, poolKey string, maxConns int) (*Connection, error) {
+	t.mu.Lock()
+	for {
+		conn, retired := t.pickLocked(poolKey)
+		if conn != nil || t.dialing[poolKey] == 0 && (maxConns <= 0 || len(t.connections[poolKey]) < maxConns) {
+			if conn == nil {
+				t.dialing[poolKey]++
+			}
+			t.mu.Unlock()
+			closeRetired(retired)
+			return conn, nil
+		}
+
+		if t.capacity == nil {
+			t.capacity = make(chan struct{})
+		}
+		wait := t.capacity
+		t.mu.Unlock()
+		closeRetired(retired)
+
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-t.stopChan:
+			return nil, wrapStaleHTTP2Error("transport closed", errConnClosed)
+		}
+		t.mu.Lock()
+	}
+}
+
+// pickLocked returns the least-loaded connection of the set with a free stream
+// slot and reserves the slot. Closed connections and connections that received
+// GOAWAY are dropped from the set; connections past retireStreamID are retired
+// and returned for closing once idle. Caller holds t.mu.
+func (t *Transport) pickLocked(poolKey string) (best *Connection, retired []*Connection) {
+	var live []*Connection
+	bestLoad := 0
+	for _, conn := range t.connections[poolKey] {
+		conn.mu.Lock()
+		switch {
+		case conn.Closed || conn.goAwayReceived:
+		case conn.NextStreamID > retireStreamID:
+			conn.retired = true
+			retired = append(retired, conn)
+		default:
+			live = append(live, conn)
+			load := conn.loadLocked()
+			if conn.belowStreamLimitLocked(load) && (best == nil || load < bestLoad) {
+				best, bestLoad = conn, load
+			}
+		}
+		conn.mu.Unlock()
+	}
+	t.setConnectionsLocked(poolKey, live)
+
+	if best != nil {
+		best.mu.Lock()
+		best.reserved++
+		best.mu.Unlock()
+	}
+	return best, retired
+}
+
+// addConnection finishes a dial registered by acquire. A successfully dialed
+// connection joins the set with one slot reserved for the dialing request.
+func (t *Transport) addConnection(poolKey string, conn *Connection) {
+	conn.mu.Lock()
+	conn.reserved++
+	conn.mu.Unlock()
+
+	t.mu.Lock()
+	t.dialing[poolKey]--
+	t.nextConnID++
+	conn.id = t.nextConnID
+	t.connections[poolKey] = append(t.connections[poolKey], conn)
+	t.notifyCapacityLocked()
+	t.mu.Unlock()
+}
+
+// dialFailed finishes a failed dial registered by acquire.
+func (t *Transport) dialFailed(poolKey string) {
+	t.mu.Lock()
+	t.dialing[poolKey]--
+	t.notifyCapacityLocked()
+	t.mu.Unlock()
+}
+
+// notifyCapacity wakes requests waiting in acquire; called whenever a stream slot
+// is freed or a connection leaves the pool.
+func (t *Transport) notifyCapacity() {
+	t.mu.Lock()
+	t.notifyCapacityLocked()
+	t.mu.Unlock()
+}
+
+func (t *Transport) notifyCapacityLocked() {
+	if t.capacity != nil {
+		close(t.capacity)
+		t.capacity = nil
+	}
+}
+
+// setConnectionsLocked replaces the connection set of poolKey. Caller holds t.mu.
+func (t *Transport) setConnectionsLocked(poolKey string, set []*Connection) {
+	if len(set) == 0 {
+		delete(t.connections, poolKey)
+		return
+	}
+	t.connections[poolKey] = set
+}
+
+// closeRetired closes the retired connections that have no stream left; the
+// others are closed by releaseRetired when their last stream ends.
+func closeRetired(conns []*Connection) {
+	for _, conn := range conns {
+		conn.releaseRetired()
+	}
+}
+
+// loadLocked returns the number of client streams open or reserved on the
+// connection. Caller holds c.mu.
+func (c *Connection) loadLocked() int {
+	load := c.reserved
+	for id := range c.Streams {
+		if id%2 == 1 {
+			load++
+		}
+	}
+	return load
+}
+
+// belowStreamLimitLocked reports whether load leaves room for another stream
+// under the peer's SETTINGS_MAX_CONCURRENT_STREAMS (unlimited until the peer
+// sets it). Caller holds c.mu.
+func (c *Connection) belowStreamLimitLocked(load int) bool {
+	limit, ok := c.PeerSettings[http2.SettingMaxConcurrentStreams]
+	return !ok || uint32(load) < limit
+}
+
+// unreserve releases the stream slot reserved by acquire once the request has
+// opened its stream (or failed to).
+func (c *Connection) unreserve() {
+	c.mu.Lock()
+	if c.reserved > 0 {
+		c.reserved--
+	}
+	c.mu.Unlock()
+	c.releaseRetired()
+}
+
+// releaseRetired closes a retired connection once no stream uses it.
+func (c *Connection) releaseRetired() {
+	c.mu.RLock()
+	idle := c.retired && c.reserved == 0 && len(c.Streams) == 0
+	c.mu.RUnlock()
+	if idle {
+		c.Close()
+	}
+}
@@ -0,0 +1,186 @@
+package http2
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/WhileEndless/go-rawhttp/pkg/errors"
+	"github.com/WhileEndless/go-rawhttp/pkg/timing"
+	"golang.org/x/net/http2"
+)
+
+// Server push reception (RFC 9113 Section 8.4). When push is enabled the read loop
+// registers every promised stream and hands it to the originating stream as an
+// fkPush event. The request goroutine then reads each pushed response concurrently
+// (a large push must never stall the read loop behind the originating stream) and
+// attaches it to Response.ServerPush.
+
+// pushEnabled reports whether SETTINGS_ENABLE_PUSH = 1 is advertised.
+func (o *Options) pushEnabled() bool {
+	return !o.DisableServerPush
+}
+
+// dispatchPushPromise decodes a complete PUSH_PROMISE header block, registers the
+// promised stream and delivers it to the originating stream. Promises that cannot
+// be delivered (push disabled, originating request gone, promised headers over
+// its header limits) are refused with RST_STREAM(CANCEL); the block is still
+// decoded to keep HPACK state in sync.
+func (c *Connection) dispatchPushPromise(streamID, promisedID uint32, block []byte) error {
+	c.mu.RLock()
+	var limits headerLimits
+	if parent := c.Streams[streamID]; parent != nil {
+		limits = parent.limits
+	}
+	c.mu.RUnlock()
+	dec := &Converter{decoder: c.Decoder}
+	fields, limitErr, err := decodeHeaderBlock(c.Decoder, block, limits)
+	if err != nil {
+		return wrapStaleHTTP2Error("decoding push promise", err)
+	}
+
+	c.mu.Lock()
+	parent := c.Streams[streamID]
+	var pushed *Stream
+	if parent != nil && limitErr == nil && !c.Closed && c.Settings[http2.SettingEnablePush] == 1 {
+		pushed = &Stream{
+			ID:             promisedID,
+			State:          StateReservedRemote,
+			WindowSize:     65535,
+			PeerWindowSize: c.peerInitialWindow,
+			ready:          make(chan struct{}, 1),
+			done:           make(chan struct{}),
+			limits:         parent.limits,
+		}
+		c.Streams[promisedID] = pushed
+	}
+	c.mu.Unlock()
+
+	if pushed != nil {
+		if parent.enqueue(frameEvent{
+			kind:    fkPush,
+			headers: dec.headerFieldsToMap(fields),
+			fields:  fields,
+			pushed:  pushed,
+		}) {
+			return nil
+		}
+		// The originating request finished before it saw the promise.
+		pushed.finish()
+		close(pushed.done)
+		c.mu.Lock()
+		delete(c.Streams, promisedID)
+		c.mu.Unlock()
+	}
+
+	c.writeMu.Lock()
+	err = c.Framer.WriteRSTStream(promisedID, http2.ErrCodeCancel)
+	c.writeMu.Unlock()
+	if err != nil {
+		return wrapStaleHTTP2Error("refusing push", err)
+	}
+	return nil
+}
+
+// defaultPushWait bounds how long a complete response waits for its pushed
+// streams when no ReadTimeout is set.
+const defaultPushWait = 10 * time.Second
+
+// pushReceiver reads the pushed responses promised to one request. Results are
+// attached to their PushPromise by wait, on the request goroutine, so the caller
+// never observes a half-written promise.
+type pushReceiver struct {
+	client *Client
+	conn   *Connection
+	opts   *Options
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	wg      sync.WaitGroup
+	results []*pushResult
+}
+
+type pushResult struct {
+	promise  *PushPromise
+	response *Response
+	err      error
+
+	cancelled bool // the read ended because the receiver was cancelled
+}
+
+func newPushReceiver(ctx context.Context, c *Client, conn *Connection, opts *Options) *pushReceiver {
+	pctx, cancel := context.WithCancel(ctx)
+	return &pushReceiver{client: c, conn: conn, opts: opts, ctx: pctx, cancel: cancel}
+}
+
+// receive records a push promise on response and starts reading the pushed
+// response.
+func (p *pushReceiver) receive(response *Response, ev frameEvent) {
+	promise := &PushPromise{
+		PromisedStreamID: ev.pushed.ID,
+		Headers:          ev.headers,
+		HeaderList:       ev.fields,
+		ReceivedAt:       time.Now(),
+	}
+	response.ServerPush = append(response.ServerPush, promise)
+
+	r := &pushResult{promise: promise}
+	p.results = append(p.results, r)
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer p.client.unregisterStream(p.conn, ev.pushed)
+
+		r.response, r.err = p.client.readResponse(p.ctx, p.conn, ev.pushed, p.opts)
+		r.cancelled = r.err != nil && p.ctx.Err() != nil
+		if r.response != nil {
+			total := time.Since(promise.ReceivedAt)
+			r.response.TotalTime = total
+			r.response.Metrics = &timing.Metrics{TotalTime: total, Total: total}
+		}
+	}()
+}
+
+// wait blocks until every pushed response is complete and attaches the results.
+// A server may keep a pushed stream open forever, so the wait is bounded by
+// ReadTimeout (defaultPushWait when unset): pushed streams still open then are
+// cancelled and their promise carries a timeout error.
+func (p *pushReceiver) wait() {
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	limit := defaultPushWait
+	if p.opts != nil && p.opts.ReadTimeout > 0 {
+		limit = p.opts.ReadTimeout
+	}
+	timer := time.NewTimer(limit)
+	defer timer.Stop()
+
+	expired := false
+	select {
+	case <-done:
+	case <-timer.C:
+		expired = true
+		p.cancel()
+		<-done
+	}
+	p.cancel()
+
+	for _, r := range p.results {
+		if expired && r.cancelled {
+			r.err = errors.NewTimeoutError("reading pushed response", limit)
+		}
+		r.promise.Response = r.response
+		r.promise.Err = r.err
+	}
+	p.results = nil
+}
+
+// abort cancels the pushed streams still being read and waits for them.
+func (p *pushReceiver) abort() {
+	p.cancel()
+	p.wait()
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
 )
 
 // frameEventKind classifies a dispatched stream event.
@@ -18,6 +19,7 @@ const (
 	fkData                          // a copy of a DATA payload
 	fkRST                           // RST_STREAM for the stream
 	fkConnErr                       // connection-level error targeted at the stream (e.g. GOAWAY)
+	fkPush                          // PUSH_PROMISE on the stream; pushed is the promised stream
 )
 
 // frameEvent is a safe, owned snapshot of a frame routed from the connection's
@@ -26,11 +28,14 @@ const (
 // buffers after each ReadFrame call.
 type frameEvent struct {
 	kind      frameEventKind
-	headers   map[string]string
+	headers   map[string]string   // last value per name (legacy HeadersFrame view)
+	fields    []hpack.HeaderField // decoded header block in wire order
 	data      []byte
 	endStream bool
 	errCode   http2.ErrCode
 	err       error
+	limitErr  error   // header limit the block exceeded; fields holds those within it
+	pushed    *Stream // promised stream (fkPush)
 }
 
 // touch records connection activity (used for idle/keepalive tracking).
@@ -40,37 +45,92 @@ func (c *Connection) touch() {
 	c.mu.Unlock()
 }
 
-// routeEvent delivers an event to the stream's inbox without blocking the read
-// loop indefinitely: it gives up if the stream finished (done) or the connection
-// died (closedCh). Events for unknown/finished streams are dropped.
+// routeEvent queues an event for its stream without blocking the read loop.
+// Events for unknown/finished streams are dropped.
 func (c *Connection) routeEvent(streamID uint32, ev frameEvent) {
 	c.mu.RLock()
 	s := c.Streams[streamID]
 	c.mu.RUnlock()
-	if s == nil {
-		return
+	if s != nil {
+		s.enqueue(ev)
+	}
+}
+
+// enqueue adds an event to the stream's inbox and reports whether it did; a
+// finished stream takes no more events.
+func (s *Stream) enqueue(ev frameEvent) bool {
+	s.inboxMu.Lock()
+	if s.finished {
+		s.inboxMu.Unlock()
+		return false
 	}
+	s.inbox = append(s.inbox, ev)
+	s.inboxMu.Unlock()
+
 	select {
-	case s.inbox <- ev:
-	case <-s.done:
-	case <-c.closedCh:
+	case s.ready <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// dequeue removes the oldest event from the stream's inbox.
+func (s *Stream) dequeue() (frameEvent, bool) {
+	s.inboxMu.Lock()
+	defer s.inboxMu.Unlock()
+	if len(s.inbox) == 0 {
+		return frameEvent{}, false
+	}
+	ev := s.inbox[0]
+	s.inbox[0] = frameEvent{}
+	s.inbox = s.inbox[1:]
+	return ev, true
+}
+
+// finish marks the stream finished and drops its queued events.
+func (s *Stream) finish() {
+	s.inboxMu.Lock()
+	s.finished = true
+	s.inbox = nil
+	s.inboxMu.Unlock()
+}
+
+// consumed returns the stream-level flow-control credit of n DATA bytes the
+// reader of s has consumed, unless the stream is closed for the peer's DATA.
+func (c *Connection) consumed(s *Stream, n int) error {
+	if n <= 0 {
+		return nil
 	}
+	c.mu.RLock()
+	open := !s.peerDone && !s.cancelled
+	c.mu.RUnlock()
+	if !open {
+		return nil
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.Framer.WriteWindowUpdate(s.ID, uint32(n))
 }
 
 // removeConnection evicts a connection from the pool. It only removes the exact
-// connection still mapped under its pool key (identity check) so a fresh
-// replacement that reused the key during a race is never evicted. It does NOT
-// close the socket; the read loop's fail() owns socket teardown.
+// connection from the set of its pool key (identity check), so the other
+// connections to the origin are never evicted. It does NOT close the socket; the
+// read loop's fail() owns socket teardown.
 func (t *Transport) removeConnection(conn *Connection) {
 	if conn == nil {
 		return
 	}
 	t.mu.Lock()
 	if conn.PoolKey != "" {
-		if cur, ok := t.connections[conn.PoolKey]; ok && cur == conn {
-			delete(t.connections, conn.PoolKey)
+		var live []*Connection
+		for _, cur := range t.connections[conn.PoolKey] {
+			if cur != conn {
+				live = append(live, cur)
+			}
 		}
+		t.setConnectionsLocked(conn.PoolKey, live)
 	}
+	t.notifyCapacityLocked()
 	t.mu.Unlock()
 }
 
@@ -97,6 +157,7 @@ func (t *Transport) runReadLoop(conn *Connection) {
 			break
 		}
 		conn.touch()
+		conn.traceRead(f)
 		if term := t.dispatchFrame(conn, f); term != nil {
 			termErr = term
 			break
@@ -112,20 +173,35 @@ func (t *Transport) runReadLoop(conn *Connection) {
 func (t *Transport) dispatchFrame(conn *Connection, raw http2.Frame) error {
 	switch f := raw.(type) {
 	case *http2.HeadersFrame:
-		// HPACK decoding is stateful and must happen in stream order; the read loop
-		// is the single decoder user, so this is safe.
-		dec := &Converter{decoder: conn.Decoder}
-		headers, err := dec.DecodeHeaders(f.HeaderBlockFragment())
-		if err != nil {
-			// A header-block decoding failure desynchronizes HPACK state for the
-			// whole connection; tear it down.
-			return wrapStaleHTTP2Error("decoding headers", err)
+		if !f.HeadersEnded() {
+			// The block continues in CONTINUATION frames; the Framer guarantees they
+			// follow immediately on the same stream. Copy: the Framer reuses its buffer.
+			conn.headerBlock = &headerBlock{
+				streamID:  f.StreamID,
+				endStream: f.StreamEnded(),
+				fragment:  append([]byte(nil), f.HeaderBlockFragment()...),
+			}
+			return conn.checkHeaderBlockSize()
+		}
+		return conn.dispatchHeaderBlock(f.StreamID, f.HeaderBlockFragment(), f.StreamEnded())
+
+	case *http2.ContinuationFrame:
+		hb := conn.headerBlock
+		if hb == nil || hb.streamID != f.StreamID {
+			return wrapStaleHTTP2Error("reading headers",
+				fmt.Errorf("unexpected CONTINUATION frame on stream %d", f.StreamID))
+		}
+		hb.fragment = append(hb.fragment, f.HeaderBlockFragment()...)
+		if err := conn.checkHeaderBlockSize(); err != nil {
+			return err
+		}
+		if f.HeadersEnded() {
+			conn.headerBlock = nil
+			if hb.promisedID != 0 {
+				return conn.dispatchPushPromise(hb.streamID, hb.promisedID, hb.fragment)
+			}
+			return conn.dispatchHeaderBlock(hb.streamID, hb.fragment, hb.endStream)
 		}
-		conn.routeEvent(f.StreamID, frameEvent{
-			kind:      fkHeaders,
-			headers:   headers,
-			endStream: f.StreamEnded(),
-		})
 
 	case *http2.DataFrame:
 		// Copy the payload: the Framer reuses its read buffer after this returns.
@@ -133,19 +209,22 @@ func (t *Transport) dispatchFrame(conn *Connection, raw http2.Frame) error {
 		data := make([]byte, len(payload))
 		copy(data, payload)
 
-		// Replenish flow-control windows so the peer can keep sending.
+		// Replenish the connection window so other streams keep flowing. The
+		// stream's credit is returned as its reader consumes the bytes
+		// (Connection.consumed), so a slow reader only throttles its own stream
+		// and its queue never exceeds the stream window.
 		if len(data) > 0 {
 			conn.writeMu.Lock()
-			werr := conn.Framer.WriteWindowUpdate(f.StreamID, uint32(len(data)))
-			if werr == nil {
-				werr = conn.Framer.WriteWindowUpdate(0, uint32(len(data)))
-			}
+			werr := conn.Framer.WriteWindowUpdate(0, uint32(len(data)))
 			conn.writeMu.Unlock()
 			if werr != nil {
 				return wrapStaleHTTP2Error("window update", werr)
 			}
 		}
 
+		if f.StreamEnded() {
+			conn.markPeerDone(f.StreamID)
+		}
 		conn.routeEvent(f.StreamID, frameEvent{
 			kind:      fkData,
 			data:      data,
@@ -153,10 +232,15 @@ func (t *Transport) dispatchFrame(conn *Connection, raw http2.Frame) error {
 		})
 
 	case *http2.RSTStreamFrame:
+		conn.markPeerDone(f.StreamID)
 		conn.routeEvent(f.StreamID, frameEvent{kind: fkRST, errCode: f.ErrCode})
 
 	case *http2.SettingsFrame:
 		if !f.IsAck() {
+			if err := conn.applyPeerSettings(f); err != nil {
+				return wrapStaleHTTP2Error("peer settings", err)
+			}
+			t.notifyCapacity() // SETTINGS_MAX_CONCURRENT_STREAMS may have grown
 			conn.writeMu.Lock()
 			err := conn.Framer.WriteSettingsAck()
 			conn.writeMu.Unlock()
@@ -167,7 +251,9 @@ func (t *Transport) dispatchFrame(conn *Connection, raw http2.Frame) error {
 
 	case *http2.PingFrame:
 		// Only ACK peer-initiated PINGs; never ACK an ACK (avoids a ping loop).
-		if !f.IsAck() {
+		if f.IsAck() {
+			conn.ackPing(f.Data)
+		} else {
 			conn.writeMu.Lock()
 			err := conn.Framer.WritePing(true, f.Data)
 			conn.writeMu.Unlock()
@@ -180,12 +266,84 @@ func (t *Transport) dispatchFrame(conn *Connection, raw http2.Frame) error {
 		return t.handleGoAway(conn, f)
 
 	case *http2.WindowUpdateFrame:
-		// Connection-level send-window accounting is not required for correct reads.
+		// Grants send credit to a writer blocked on the stream or connection window.
+		if err := conn.addSendWindow(f.StreamID, f.Increment); err != nil {
+			return wrapStaleHTTP2Error("window update", err)
+		}
 
 	case *http2.PushPromiseFrame:
-		// Server push is disabled by default; ignore promised streams.
+		// The promised request headers are decoded even when push is disabled so
+		// HPACK state stays in sync; see push.go.
+		if !f.HeadersEnded() {
+			conn.headerBlock = &headerBlock{
+				streamID:   f.StreamID,
+				promisedID: f.PromiseID,
+				fragment:   append([]byte(nil), f.HeaderBlockFragment()...),
+			}
+			return conn.checkHeaderBlockSize()
+		}
+		return conn.dispatchPushPromise(f.StreamID, f.PromiseID, f.HeaderBlockFragment())
+	}
+
+	return nil
+}
+
+// headerBlock accumulates a header block split across a HEADERS or PUSH_PROMISE
+// frame and its CONTINUATION frames until END_HEADERS (RFC 9113 Section 6.10). It
+// is owned by the read loop.
+type headerBlock struct {
+	streamID   uint32
+	promisedID uint32 // non-zero for a PUSH_PROMISE block
+	endStream  bool
+	fragment   []byte
+}
+
+// defaultMaxHeaderBlock bounds header block reassembly when neither our
+// SETTINGS nor the options give a header list size.
+const defaultMaxHeaderBlock = 10 << 20
+
+// checkHeaderBlockSize bounds the header block being reassembled, so an endless
+// CONTINUATION sequence cannot exhaust memory. The bound is local: it holds
+// even when a fingerprint does not advertise SETTINGS_MAX_HEADER_LIST_SIZE.
+func (c *Connection) checkHeaderBlockSize() error {
+	limit := c.maxHeaderBlock
+	if limit == 0 {
+		limit = defaultMaxHeaderBlock
 	}
+	if uint32(len(c.headerBlock.fragment)) > limit {
+		return wrapStaleHTTP2Error("reading headers",
+			fmt.Errorf("header block on stream %d exceeds %d bytes", c.headerBlock.streamID, limit))
+	}
+	return nil
+}
 
+// dispatchHeaderBlock decodes a complete header block and routes it to its stream.
+func (c *Connection) dispatchHeaderBlock(streamID uint32, block []byte, endStream bool) error {
+	// HPACK decoding is stateful and must happen in stream order; the read loop
+	// is the single decoder user, so this is safe.
+	c.mu.RLock()
+	var limits headerLimits
+	if s := c.Streams[streamID]; s != nil {
+		limits = s.limits
+	}
+	c.mu.RUnlock()
+	dec := &Converter{decoder: c.Decoder}
+	fields, limitErr, err := decodeHeaderBlock(c.Decoder, block, limits)
+	if err != nil {
+		// A header-block decoding failure desynchronizes HPACK state for the
+		// whole connection; tear it down.
+		return wrapStaleHTTP2Error("decoding headers", err)
+	}
+	if endStream {
+		c.markPeerDone(streamID)
+	}
+	c.routeEvent(streamID, frameEvent{
+		kind:      fkHeaders,
+		headers:   dec.headerFieldsToMap(fields),
+		fields:    fields,
+		endStream: endStream,
+		limitErr:  limitErr,
+	})
 	return nil
 }
 
@@ -214,11 +372,7 @@ func (t *Transport) handleGoAway(conn *Connection, f *http2.GoAwayFrame) error {
 	conn.mu.Unlock()
 
 	for _, s := range rejected {
-		select {
-		case s.inbox <- frameEvent{kind: fkConnErr, err: goErr}:
-		case <-s.done:
-		case <-conn.closedCh:
-		}
+		s.enqueue(frameEvent{kind: fkConnErr, err: goErr})
 	}
 
 	// If nothing remains to be serviced, terminate the loop now.
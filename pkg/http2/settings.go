@@ -0,0 +1,33 @@
+package http2
+
+import "golang.org/x/net/http2"
+
+// peerSettings returns a copy of the peer's current SETTINGS values.
+func (c *Connection) peerSettings() map[http2.SettingID]uint32 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	settings := make(map[http2.SettingID]uint32, len(c.PeerSettings))
+	for id, v := range c.PeerSettings {
+		settings[id] = v
+	}
+	return settings
+}
+
+// record adds a frame read during the SETTINGS handshake.
+func (p *PeerPreface) record(frame http2.Frame) {
+	p.Frames = append(p.Frames, frame.Header())
+	switch f := frame.(type) {
+	case *http2.SettingsFrame:
+		if !f.IsAck() && p.Settings == nil {
+			p.Settings = []http2.Setting{}
+			f.ForeachSetting(func(s http2.Setting) error {
+				p.Settings = append(p.Settings, s)
+				return nil
+			})
+		}
+	case *http2.WindowUpdateFrame:
+		if f.StreamID == 0 && p.WindowUpdate == 0 {
+			p.WindowUpdate = f.Increment
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package http2
+
+import (
+	"encoding/binary"
+
+	"github.com/WhileEndless/go-rawhttp/pkg/transport"
+	"golang.org/x/net/http2"
+)
+
+// frameWriter is the write side of a connection's Framer. It writes to the
+// socket and, once a traced request has used the connection, reports every frame
+// in the written bytes to FrameWritten. The Framer writes one complete frame per
+// call; a coalesced write (releaseFinal) may carry several.
+type frameWriter struct {
+	conn *Connection
+}
+
+func (w frameWriter) Write(p []byte) (int, error) {
+	n, err := w.conn.Conn.Write(p)
+	if w.conn.tracing.Load() {
+		for b := p[:n]; len(b) >= frameHeaderLen; {
+			length := uint32(b[0])<<16 | uint32(b[1])<<8 | uint32(b[2])
+			w.conn.traceFrame(http2.FrameHeader{
+				Type:     http2.FrameType(b[3]),
+				Flags:    http2.Flags(b[4]),
+				StreamID: binary.BigEndian.Uint32(b[5:9]) & (1<<31 - 1),
+				Length:   length,
+			}, false)
+			if uint32(len(b)-frameHeaderLen) < length {
+				break
+			}
+			b = b[frameHeaderLen+int(length):]
+		}
+	}
+	return n, err
+}
+
+// frameHeaderLen is the size of an HTTP/2 frame header.
+const frameHeaderLen = 9
+
+// traceFrame reports a frame read from or written to the connection. Frames of a
+// stream go to the trace of the request owning it; connection-level frames go to
+// the trace of the request that opened the connection.
+func (c *Connection) traceFrame(h http2.FrameHeader, read bool) {
+	trace := c.trace
+	if h.StreamID != 0 {
+		c.mu.RLock()
+		s := c.Streams[h.StreamID]
+		c.mu.RUnlock()
+		if s == nil {
+			return
+		}
+		trace = s.trace
+	}
+	info := transport.FrameInfo{Type: h.Type.String(), StreamID: h.StreamID, Flags: uint8(h.Flags), Length: h.Length}
+	if read {
+		trace.TraceFrameRead(info)
+	} else {
+		trace.TraceFrameWritten(info)
+	}
+}
+
+// traceRead reports a frame returned by the Framer to FrameRead.
+func (c *Connection) traceRead(f http2.Frame) {
+	if c.tracing.Load() {
+		c.traceFrame(f.Header(), true)
+	}
+}
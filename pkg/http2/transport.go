@@ -15,6 +15,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/WhileEndless/go-rawhttp/pkg/timing"
 	"github.com/WhileEndless/go-rawhttp/pkg/transport"
 	"golang.org/x/net/http2"
 	"golang.org/x/net/http2/hpack"
@@ -28,10 +29,17 @@ const (
 
 // Transport manages HTTP/2 connections
 type Transport struct {
-	connections map[string]*Connection
+	connections map[string][]*Connection // Pooled connections by pool key
 	mu          sync.RWMutex
 	options     *Options
 
+	// Scheduling (see pool.go), guarded by mu: dials in progress per pool key, the
+	// last connection ID handed out, and a channel closed (and replaced) whenever
+	// a stream slot may have been freed.
+	dialing    map[string]int
+	nextConnID uint64
+	capacity   chan struct{}
+
 	// Lifecycle management
 	stopChan chan struct{}  // Channel to signal background goroutines to stop
 	wg       sync.WaitGroup // WaitGroup to track running goroutines
@@ -51,12 +59,15 @@ func NewTransport(opts *Options) *Transport {
 	}
 
 	t := &Transport{
-		connections: make(map[string]*Connection),
+		connections: make(map[string][]*Connection),
+		dialing:     make(map[string]int),
 		options:     opts,
 		stopChan:    make(chan struct{}),
 	}
 
-	// Start connection health checker
+	// Start connection health checker. Add to the wait group before starting it so
+	// Close never races Add/Wait (same rule as the per-connection read loop).
+	t.wg.Add(1)
 	go t.healthChecker()
 
 	return t
@@ -64,7 +75,6 @@ func NewTransport(opts *Options) *Transport {
 
 // healthChecker periodically checks connection health
 func (t *Transport) healthChecker() {
-	t.wg.Add(1)
 	defer t.wg.Done()
 
 	ticker := time.NewTicker(30 * time.Second)
@@ -87,144 +97,134 @@ func (t *Transport) checkConnectionHealth() {
 	defer t.mu.Unlock()
 
 	now := time.Now()
-	for addr, conn := range t.connections {
-		// Check if connection is idle
-		conn.mu.RLock()
-		idleTime := now.Sub(conn.LastActivity)
-		closed := conn.Closed
-		conn.mu.RUnlock()
-
-		if closed {
-			// Remove closed connections
-			delete(t.connections, addr)
-			continue
-		}
-
-		// Send PING for keep-alive if idle for more than 15 seconds
-		if idleTime > 15*time.Second {
-			pingData := [8]byte{0, 0, 0, 0, 0, 0, 0, byte(now.Unix())}
-
-			// Lock before writing to prevent concurrent write panic
-			conn.mu.Lock()
-			err := conn.Framer.WritePing(false, pingData)
-			if err == nil {
-				// Update last activity on success
-				conn.LastActivity = now
+	for addr, set := range t.connections {
+		var live []*Connection
+		for _, conn := range set {
+			// Check if connection is idle
+			conn.mu.RLock()
+			idleTime := now.Sub(conn.LastActivity)
+			closed := conn.Closed
+			conn.mu.RUnlock()
+
+			if closed {
+				// Remove closed connections
+				continue
 			}
-			conn.mu.Unlock()
 
-			if err != nil {
-				// Connection is broken, close and remove it
+			// Remove connections idle for too long (5 minutes)
+			if idleTime > 5*time.Minute {
 				conn.Close()
-				delete(t.connections, addr)
+				continue
 			}
-		}
 
-		// Remove connections idle for too long (5 minutes)
-		if idleTime > 5*time.Minute {
-			conn.Close()
-			delete(t.connections, addr)
+			// Probe connections idle for more than 15 seconds with a PING; one whose
+			// ACK does not arrive within its ping timeout is evicted (see probe).
+			if idleTime > 15*time.Second {
+				t.probe(conn)
+			}
+			live = append(live, conn)
 		}
+		t.setConnectionsLocked(addr, live)
 	}
+	t.notifyCapacityLocked()
 }
 
 // Connect establishes an HTTP/2 connection with the given options.
 // The opts parameter takes precedence over the transport's default options.
 func (t *Transport) Connect(ctx context.Context, host string, port int, scheme string, opts *Options) (*Connection, error) {
+	conn, err := t.connect(ctx, host, port, scheme, opts, nil)
+	if err != nil {
+		return nil, err
+	}
+	conn.unreserve()
+	return conn, nil
+}
+
+// connect implements Connect; timer (may be nil) records the pool wait and the
+// phases of a new connection. With ReuseConnection the connection is scheduled
+// from the origin's pooled set (see acquire) and one stream slot stays reserved
+// on it for the caller, who must call unreserve once the stream is open.
+func (t *Transport) connect(ctx context.Context, host string, port int, scheme string, opts *Options, timer *timing.Timer) (*Connection, error) {
 	// Use provided options or fall back to transport defaults
 	if opts == nil {
 		opts = t.options
 	}
 
-	// Create pool key that includes proxy information if present
-	// This ensures different proxies use different pooled connections
-	var poolKey string
-	if opts.Proxy != nil {
-		proxyPort := opts.Proxy.Port
-		if proxyPort == 0 {
-			// Apply default port
-			switch opts.Proxy.Type {
-			case "http":
-				proxyPort = 8080
-			case "https":
-				proxyPort = 443
-			case "socks4", "socks5":
-				proxyPort = 1080
-			}
-		}
-		// Format: "proxy_type:proxy_host:proxy_port->target_host:target_port"
-		poolKey = fmt.Sprintf("%s:%s:%d->%s:%d", opts.Proxy.Type, opts.Proxy.Host, proxyPort, host, port)
-	} else {
-		// Direct connection: just use target address
-		poolKey = fmt.Sprintf("%s:%d", host, port)
+	// The pool key covers the target, the proxy and the TLS parameters, so only
+	// requests with an identical connection setup share a connection.
+	key := transport.NewPoolKey(opts.poolKeyConfig(scheme, host, port), alpnProtocols(opts))
+	if opts.Fingerprint != nil {
+		key.HTTP2Fingerprint = opts.Fingerprint.poolKey()
 	}
+	poolKey := key.String()
 
-	// Check for existing connection if reuse is enabled
-	// Use write lock to prevent race conditions when multiple goroutines
-	// try to create a connection simultaneously
-	var needUnlock bool
+	// Schedule the request on a pooled connection if reuse is enabled. Otherwise
+	// acquire has registered our dial, so concurrent requests to the origin wait
+	// for it instead of dialing too.
 	if opts.ReuseConnection {
-		t.mu.Lock()
-		needUnlock = true
-		if conn, exists := t.connections[poolKey]; exists && !conn.isClosed() {
-			// Wait for connection to be ready if it's still initializing
-			if !conn.isReady() {
-				t.mu.Unlock()
-				// Wait a bit for connection to become ready
-				for i := 0; i < 100 && !conn.isReady() && !conn.isClosed(); i++ {
-					time.Sleep(10 * time.Millisecond)
-				}
-				if conn.isReady() {
-					conn.markReused() // Mark as reused from pool (PoolKey set at creation)
-					return conn, nil
-				}
-				// Connection failed or timed out, create a new one
-				t.mu.Lock()
-				needUnlock = true
-				delete(t.connections, poolKey)
-			} else {
-				t.mu.Unlock()
-				conn.markReused() // Mark as reused from pool (PoolKey set at creation)
-				return conn, nil
-			}
+		timer.StartPoolWait()
+		conn, err := t.acquire(ctx, poolKey, opts.MaxConnsPerHost)
+		timer.EndPoolWait()
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect: %w", err)
+		}
+		if conn != nil {
+			conn.markReused() // Mark as reused from pool (PoolKey set at creation)
+			return conn, nil
 		}
-		// Keep the lock held to prevent race - will be released after storing connection
 	}
+	dialed := false
+	defer func() {
+		if opts.ReuseConnection && !dialed {
+			t.dialFailed(poolKey)
+		}
+	}()
 
 	// Establish new connection
-	var rawConn net.Conn
-	var err error
-
-	if scheme == "https" {
-		// TLS connection with ALPN (with optional proxy support)
-		targetAddr := fmt.Sprintf("%s:%d", host, port)
-		rawConn, err = t.connectTLS(ctx, targetAddr, host, opts)
-	} else {
-		// Plain TCP connection (H2C), proxy-aware
-		targetAddr := fmt.Sprintf("%s:%d", host, port)
-		rawConn, err = t.connectH2C(ctx, targetAddr, host, opts)
+	targetAddr := fmt.Sprintf("%s:%d", host, port)
+	rawConn, attempts, err := t.dial(ctx, targetAddr, host, opts, timer)
+	var hello transport.ClientHelloFingerprint
+	if err == nil {
+		if scheme == "https" {
+			// TLS connection with ALPN
+			rawConn, hello, err = t.connectTLS(ctx, rawConn, host, opts, timer)
+		} else {
+			// Plain TCP connection (H2C)
+			rawConn, err = t.connectH2C(ctx, rawConn, targetAddr, opts)
+		}
 	}
 
 	if err != nil {
-		if needUnlock {
-			t.mu.Unlock() // Release lock on connection error
-		}
 		return nil, fmt.Errorf("failed to connect: %w", err)
 	}
 
 	// Create HTTP/2 connection
 	conn := &Connection{
 		Conn:          rawConn, // Store the underlying connection
-		Framer:        http2.NewFramer(rawConn, rawConn),
 		Streams:       make(map[uint32]*Stream),
-		NextStreamID:  1, // Client streams use odd IDs
+		NextStreamID:  opts.Fingerprint.firstStreamID(), // Client streams use odd IDs
 		MaxConcurrent: opts.MaxConcurrentStreams,
 		WindowSize:    int32(opts.InitialWindowSize),
 		Settings:      make(map[http2.SettingID]uint32),
 		PeerSettings:  make(map[http2.SettingID]uint32),
 		LastActivity:  time.Now(),
 		closedCh:      make(chan struct{}),
+
+		PeerWindowSize:    defaultPeerWindow,
+		peerInitialWindow: defaultPeerWindow,
+		peerMaxFrameSize:  defaultPeerMaxFrameSize,
+		flowCh:            make(chan struct{}),
+
+		pingTimeout: opts.PingTimeout,
+		fingerprint: opts.Fingerprint,
+		hello:       hello,
+		trace:       opts.Trace,
+	}
+	if conn.pingTimeout <= 0 {
+		conn.pingTimeout = defaultPingTimeout
 	}
+	conn.tracing.Store(opts.Trace != nil)
+	conn.Framer = http2.NewFramer(frameWriter{conn}, rawConn)
 
 	// Initialize HPACK encoder/decoder for this connection
 	// Each connection needs its own HPACK context
@@ -233,15 +233,12 @@ func (t *Transport) Connect(ctx context.Context, host string, port int, scheme s
 	conn.Encoder.SetMaxDynamicTableSize(opts.HeaderTableSize)
 	conn.Decoder = hpack.NewDecoder(opts.HeaderTableSize, nil)
 
-	// Important: Release lock before blocking I/O but don't store connection yet
-	if needUnlock {
-		t.mu.Unlock()
-		needUnlock = false // We'll re-acquire if needed
-	}
-
 	// Send initial settings (this can block waiting for ACK). This performs the only
 	// reads done outside the read loop; the loop takes over all reads afterwards.
-	if err := t.sendInitialSettings(conn, opts); err != nil {
+	timer.StartSettings()
+	err = t.sendInitialSettings(conn, opts)
+	timer.EndSettings()
+	if err != nil {
 		// Defensive nil check before closing (rawConn should not be nil here, but being extra safe)
 		if rawConn != nil {
 			rawConn.Close()
@@ -251,6 +248,8 @@ func (t *Transport) Connect(ctx context.Context, host string, port int, scheme s
 
 	// Store pool key before marking as ready
 	conn.PoolKey = poolKey
+	conn.key = key
+	conn.dialAttempts = attempts
 
 	// Start the single per-connection read loop. From here on, ALL reads from the
 	// Framer belong to the loop; request goroutines receive frames via stream inboxes.
@@ -261,47 +260,80 @@ func (t *Transport) Connect(ctx context.Context, host string, port int, scheme s
 	// Mark connection as ready
 	conn.setReady()
 
-	// Now store the fully initialized connection
+	// Now add the fully initialized connection to the origin's set
 	if opts.ReuseConnection {
-		t.mu.Lock()
-		// Check if someone else created a connection while we were initializing
-		if existing, exists := t.connections[poolKey]; exists && existing.isReady() {
-			t.mu.Unlock()
-			// Use the existing connection and close ours
-			conn.Close()
-			existing.PoolKey = poolKey // Ensure pool key is set
-			return existing, nil
-		}
-		t.connections[poolKey] = conn
-		t.mu.Unlock()
+		dialed = true
+		t.addConnection(poolKey, conn)
 	}
 
 	return conn, nil
 }
 
-// connectTLS establishes a TLS connection with ALPN negotiation (supports proxy)
-func (t *Transport) connectTLS(ctx context.Context, addr, serverName string, opts *Options) (net.Conn, error) {
-	var conn net.Conn
-	var err error
+// alpnProtocols returns the ALPN protocols offered in the TLS handshake. Without
+// explicit TLSConfig.NextProtos the defaults are offered; h2 is prepended to a user
+// list lacking it, since ALPN must succeed for the HTTP/2 transport. Users who want
+// to avoid HTTP/2 should set Protocol = "http/1.1" instead.
+func alpnProtocols(opts *Options) []string {
+	if opts.TLSConfig == nil || len(opts.TLSConfig.NextProtos) == 0 {
+		return []string{"h2", "http/1.1"}
+	}
+	for _, proto := range opts.TLSConfig.NextProtos {
+		if proto == "h2" {
+			return opts.TLSConfig.NextProtos
+		}
+	}
+	return append([]string{"h2"}, opts.TLSConfig.NextProtos...)
+}
+
+// poolKeyConfig describes the connection options as a transport.Config, for
+// deriving the pool key.
+func (o *Options) poolKeyConfig(scheme, host string, port int) transport.Config {
+	config := transport.Config{
+		Scheme:         scheme,
+		Host:           host,
+		Port:           port,
+		SNI:            o.SNI,
+		DisableSNI:     o.DisableSNI,
+		InsecureTLS:    o.InsecureTLS,
+		ClientCertPEM:  o.ClientCertPEM,
+		ClientCertFile: o.ClientCertFile,
+		TLSConfig:      o.TLSConfig,
+		MinTLSVersion:  o.MinTLSVersion,
+		MaxTLSVersion:  o.MaxTLSVersion,
+		CipherSuites:   o.CipherSuites,
+		TLSFingerprint: o.TLSFingerprint,
+		Network:        o.Network,
+		DialContext:    o.DialContext,
+		Resolver:       o.Resolver,
+	}
+	if o.Proxy != nil {
+		config.Proxy = &transport.ProxyConfig{Type: o.Proxy.Type, Host: o.Proxy.Host, Port: o.Proxy.Port}
+	}
+	return config
+}
 
-	// Check if we need to use a proxy
+// dialer returns the dialer honoring the DialContext and Resolver hooks.
+func (o *Options) dialer(timeout time.Duration) transport.Dialer {
+	return transport.Dialer{DialContext: o.DialContext, Resolver: o.Resolver, Timeout: timeout, Trace: o.Trace}
+}
+
+// dial opens the connection to the target: a tunnel when a proxy is configured
+// (so an explicit HTTP/2 request over a proxy is never silently downgraded to a
+// direct connection), a direct connection otherwise, which honors Network and
+// HappyEyeballs and reports its attempts.
+func (t *Transport) dial(ctx context.Context, addr, serverName string, opts *Options, timer *timing.Timer) (net.Conn, []transport.DialAttempt, error) {
 	if opts.Proxy != nil {
-		// Connect via proxy using CONNECT tunnel
-		conn, err = t.connectViaProxy(ctx, addr, serverName, opts)
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		// Direct connection
-		dialer := &net.Dialer{
-			Timeout: 30 * time.Second,
-		}
-		conn, err = dialer.DialContext(ctx, "tcp", addr)
-		if err != nil {
-			return nil, err
-		}
+		conn, err := t.connectViaProxy(ctx, addr, serverName, opts, timer)
+		return conn, nil, err
+	}
+	if err := transport.ValidateNetwork(opts.Network); err != nil {
+		return nil, nil, err
 	}
+	return opts.dialer(30*time.Second).DialTarget(ctx, opts.Network, addr, opts.HappyEyeballs, opts.FallbackDelay, timer)
+}
 
+// connectTLS performs the TLS handshake with ALPN negotiation on conn
+func (t *Transport) connectTLS(ctx context.Context, conn net.Conn, serverName string, opts *Options, timer *timing.Timer) (net.Conn, transport.ClientHelloFingerprint, error) {
 	// Create TLS config with ALPN
 	var tlsConfig *tls.Config
 
@@ -310,32 +342,8 @@ func (t *Transport) connectTLS(ctx context.Context, addr, serverName string, opt
 		// Clone to avoid modifying the original
 		tlsConfig = opts.TLSConfig.Clone()
 
-		// Handle NextProtos with respect for user's explicit configuration
-		if len(tlsConfig.NextProtos) == 0 {
-			// User didn't specify NextProtos, use defaults for HTTP/2
-			tlsConfig.NextProtos = []string{"h2", "http/1.1"}
-		} else {
-			// User explicitly set NextProtos - check if h2 is present
-			hasH2 := false
-			for _, proto := range tlsConfig.NextProtos {
-				if proto == "h2" {
-					hasH2 = true
-					break
-				}
-			}
-
-			// IMPORTANT: Only add h2 if user seems to want HTTP/2 support
-			// If user explicitly set NextProtos without h2, they likely want to avoid HTTP/2
-			// However, since they're using HTTP/2 transport, we need h2 for ALPN to succeed
-			//
-			// Best practice: Users should set opts.Protocol = "http/1.1" instead of
-			// trying to control protocol via NextProtos when using go-rawhttp
-			if !hasH2 {
-				// Prepend h2 to the list for backward compatibility
-				// NOTE: This maintains existing behavior but logs a warning would be better
-				tlsConfig.NextProtos = append([]string{"h2"}, tlsConfig.NextProtos...)
-			}
-		}
+		// Offer h2 even when the user's NextProtos lack it (see alpnProtocols)
+		tlsConfig.NextProtos = alpnProtocols(opts)
 
 		// Apply InsecureTLS flag (overrides TLSConfig setting)
 		if opts.InsecureTLS {
@@ -347,7 +355,7 @@ func (t *Transport) connectTLS(ctx context.Context, addr, serverName string, opt
 	} else {
 		// Use default TLS config
 		tlsConfig = &tls.Config{
-			NextProtos:         []string{"h2", "http/1.1"},
+			NextProtos:         alpnProtocols(opts),
 			MinVersion:         tls.VersionTLS12,
 			InsecureSkipVerify: opts.InsecureTLS,
 		}
@@ -358,7 +366,8 @@ func (t *Transport) connectTLS(ctx context.Context, addr, serverName string, opt
 	// Load client certificate for mutual TLS (mTLS) if provided
 	clientCert, err := t.loadClientCertificate(opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		conn.Close()
+		return nil, transport.ClientHelloFingerprint{}, fmt.Errorf("failed to load client certificate: %w", err)
 	}
 	if clientCert != nil {
 		tlsConfig.Certificates = append(tlsConfig.Certificates, *clientCert)
@@ -386,66 +395,47 @@ func (t *Transport) connectTLS(ctx context.Context, addr, serverName string, opt
 	// Already have 'conn' from above (either proxy or direct connection)
 	// No need to dial again
 
-	// Perform TLS handshake
-	tlsConn := tls.Client(conn, tlsConfig)
-
 	// Set handshake timeout
 	deadline := time.Now().Add(10 * time.Second)
 	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
 		deadline = d
 	}
-	tlsConn.SetDeadline(deadline)
+	conn.SetDeadline(deadline)
 
-	if err := tlsConn.Handshake(); err != nil {
+	// Perform TLS handshake
+	timer.StartTLS()
+	opts.Trace.TraceTLSHandshakeStart()
+	tlsConn, hello, err := transport.HandshakeTLS(ctx, conn, tlsConfig, opts.TLSFingerprint)
+	if err != nil {
+		opts.Trace.TraceTLSHandshakeDone(tls.ConnectionState{}, err)
 		conn.Close()
-		return nil, fmt.Errorf("TLS handshake failed: %w", err)
+		return nil, hello, fmt.Errorf("TLS handshake failed: %w", err)
 	}
+	timer.EndTLS()
+	state, _ := transport.TLSConnectionState(tlsConn)
+	opts.Trace.TraceTLSHandshakeDone(state, nil)
 
 	// Clear deadline
 	tlsConn.SetDeadline(time.Time{})
 
 	// Verify ALPN negotiation
-	state := tlsConn.ConnectionState()
 	if state.NegotiatedProtocol != "h2" {
 		tlsConn.Close() // Close TLS connection (which also closes underlying TCP connection)
-		return nil, fmt.Errorf("server does not support HTTP/2 (negotiated: %s)", state.NegotiatedProtocol)
+		return nil, hello, fmt.Errorf("server does not support HTTP/2 (negotiated: %s)", state.NegotiatedProtocol)
 	}
 
 	// Send HTTP/2 preface
 	if _, err := tlsConn.Write([]byte(ClientPreface)); err != nil {
 		tlsConn.Close()
-		return nil, fmt.Errorf("failed to send HTTP/2 preface: %w", err)
+		return nil, hello, fmt.Errorf("failed to send HTTP/2 preface: %w", err)
 	}
 
-	return tlsConn, nil
+	return tlsConn, hello, nil
 }
 
-// connectH2C establishes a cleartext HTTP/2 connection. When a proxy is
-// configured it tunnels through it (HTTP/HTTPS CONNECT or SOCKS) instead of
-// dialing the target directly, so an explicit HTTP/2 request over a proxy is
-// never silently downgraded to a direct HTTP/1.1 connection (the proxy is
-// honored, not bypassed).
-func (t *Transport) connectH2C(ctx context.Context, addr, serverName string, opts *Options) (net.Conn, error) {
-	var conn net.Conn
-	var err error
-
-	if opts != nil && opts.Proxy != nil {
-		// Establish a tunnel to the cleartext target through the proxy, then
-		// speak H2C (preface / upgrade) over that tunnel.
-		conn, err = t.connectViaProxy(ctx, addr, serverName, opts)
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		dialer := &net.Dialer{
-			Timeout: 30 * time.Second,
-		}
-		conn, err = dialer.DialContext(ctx, "tcp", addr)
-		if err != nil {
-			return nil, err
-		}
-	}
-
+// connectH2C starts cleartext HTTP/2 on conn, which is either a direct
+// connection or a tunnel through the proxy (see dial).
+func (t *Transport) connectH2C(ctx context.Context, conn net.Conn, addr string, opts *Options) (net.Conn, error) {
 	// Option 1: Direct HTTP/2 (prior knowledge)
 	if t.options.EnableMultiplexing {
 		// Send HTTP/2 preface directly
@@ -510,7 +500,7 @@ func (t *Transport) encodeHTTP2Settings() string {
 
 	settings := map[http2.SettingID]uint32{
 		http2.SettingHeaderTableSize:      t.options.HeaderTableSize,
-		http2.SettingEnablePush:           boolToUint32(t.options.EnableServerPush),
+		http2.SettingEnablePush:           boolToUint32(t.options.pushEnabled()),
 		http2.SettingMaxConcurrentStreams: t.options.MaxConcurrentStreams,
 		http2.SettingInitialWindowSize:    t.options.InitialWindowSize,
 		http2.SettingMaxFrameSize:         t.options.MaxFrameSize,
@@ -524,26 +514,55 @@ func (t *Transport) encodeHTTP2Settings() string {
 	return base64.RawURLEncoding.EncodeToString(payload)
 }
 
-// sendInitialSettings sends initial SETTINGS frame (aligned with Go's approach)
+// sendInitialSettings sends initial SETTINGS frame (aligned with Go's approach).
+// With a fingerprint profile the SETTINGS, the connection WINDOW_UPDATE and the
+// PRIORITY frames are the profile's, written back to back as browsers do.
 func (t *Transport) sendInitialSettings(conn *Connection, opts *Options) error {
-	// Send only the settings that Go's HTTP/2 sends (minimal set)
-	settings := map[http2.SettingID]uint32{
-		http2.SettingEnablePush:        boolToUint32(opts.EnableServerPush), // Always 0
-		http2.SettingInitialWindowSize: opts.InitialWindowSize,              // 4MB
-		http2.SettingMaxFrameSize:      opts.MaxFrameSize,                   // 16KB
-		http2.SettingMaxHeaderListSize: opts.MaxHeaderListSize,              // 10MB
+	// Send only the settings that Go's HTTP/2 sends (minimal set), in a stable order
+	settings := []http2.Setting{
+		{ID: http2.SettingEnablePush, Val: boolToUint32(opts.pushEnabled())}, // 0 when DisableServerPush
+		{ID: http2.SettingInitialWindowSize, Val: opts.InitialWindowSize},    // 4MB
+		{ID: http2.SettingMaxFrameSize, Val: opts.MaxFrameSize},              // 16KB
+		{ID: http2.SettingMaxHeaderListSize, Val: opts.MaxHeaderListSize},    // 10MB
+	}
+	fp := opts.Fingerprint
+	if fp != nil {
+		settings = fp.Settings
 	}
 
 	// Store our settings
-	for id, value := range settings {
-		conn.Settings[id] = value
+	for _, s := range settings {
+		conn.Settings[s.ID] = s.Val
+	}
+	// Header blocks are bounded by the list size we announce, or by ours when a
+	// fingerprint leaves it out
+	conn.maxHeaderBlock = opts.MaxHeaderListSize
+	if size := conn.Settings[http2.SettingMaxHeaderListSize]; size > 0 {
+		conn.maxHeaderBlock = size
+	}
+	// The peer may index headers up to the table size we announce
+	if size, ok := conn.Settings[http2.SettingHeaderTableSize]; ok {
+		conn.Decoder.SetAllowedMaxDynamicTableSize(size)
 	}
 
 	// Send SETTINGS frame
-	if err := conn.Framer.WriteSettings(convertSettings(settings)...); err != nil {
+	if err := conn.Framer.WriteSettings(settings...); err != nil {
 		return fmt.Errorf("failed to write settings: %w", err)
 	}
 
+	if fp != nil {
+		if fp.WindowUpdate > 0 {
+			if err := conn.Framer.WriteWindowUpdate(0, fp.WindowUpdate); err != nil {
+				return fmt.Errorf("failed to write connection window update: %w", err)
+			}
+		}
+		for _, p := range fp.Priorities {
+			if err := conn.Framer.WritePriority(p.StreamID, convertPriority(&p.PriorityParam)); err != nil {
+				return fmt.Errorf("failed to write priority: %w", err)
+			}
+		}
+	}
+
 	// Wait for SETTINGS ACK from server (required by HTTP/2 spec)
 	if err := t.waitForSettingsAck(conn); err != nil {
 		return fmt.Errorf("failed to receive settings ACK: %w", err)
@@ -551,7 +570,7 @@ func (t *Transport) sendInitialSettings(conn *Connection, opts *Options) error {
 
 	// Send connection-level window update (like Go's HTTP/2 does)
 	// Go sends a WINDOW_UPDATE to increase the connection window size
-	if opts.InitialWindowSize > 65535 {
+	if fp == nil && opts.InitialWindowSize > 65535 {
 		increment := opts.InitialWindowSize - 65535
 		if err := conn.Framer.WriteWindowUpdate(0, increment); err != nil {
 			return fmt.Errorf("failed to write connection window update: %w", err)
@@ -574,7 +593,8 @@ func (t *Transport) waitForSettingsAck(conn *Connection) error {
 	timeout := time.NewTimer(10 * time.Second)
 	defer timeout.Stop()
 
-	// Read frames until we get SETTINGS ACK
+	// Read frames until we get SETTINGS ACK, recording them in the preface
+	conn.preface = &PeerPreface{}
 	for {
 		// Check for timeout
 		select {
@@ -587,6 +607,8 @@ func (t *Transport) waitForSettingsAck(conn *Connection) error {
 		if err != nil {
 			return fmt.Errorf("failed to read frame while waiting for SETTINGS ACK: %w", err)
 		}
+		conn.traceRead(frame)
+		conn.preface.record(frame)
 
 		switch f := frame.(type) {
 		case *http2.SettingsFrame:
@@ -594,7 +616,10 @@ func (t *Transport) waitForSettingsAck(conn *Connection) error {
 				// Got SETTINGS ACK, we can proceed
 				return nil
 			} else {
-				// Server sent its own SETTINGS, we should ACK it
+				// Server sent its own SETTINGS: record them and ACK
+				if err := conn.applyPeerSettings(f); err != nil {
+					return fmt.Errorf("invalid server SETTINGS: %w", err)
+				}
 				if err := conn.Framer.WriteSettingsAck(); err != nil {
 					return fmt.Errorf("failed to ACK server settings: %w", err)
 				}
@@ -602,8 +627,10 @@ func (t *Transport) waitForSettingsAck(conn *Connection) error {
 			}
 
 		case *http2.WindowUpdateFrame:
-			// Server might send window updates, that's fine, ignore for now
-			continue
+			// Server may grow the connection send window before the first request
+			if err := conn.addSendWindow(f.StreamID, f.Increment); err != nil {
+				return err
+			}
 
 		case *http2.PingFrame:
 			// Server might send PING, we should respond
@@ -634,9 +661,9 @@ func (t *Transport) Close() error {
 	// t.wg.Done(); the read loop also evicts itself from the pool via removeConnection
 	// (which needs t.mu), so t.mu must NOT be held during the wait below.
 	t.mu.Lock()
-	conns := make([]*Connection, 0, len(t.connections))
-	for addr, conn := range t.connections {
-		conns = append(conns, conn)
+	var conns []*Connection
+	for addr, set := range t.connections {
+		conns = append(conns, set...)
 		delete(t.connections, addr)
 	}
 	t.mu.Unlock()
@@ -654,18 +681,20 @@ func (t *Transport) Close() error {
 	return lastErr
 }
 
-// CloseConnection closes a specific connection
+// CloseConnection closes the pooled connections of a pool key.
 func (t *Transport) CloseConnection(addr string) error {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
-	if conn, exists := t.connections[addr]; exists {
-		err := conn.Close()
-		delete(t.connections, addr)
-		return err
+	var lastErr error
+	for _, conn := range t.connections[addr] {
+		if err := conn.Close(); err != nil {
+			lastErr = err
+		}
 	}
-
-	return nil
+	delete(t.connections, addr)
+	t.notifyCapacityLocked()
+	return lastErr
 }
 
 // GetPoolStats returns current HTTP/2 connection pool statistics (DEF-5).
@@ -675,30 +704,37 @@ func (t *Transport) GetPoolStats() *ConnectionPoolStats {
 	defer t.mu.RUnlock()
 
 	stats := &ConnectionPoolStats{
-		ActiveConnections: len(t.connections),
-		Connections:       make(map[string]ConnectionStats),
+		Connections: make(map[string]ConnectionStats),
 	}
 
 	totalStreams := 0
-	for addr, conn := range t.connections {
-		conn.mu.RLock()
-		activeStreams := 0
-		for _, stream := range conn.Streams {
-			if !stream.Closed {
-				activeStreams++
+	for addr, set := range t.connections {
+		for _, conn := range set {
+			conn.mu.RLock()
+			activeStreams := 0
+			for _, stream := range conn.Streams {
+				if !stream.Closed {
+					activeStreams++
+				}
 			}
+			totalStreams += len(conn.Streams)
+
+			stats.Connections[fmt.Sprintf("%s#%d", addr, conn.id)] = ConnectionStats{
+				Address:       addr,
+				ID:            conn.id,
+				StreamsActive: activeStreams,
+				StreamsTotal:  len(conn.Streams),
+				StreamsMax:    conn.PeerSettings[http2.SettingMaxConcurrentStreams],
+				Load:          conn.loadLocked(),
+				LastActivity:  conn.LastActivity,
+				Ready:         conn.Ready,
+				Key:           conn.key,
+				RTT:           conn.rtt,
+			}
+			conn.mu.RUnlock()
 		}
-		totalStreams += len(conn.Streams)
-
-		stats.Connections[addr] = ConnectionStats{
-			Address:       addr,
-			StreamsActive: activeStreams,
-			StreamsTotal:  len(conn.Streams),
-			LastActivity:  conn.LastActivity,
-			Ready:         conn.Ready,
-		}
-		conn.mu.RUnlock()
 	}
+	stats.ActiveConnections = len(stats.Connections)
 
 	stats.TotalStreams = totalStreams
 	return stats
@@ -754,24 +790,14 @@ func boolToUint32(b bool) uint32 {
 	return 0
 }
 
-func convertSettings(settings map[http2.SettingID]uint32) []http2.Setting {
-	var result []http2.Setting
-	for id, val := range settings {
-		result = append(result, http2.Setting{
-			ID:  id,
-			Val: val,
-		})
-	}
-	return result
-}
-
 func containsUpgradeSuccess(response string) bool {
 	// Check for successful upgrade response
 	return len(response) > 12 && response[:12] == "HTTP/1.1 101"
 }
 
-// connectViaProxy establishes connection through HTTP/HTTPS/SOCKS proxy
-func (t *Transport) connectViaProxy(ctx context.Context, targetAddr, serverName string, opts *Options) (net.Conn, error) {
+// connectViaProxy establishes connection through HTTP/HTTPS/SOCKS proxy. timer
+// records the connection to the proxy as TCP and the tunnel setup as proxy phase.
+func (t *Transport) connectViaProxy(ctx context.Context, targetAddr, serverName string, opts *Options, timer *timing.Timer) (net.Conn, error) {
 	proxy := opts.Proxy
 	if proxy == nil {
 		return nil, fmt.Errorf("proxy configuration is nil")
@@ -799,26 +825,33 @@ func (t *Transport) connectViaProxy(ctx context.Context, targetAddr, serverName
 	}
 
 	// Route to appropriate proxy handler
+	var conn net.Conn
+	var err error
+	timer.StartTCP()
 	switch proxy.Type {
 	case "http", "https":
-		return t.connectViaHTTPProxy(ctx, proxy, proxyAddr, targetAddr, serverName, timeout, opts)
+		conn, err = t.connectViaHTTPProxy(ctx, proxy, proxyAddr, targetAddr, serverName, timeout, opts, timer)
 	case "socks4":
-		return t.connectViaSOCKS4Proxy(ctx, proxy, proxyAddr, targetAddr, timeout)
+		conn, err = t.connectViaSOCKS4Proxy(ctx, proxy, proxyAddr, targetAddr, timeout, opts, timer)
 	case "socks5":
-		return t.connectViaSOCKS5Proxy(ctx, proxy, proxyAddr, targetAddr, timeout)
+		conn, err = t.connectViaSOCKS5Proxy(ctx, proxy, proxyAddr, targetAddr, timeout, opts, timer)
 	default:
 		return nil, fmt.Errorf("unsupported proxy type: %s", proxy.Type)
 	}
+	timer.EndProxy()
+	opts.Trace.TraceProxyConnectDone(proxy.Type, proxyAddr, err)
+	return conn, err
 }
 
 // connectViaHTTPProxy connects through HTTP/HTTPS CONNECT proxy
-func (t *Transport) connectViaHTTPProxy(ctx context.Context, proxy *ProxyConfig, proxyAddr, targetAddr, serverName string, timeout time.Duration, opts *Options) (net.Conn, error) {
+func (t *Transport) connectViaHTTPProxy(ctx context.Context, proxy *ProxyConfig, proxyAddr, targetAddr, serverName string, timeout time.Duration, opts *Options, timer *timing.Timer) (net.Conn, error) {
 	// Connect to proxy server
-	dialer := &net.Dialer{Timeout: timeout}
-	conn, err := dialer.DialContext(ctx, "tcp", proxyAddr)
+	conn, err := opts.dialer(timeout).Dial(ctx, "tcp", proxyAddr)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to proxy: %w", err)
 	}
+	timer.EndTCP()
+	timer.StartProxy()
 
 	// If proxy type is HTTPS, upgrade connection to TLS
 	if proxy.Type == "https" {
@@ -899,7 +932,7 @@ func (t *Transport) connectViaHTTPProxy(ctx context.Context, proxy *ProxyConfig,
 }
 
 // connectViaSOCKS4Proxy connects through a SOCKS4 proxy
-func (t *Transport) connectViaSOCKS4Proxy(ctx context.Context, proxy *ProxyConfig, proxyAddr, targetAddr string, timeout time.Duration) (net.Conn, error) {
+func (t *Transport) connectViaSOCKS4Proxy(ctx context.Context, proxy *ProxyConfig, proxyAddr, targetAddr string, timeout time.Duration, opts *Options, timer *timing.Timer) (net.Conn, error) {
 	// Parse target address
 	host, portStr, err := net.SplitHostPort(targetAddr)
 	if err != nil {
@@ -911,7 +944,8 @@ func (t *Transport) connectViaSOCKS4Proxy(ctx context.Context, proxy *ProxyConfi
 	}
 
 	// SOCKS4 requires IPv4 address - resolve hostname
-	ips, err := net.LookupIP(host)
+	dialer := opts.dialer(timeout)
+	ips, err := dialer.LookupIP(ctx, host)
 	if err != nil {
 		return nil, fmt.Errorf("DNS resolution failed for %s: %w", host, err)
 	}
@@ -928,11 +962,12 @@ func (t *Transport) connectViaSOCKS4Proxy(ctx context.Context, proxy *ProxyConfi
 	}
 
 	// Connect to SOCKS4 proxy
-	dialer := &net.Dialer{Timeout: timeout}
-	conn, err := dialer.DialContext(ctx, "tcp", proxyAddr)
+	conn, err := dialer.Dial(ctx, "tcp", proxyAddr)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to SOCKS4 proxy: %w", err)
 	}
+	timer.EndTCP()
+	timer.StartProxy()
 
 	// Build SOCKS4 request
 	// Format: [VER(0x04)][CMD(0x01=CONNECT)][PORT(2 bytes)][IP(4 bytes)][USERID][NULL]
@@ -985,7 +1020,7 @@ func (t *Transport) connectViaSOCKS4Proxy(ctx context.Context, proxy *ProxyConfi
 }
 
 // connectViaSOCKS5Proxy connects through a SOCKS5 proxy using golang.org/x/net/proxy
-func (t *Transport) connectViaSOCKS5Proxy(ctx context.Context, proxy *ProxyConfig, proxyAddr, targetAddr string, timeout time.Duration) (net.Conn, error) {
+func (t *Transport) connectViaSOCKS5Proxy(ctx context.Context, proxy *ProxyConfig, proxyAddr, targetAddr string, timeout time.Duration, opts *Options, timer *timing.Timer) (net.Conn, error) {
 	// Create SOCKS5 authentication if credentials provided
 	var auth *netproxy.Auth
 	if proxy.Username != "" {
@@ -995,15 +1030,24 @@ func (t *Transport) connectViaSOCKS5Proxy(ctx context.Context, proxy *ProxyConfi
 		}
 	}
 
+	// Connect to the SOCKS5 proxy; the handshake then runs over this connection
+	proxyConn, err := opts.dialer(timeout).Dial(ctx, "tcp", proxyAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to SOCKS5 proxy: %w", err)
+	}
+	timer.EndTCP()
+	timer.StartProxy()
+
 	// Create SOCKS5 dialer
-	dialer, err := netproxy.SOCKS5("tcp", proxyAddr, auth, &net.Dialer{Timeout: timeout})
+	dialer, err := netproxy.SOCKS5("tcp", proxyAddr, auth, transport.ConnectedForward(proxyConn))
 	if err != nil {
+		proxyConn.Close()
 		return nil, fmt.Errorf("failed to create SOCKS5 dialer: %w", err)
 	}
 
 	// Dial target through SOCKS5 proxy
 	// Note: golang.org/x/net/proxy automatically resolves DNS via proxy by default
-	conn, err := dialer.Dial("tcp", targetAddr)
+	conn, err := dialer.(netproxy.ContextDialer).DialContext(ctx, "tcp", targetAddr)
 	if err != nil {
 		return nil, fmt.Errorf("SOCKS5 connection failed: %w", err)
 	}
@@ -4,11 +4,14 @@ import (
 	"bytes"
 	"crypto/tls"
 	"fmt"
+	"io"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/WhileEndless/go-rawhttp/pkg/timing"
+	"github.com/WhileEndless/go-rawhttp/pkg/transport"
 	"golang.org/x/net/http2"
 	"golang.org/x/net/http2/hpack"
 )
@@ -44,7 +47,10 @@ type Options struct {
 	// HeaderTableSize sets HPACK table size (SETTINGS_HEADER_TABLE_SIZE)
 	HeaderTableSize uint32
 
-	// DisableServerPush disables server push (SETTINGS_ENABLE_PUSH = 0)
+	// DisableServerPush disables server push (SETTINGS_ENABLE_PUSH = 0). When
+	// false, pushed responses are read and attached to Response.ServerPush.
+	// Pushed streams still open ReadTimeout (10s when unset) after the response
+	// ended are cancelled and reported through PushPromise.Err.
 	DisableServerPush bool
 
 	// EnableCompression enables HPACK header compression
@@ -54,11 +60,30 @@ type Options struct {
 	EnableMultiplexing bool
 	ReuseConnection    bool
 
+	// MaxConnsPerHost caps the pooled connections per pool key (0 = unlimited).
+	// Requests go to the least-loaded connection below the peer's
+	// SETTINGS_MAX_CONCURRENT_STREAMS; another connection is dialed only when all
+	// are saturated, and at the cap requests wait for a free stream slot.
+	MaxConnsPerHost int
+
 	// ReadTimeout bounds how long a request waits for response frames (v2.2.0+).
 	// Enforced per-request by the request goroutine (rolling: reset on each received
 	// frame). 0 means no per-request timeout. Threaded from client.Options.ReadTimeout.
 	ReadTimeout time.Duration
 
+	// PingTimeout is how long the health checker waits for the ACK of the PING
+	// it sends on idle pooled connections before evicting the connection as dead
+	// (default: 15s). Connections take it from the request that opened them.
+	PingTimeout time.Duration
+
+	// Response size limits, threaded from client.Options. MaxHeaderBytes counts
+	// each header field as name + value + 32 bytes (RFC 9113 Section 6.5.2). A
+	// response exceeding one ends with a limit error and the stream is cancelled.
+	// 0 means unlimited.
+	MaxHeaderBytes int
+	MaxHeaderCount int
+	MaxBodyBytes   int64
+
 	// TLS configuration
 	// InsecureTLS skips TLS certificate verification (for testing/development).
 	// IMPORTANT (DEF-13): This flag ALWAYS overrides TLSConfig.InsecureSkipVerify,
@@ -87,6 +112,10 @@ type Options struct {
 	TLSRenegotiation tls.RenegotiationSupport // TLS renegotiation support
 	CipherSuites     []uint16                 // Allowed cipher suites
 
+	// TLSFingerprint shapes the ClientHello like a browser's (nil: crypto/tls's
+	// own ClientHello). The ALPN extension offers alpnProtocols.
+	TLSFingerprint *transport.TLSFingerprint
+
 	// SNI specifies custom Server Name Indication for TLS handshake.
 	// Priority: TLSConfig.ServerName > SNI > Host (if DisableSNI is false)
 	SNI string
@@ -100,6 +129,24 @@ type Options struct {
 	// All proxy types supported: http, https, socks4, socks5
 	Proxy *ProxyConfig
 
+	// DialContext replaces net.Dialer for every connection, including the ones to
+	// proxies. Resolver, when set, resolves target and proxy host names before
+	// dialing (and the SOCKS4 target). Threaded from client.Options.
+	DialContext transport.DialContextFunc
+	Resolver    transport.Resolver
+
+	// Network, HappyEyeballs and FallbackDelay control direct connections the
+	// same way as transport.Config: Network forces "tcp4" or "tcp6", and
+	// HappyEyeballs races the addresses of both families (RFC 8305).
+	Network       string
+	HappyEyeballs bool
+	FallbackDelay time.Duration
+
+	// Trace receives the lifecycle hooks of the request and its connection,
+	// including FrameRead/FrameWritten for the request's stream. Threaded from
+	// client.Options.
+	Trace *transport.ClientTrace
+
 	// EnableProtocolFallback is passed from client.Options (DEF-16, v2.1.4+).
 	// Used internally to determine if fallback to HTTP/1.1 should occur on failure.
 	EnableProtocolFallback bool
@@ -111,6 +158,18 @@ type Options struct {
 	// Priority settings
 	Priority *PriorityParam
 
+	// Fingerprint replaces the connection preface (SETTINGS, connection
+	// WINDOW_UPDATE, PRIORITY frames) and the request HEADERS layout
+	// (pseudo-header and header order, HEADERS priority) with a client profile;
+	// see Profile and ParseAkamaiFingerprint. Connections with different
+	// profiles are pooled apart.
+	Fingerprint *Fingerprint
+
+	// SinglePacket makes DoMulti withhold the last frame of every request and
+	// then write all of them in one TCP write, so the requests complete at the
+	// server at the same moment (single-packet attack timing).
+	SinglePacket bool
+
 	// Debug contains HTTP/2 debugging flags (optional, all default to false).
 	// These flags enable detailed logging of HTTP/2 protocol operations.
 	// Production safe - explicit opt-in with zero overhead when disabled.
@@ -147,8 +206,12 @@ type Frame interface {
 
 // HeadersFrame represents an HTTP/2 HEADERS frame
 type HeadersFrame struct {
-	StreamId   uint32
-	Headers    map[string]string
+	StreamId uint32
+	Headers  map[string]string
+	// Fields is the header block in wire order, duplicates included. Set on
+	// received frames (Headers keeps only the last value per name); on frames
+	// to send it takes precedence over Headers, fixing the encoded order.
+	Fields     []hpack.HeaderField
 	EndStream  bool
 	EndHeaders bool
 	Priority   *PriorityParam
@@ -181,6 +244,10 @@ type DataFrame struct {
 	Data      []byte
 	EndStream bool
 	PadLength uint8
+
+	// Length is the payload length of a received frame. Frames of a streamed
+	// body (BodyStream) keep only their length; Data is nil.
+	Length int
 }
 
 func (f *DataFrame) Type() http2.FrameType { return http2.FrameData }
@@ -210,12 +277,42 @@ type Stream struct {
 	DataReceived    bool
 	Closed          bool
 
-	// Multiplexing dispatch (v2.2.0+): the connection's single read loop routes
-	// decoded frames for this stream into inbox. done is closed by the owning
-	// request goroutine when it stops reading, so the read loop never blocks on a
-	// finished/abandoned stream.
-	inbox chan frameEvent
-	done  chan struct{}
+	// Multiplexing dispatch (v2.2.0+): the connection's single read loop queues
+	// decoded frames for this stream in inbox and signals ready. The queue never
+	// blocks the read loop; the stream window bounds it, as the stream's DATA
+	// credit is returned only once the request goroutine consumes the bytes. done
+	// is closed by the owning request goroutine when it stops reading; finished is
+	// set with it, under inboxMu.
+	inboxMu  sync.Mutex
+	inbox    []frameEvent
+	ready    chan struct{}
+	finished bool
+	done     chan struct{}
+
+	// pending holds request frames that follow the HEADERS frame (DATA, trailers);
+	// they are written by sendPending under send-side flow control. peerDone is set
+	// (under conn.mu) once the peer reset or ended the stream, cancelled once we
+	// reset it.
+	pending   []Frame
+	peerDone  bool
+	cancelled bool
+
+	// final is the withheld last request frame of a single-packet batch (DoMulti),
+	// sentAt the time it was released. openedAt and firstEventAt (set by the
+	// request goroutine) time the stream.
+	final        Frame
+	sentAt       time.Time
+	openedAt     time.Time
+	firstEventAt time.Time
+
+	// trace receives the request hooks and the frames of this stream; timer (set
+	// by Client.Do) records its TTFB and body phases.
+	trace *transport.ClientTrace
+	timer *timing.Timer
+
+	// limits bound the response header blocks of this stream; the read loop
+	// enforces them while decoding.
+	limits headerLimits
 }
 
 // StreamState represents the state of an HTTP/2 stream
@@ -238,22 +335,39 @@ type Request struct {
 	Authority string
 	Scheme    string
 	Headers   map[string]string
-	Body      []byte
-	RawText   []byte // Original HTTP/1.1-style raw request
+	// HeaderOrder lists the names of Headers (canonical form) in the order of
+	// the raw request.
+	HeaderOrder []string
+	Body        []byte
+	// Trailers are sent as a trailing HEADERS frame after the body. They are taken
+	// from the trailer section of a "Transfer-Encoding: chunked" raw request.
+	Trailers map[string]string
+	RawText  []byte // Original HTTP/1.1-style raw request
 }
 
 // Response represents an HTTP/2 response
 type Response struct {
-	Status      int
-	StatusText  string
-	Headers     map[string][]string
+	Status     int
+	StatusText string
+	Headers    map[string][]string
+	// HeaderList is the exact decoded header list of the response (pseudo-headers
+	// included) in wire order, with duplicates such as repeated set-cookie fields
+	// preserved. Trailer fields are not included (see Trailers).
+	HeaderList []hpack.HeaderField
+	// Trailers holds the fields of a HEADERS block received after the response head
+	// (RFC 9113 Section 8.1), e.g. grpc-status. For streamed bodies it is filled
+	// once the stream reaches EOF.
+	Trailers    map[string][]string
 	Body        []byte
 	Frames      []Frame
 	RawFrames   [][]byte
 	HTTPVersion string
 
 	// HTTP/2 specific metadata
-	StreamID   uint32
+	StreamID uint32
+	// ServerPush holds the streams the server promised while sending this
+	// response, each with its pushed response. For streamed bodies the pushed
+	// responses are complete once BodyStream reaches EOF.
 	ServerPush []*PushPromise
 	HPACKStats *HPACKStats
 	FrameStats *FrameStats
@@ -271,17 +385,66 @@ type Response struct {
 	TLSServerName      string // TLS Server Name (SNI)
 	ConnectionReused   bool   // Whether connection was reused from pool
 
+	// ClientHello fingerprints of the connection's TLS handshake
+	JA3     string // JA3 string ("version,ciphers,extensions,groups,point formats")
+	JA3Hash string // MD5 of JA3, hex-encoded
+	JA4     string // JA4 fingerprint
+
+	// Informational holds the interim 1xx responses (e.g. 103 Early Hints) received
+	// before the final HEADERS block, in arrival order.
+	Informational []InterimResponse
+
 	// Proxy metadata (added for consistency with HTTP/1.1)
 	ProxyUsed bool   // Whether an upstream proxy was used
 	ProxyType string // Proxy type (http, https, socks4, socks5)
 	ProxyAddr string // Proxy server address
+
+	// DialAttempts lists the addresses tried when the connection was opened and
+	// their outcomes (empty for proxied connections).
+	DialAttempts []transport.DialAttempt
+
+	// RTT is the connection's round-trip time measured by the last acknowledged
+	// PING (see Connection.Ping), 0 if none was measured yet.
+	RTT time.Duration
+
+	// PeerPreface holds what the server sent while opening the connection, and
+	// PeerSettings the server's SETTINGS values in effect when the response was
+	// read (mid-connection updates included). Together they fingerprint the
+	// server's HTTP/2 stack.
+	PeerPreface  *PeerPreface
+	PeerSettings map[http2.SettingID]uint32
+
+	// BodyStream exposes the response body as a stream (only set by
+	// DoStreamWithOptions); Body stays empty. Callers MUST Close it.
+	BodyStream io.ReadCloser
+}
+
+// PeerPreface records the frames a server sent during the connection's SETTINGS
+// handshake, up to the ACK of the client's SETTINGS.
+type PeerPreface struct {
+	Settings     []http2.Setting     // The server's first SETTINGS frame, in wire order
+	WindowUpdate uint32              // Increment of its first connection-level WINDOW_UPDATE (0 if none)
+	Frames       []http2.FrameHeader // Every frame received, in order
+}
+
+// InterimResponse is an informational 1xx HEADERS block received before the
+// final response on a stream.
+type InterimResponse struct {
+	Status     int
+	StatusText string
+	Headers    map[string][]string
+	HeaderList []hpack.HeaderField // Decoded fields in wire order
+	ReceivedAt time.Time
 }
 
 // PushPromise represents a server push promise
 type PushPromise struct {
 	PromisedStreamID uint32
-	Headers          map[string]string
-	Response         *Response
+	Headers          map[string]string   // Promised request headers (last value per name)
+	HeaderList       []hpack.HeaderField // Promised request header fields in wire order
+	ReceivedAt       time.Time           // When the PUSH_PROMISE was received
+	Response         *Response           // Pushed response (nil if Err is set)
+	Err              error               // Error reading the pushed response
 }
 
 // HPACKStats contains HPACK compression statistics
@@ -310,17 +473,22 @@ type ConnectionPoolStats struct {
 	// Total number of streams across all connections
 	TotalStreams int
 
-	// Connection details (map of address to connection stats)
+	// Connection details, keyed by "<pool key>#<connection ID>"
 	Connections map[string]ConnectionStats
 }
 
 // ConnectionStats contains statistics for a single HTTP/2 connection
 type ConnectionStats struct {
-	Address        string    // Connection address (host:port)
-	StreamsActive  int       // Currently active streams on this connection
-	StreamsTotal   int       // Total streams created on this connection
-	LastActivity   time.Time // Last activity timestamp
-	Ready          bool      // True if connection is ready for use
+	Address       string            // Pool key of the connection (see Key)
+	ID            uint64            // Distinguishes the connections sharing a pool key
+	StreamsActive int               // Currently active streams on this connection
+	StreamsTotal  int               // Total streams created on this connection
+	StreamsMax    uint32            // Peer's SETTINGS_MAX_CONCURRENT_STREAMS (0 if not advertised)
+	Load          int               // Client streams open or scheduled, as used for scheduling
+	LastActivity  time.Time         // Last activity timestamp
+	Ready         bool              // True if connection is ready for use
+	Key           transport.PoolKey // Target, proxy and TLS dimensions of this connection
+	RTT           time.Duration     // Round-trip time of the last acknowledged PING (0 if none)
 }
 
 // Connection represents an HTTP/2 connection
@@ -332,11 +500,11 @@ type Connection struct {
 	Decoder        *hpack.Decoder
 	Streams        map[uint32]*Stream
 	NextStreamID   uint32
-	MaxConcurrent  uint32
+	MaxConcurrent  uint32 // Peer's SETTINGS_MAX_CONCURRENT_STREAMS once advertised
 	WindowSize     int32
 	PeerWindowSize int32
 	Settings       map[http2.SettingID]uint32
-	PeerSettings   map[http2.SettingID]uint32
+	PeerSettings   map[http2.SettingID]uint32 // Latest value of every SETTINGS parameter the peer sent
 	Closed         bool
 	Ready          bool         // True when SETTINGS handshake is complete
 	LastActivity   time.Time    // For idle timeout tracking
@@ -354,6 +522,57 @@ type Connection struct {
 	connErr            error
 	goAwayReceived     bool
 	goAwayLastStreamID uint32
+
+	// Send-side flow control: the peer's SETTINGS_INITIAL_WINDOW_SIZE and
+	// SETTINGS_MAX_FRAME_SIZE, and a channel closed (and replaced) whenever send
+	// credit may have grown. Guarded by mu; see flow.go.
+	peerInitialWindow int32
+	peerMaxFrameSize  uint32
+	flowCh            chan struct{}
+
+	// headerBlock is the header block being reassembled from CONTINUATION frames
+	// (read loop only); maxHeaderBlock bounds it (set with our SETTINGS).
+	headerBlock    *headerBlock
+	maxHeaderBlock uint32
+
+	// key holds the dimensions behind PoolKey (set at creation).
+	key transport.PoolKey
+
+	// dialAttempts records how the connection was dialed (set at creation).
+	dialAttempts []transport.DialAttempt
+
+	// preface records the server's handshake frames (set at creation).
+	preface *PeerPreface
+
+	// hello holds the fingerprints of the TLS ClientHello (set at creation).
+	hello transport.ClientHelloFingerprint
+
+	// fingerprint lays out the request HEADERS frames (set at creation, from the
+	// options of the dialing request).
+	fingerprint *Fingerprint
+
+	// Liveness (see ping.go): outstanding PINGs by payload, the last measured
+	// round-trip time (both guarded by mu), how long the health checker waits for
+	// an ACK, and whether its probe is running.
+	pings       map[[8]byte]chan struct{}
+	pingSeq     uint64
+	rtt         time.Duration
+	pingTimeout time.Duration
+	probing     atomic.Bool
+
+	// Scheduling (see pool.go), guarded by mu: the connection's ID within the
+	// pool, stream slots reserved for requests about to open a stream, and
+	// whether the connection was retired ahead of stream-ID exhaustion (it is
+	// closed once its last stream ends).
+	id       uint64
+	reserved int
+	retired  bool
+
+	// trace is the trace of the request that opened the connection; it receives
+	// connection-level frames. tracing is set once any traced request used the
+	// connection, so untraced connections skip frame reporting. See trace.go.
+	trace   *transport.ClientTrace
+	tracing atomic.Bool
 }
 
 // Close gracefully closes the HTTP/2 connection (sends a GOAWAY).
@@ -435,6 +654,13 @@ func (c *Connection) wasReused() bool {
 	return c.Reused
 }
 
+// idleTime returns how long the connection has seen no activity.
+func (c *Connection) idleTime() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return time.Since(c.LastActivity)
+}
+
 // DefaultOptions returns default HTTP/2 options (aligned with Go's native HTTP/2).
 // All SETTINGS values are set to recommended defaults per RFC 7540.
 func DefaultOptions() *Options {
@@ -3,28 +3,62 @@ package timing
 
 import (
 	"fmt"
+	"strings"
 	"time"
 )
 
 // Metrics captures detailed timing information for a request.
 // All fields are properly named to match industry-standard conventions.
 type Metrics struct {
+	// PoolWait is the time spent waiting for a pooled connection (or a free
+	// connection slot) before connecting
+	PoolWait time.Duration `json:"pool_wait,omitempty"`
+
 	// DNSLookup is the time spent performing DNS resolution
 	DNSLookup time.Duration `json:"dns_lookup"`
 
-	// TCPConnect is the time spent establishing TCP connection (handshake)
+	// TCPConnect is the time spent establishing TCP connection (handshake).
+	// Through a proxy, this is the connection to the proxy.
 	TCPConnect time.Duration `json:"tcp_connect"`
 
+	// ProxyConnect is the time spent setting up the tunnel through a proxy once
+	// connected to it: the CONNECT exchange (and TLS to an HTTPS proxy) or the
+	// SOCKS handshake
+	ProxyConnect time.Duration `json:"proxy_connect,omitempty"`
+
 	// TLSHandshake is the time spent performing TLS handshake (0 for HTTP)
 	TLSHandshake time.Duration `json:"tls_handshake"`
 
+	// SettingsExchange is the HTTP/2 connection setup after TLS: from sending
+	// the preface and SETTINGS to receiving the server's acknowledgement
+	SettingsExchange time.Duration `json:"settings_exchange,omitempty"`
+
+	// RequestWrite is the time spent writing the request (HTTP/2: its frames,
+	// including waits for flow-control credit)
+	RequestWrite time.Duration `json:"request_write,omitempty"`
+
 	// TTFB (Time To First Byte) is the time spent waiting for the first response byte
 	// This represents server processing time
 	TTFB time.Duration `json:"ttfb"`
 
+	// FirstBodyByte is the time from the same starting point as TTFB to the first
+	// byte of the response body; FirstBodyByte - TTFB is the time spent receiving
+	// the response head. Zero for responses without a body.
+	FirstBodyByte time.Duration `json:"first_body_byte,omitempty"`
+
+	// BodyTransfer is the time from the first to the last byte of the body
+	BodyTransfer time.Duration `json:"body_transfer,omitempty"`
+
 	// TotalTime is the total end-to-end request time
 	TotalTime time.Duration `json:"total_time"`
 
+	// Start is when the measurement started
+	Start time.Time `json:"start,omitempty"`
+
+	// Phases lists every measured phase with its absolute start and end, in the
+	// order the phases started
+	Phases []Phase `json:"phases,omitempty"`
+
 	// Deprecated: Use DNSLookup instead
 	DNS time.Duration `json:"dns,omitempty"`
 
@@ -38,17 +72,62 @@ type Metrics struct {
 	Total time.Duration `json:"total,omitempty"`
 }
 
-// Timer helps measure request timings.
+// Phase names used in Metrics.Phases.
+const (
+	PhasePoolWait = "pool_wait"
+	PhaseDNS      = "dns"
+	PhaseTCP      = "tcp"
+	PhaseProxy    = "proxy"
+	PhaseTLS      = "tls"
+	PhaseSettings = "settings"
+	PhaseWrite    = "write"
+	PhaseTTFB     = "ttfb"
+	PhaseBody     = "body"
+)
+
+// Phase is one measured phase of a request.
+type Phase struct {
+	Name  string    `json:"name"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// Duration returns the length of the phase.
+func (p Phase) Duration() time.Duration {
+	return p.End.Sub(p.Start)
+}
+
+// phase indexes the spans of a Timer.
+type phase int
+
+const (
+	poolWait phase = iota
+	dns
+	tcp
+	proxy
+	tls
+	settings
+	write
+	ttfb
+	body
+	numPhases
+)
+
+var phaseNames = [numPhases]string{
+	PhasePoolWait, PhaseDNS, PhaseTCP, PhaseProxy, PhaseTLS,
+	PhaseSettings, PhaseWrite, PhaseTTFB, PhaseBody,
+}
+
+// span is the start and end of a phase.
+type span struct {
+	start, end time.Time
+}
+
+// Timer helps measure request timings. All methods are safe to call on a nil
+// *Timer, which measures nothing, so code paths without a timer need no checks.
 type Timer struct {
-	start     time.Time
-	dnsStart  time.Time
-	dnsEnd    time.Time
-	tcpStart  time.Time
-	tcpEnd    time.Time
-	tlsStart  time.Time
-	tlsEnd    time.Time
-	ttfbStart time.Time
-	ttfbEnd   time.Time
+	start time.Time
+	spans [numPhases]span
 }
 
 // NewTimer creates a new timing measurement session.
@@ -58,83 +137,135 @@ func NewTimer() *Timer {
 	}
 }
 
-// StartDNS marks the beginning of DNS resolution.
-func (t *Timer) StartDNS() {
-	t.dnsStart = time.Now()
+func (t *Timer) begin(p phase) {
+	if t != nil {
+		t.spans[p].start = time.Now()
+	}
 }
 
-// EndDNS marks the end of DNS resolution.
-func (t *Timer) EndDNS() {
-	t.dnsEnd = time.Now()
+func (t *Timer) end(p phase) {
+	if t != nil {
+		t.spans[p].end = time.Now()
+	}
 }
 
+// StartPoolWait marks the beginning of the wait for a pooled connection.
+func (t *Timer) StartPoolWait() { t.begin(poolWait) }
+
+// EndPoolWait marks the end of the wait for a pooled connection.
+func (t *Timer) EndPoolWait() { t.end(poolWait) }
+
+// StartDNS marks the beginning of DNS resolution.
+func (t *Timer) StartDNS() { t.begin(dns) }
+
+// EndDNS marks the end of DNS resolution.
+func (t *Timer) EndDNS() { t.end(dns) }
+
 // StartTCP marks the beginning of TCP connection.
-func (t *Timer) StartTCP() {
-	t.tcpStart = time.Now()
-}
+func (t *Timer) StartTCP() { t.begin(tcp) }
 
 // EndTCP marks the end of TCP connection.
-func (t *Timer) EndTCP() {
-	t.tcpEnd = time.Now()
-}
+func (t *Timer) EndTCP() { t.end(tcp) }
+
+// StartProxy marks the beginning of the tunnel setup through a proxy.
+func (t *Timer) StartProxy() { t.begin(proxy) }
+
+// EndProxy marks the end of the tunnel setup through a proxy.
+func (t *Timer) EndProxy() { t.end(proxy) }
 
 // StartTLS marks the beginning of TLS handshake.
-func (t *Timer) StartTLS() {
-	t.tlsStart = time.Now()
-}
+func (t *Timer) StartTLS() { t.begin(tls) }
 
 // EndTLS marks the end of TLS handshake.
-func (t *Timer) EndTLS() {
-	t.tlsEnd = time.Now()
-}
+func (t *Timer) EndTLS() { t.end(tls) }
+
+// StartSettings marks the beginning of the HTTP/2 SETTINGS exchange.
+func (t *Timer) StartSettings() { t.begin(settings) }
+
+// EndSettings marks the end of the HTTP/2 SETTINGS exchange.
+func (t *Timer) EndSettings() { t.end(settings) }
+
+// StartWrite marks the beginning of the request write.
+func (t *Timer) StartWrite() { t.begin(write) }
+
+// EndWrite marks the end of the request write.
+func (t *Timer) EndWrite() { t.end(write) }
 
 // StartTTFB marks when we start waiting for the first response byte.
-func (t *Timer) StartTTFB() {
-	t.ttfbStart = time.Now()
-}
+func (t *Timer) StartTTFB() { t.begin(ttfb) }
 
 // EndTTFB marks when we receive the first response byte.
-func (t *Timer) EndTTFB() {
-	t.ttfbEnd = time.Now()
-}
+func (t *Timer) EndTTFB() { t.end(ttfb) }
+
+// StartBody marks the arrival of the first response body byte.
+func (t *Timer) StartBody() { t.begin(body) }
+
+// EndBody marks the arrival of the last response body byte.
+func (t *Timer) EndBody() { t.end(body) }
 
 // GetMetrics returns the calculated timing metrics.
 func (t *Timer) GetMetrics() Metrics {
+	if t == nil {
+		return Metrics{}
+	}
 	totalTime := time.Since(t.start)
 
 	metrics := Metrics{
 		TotalTime: totalTime,
 		Total:     totalTime, // Deprecated: for backward compatibility
+		Start:     t.start,
 	}
 
-	if !t.dnsStart.IsZero() && !t.dnsEnd.IsZero() {
-		dnsTime := t.dnsEnd.Sub(t.dnsStart)
-		metrics.DNSLookup = dnsTime
-		metrics.DNS = dnsTime // Deprecated: for backward compatibility
+	for p, s := range t.spans {
+		if s.start.IsZero() || s.end.IsZero() {
+			continue
+		}
+		d := s.end.Sub(s.start)
+		switch phase(p) {
+		case poolWait:
+			metrics.PoolWait = d
+		case dns:
+			metrics.DNSLookup = d
+			metrics.DNS = d // Deprecated: for backward compatibility
+		case tcp:
+			metrics.TCPConnect = d
+			metrics.TCP = d // Deprecated: for backward compatibility
+		case proxy:
+			metrics.ProxyConnect = d
+		case tls:
+			metrics.TLSHandshake = d
+			metrics.TLS = d // Deprecated: for backward compatibility
+		case settings:
+			metrics.SettingsExchange = d
+		case write:
+			metrics.RequestWrite = d
+		case ttfb:
+			metrics.TTFB = d
+		case body:
+			metrics.BodyTransfer = d
+		}
+		metrics.Phases = append(metrics.Phases, Phase{Name: phaseNames[p], Start: s.start, End: s.end})
 	}
 
-	if !t.tcpStart.IsZero() && !t.tcpEnd.IsZero() {
-		tcpTime := t.tcpEnd.Sub(t.tcpStart)
-		metrics.TCPConnect = tcpTime
-		metrics.TCP = tcpTime // Deprecated: for backward compatibility
+	if w, b := t.spans[ttfb].start, t.spans[body].start; !w.IsZero() && !b.IsZero() {
+		metrics.FirstBodyByte = b.Sub(w)
 	}
 
-	if !t.tlsStart.IsZero() && !t.tlsEnd.IsZero() {
-		tlsTime := t.tlsEnd.Sub(t.tlsStart)
-		metrics.TLSHandshake = tlsTime
-		metrics.TLS = tlsTime // Deprecated: for backward compatibility
-	}
-
-	if !t.ttfbStart.IsZero() && !t.ttfbEnd.IsZero() {
-		metrics.TTFB = t.ttfbEnd.Sub(t.ttfbStart)
+	// Phases are listed in start order; a pooled connection, for instance, is
+	// waited for before anything else but may have no connection phases at all.
+	for i := 1; i < len(metrics.Phases); i++ {
+		for j := i; j > 0 && metrics.Phases[j].Start.Before(metrics.Phases[j-1].Start); j-- {
+			metrics.Phases[j], metrics.Phases[j-1] = metrics.Phases[j-1], metrics.Phases[j]
+		}
 	}
 
 	return metrics
 }
 
-// GetConnectionTime returns the total connection establishment time (DNS + TCP + TLS).
+// GetConnectionTime returns the total connection establishment time (DNS + TCP +
+// proxy tunnel + TLS).
 func (m Metrics) GetConnectionTime() time.Duration {
-	return m.DNSLookup + m.TCPConnect + m.TLSHandshake
+	return m.DNSLookup + m.TCPConnect + m.ProxyConnect + m.TLSHandshake
 }
 
 // GetServerTime returns the server processing time.
@@ -147,8 +278,24 @@ func (m Metrics) GetNetworkTime() time.Duration {
 	return m.TotalTime - m.TTFB
 }
 
-// String provides a human-readable representation of the metrics.
+// String provides a human-readable representation of the metrics. Phases that
+// were not measured for the request (pool wait, proxy, SETTINGS, ...) are left out.
 func (m Metrics) String() string {
-	return fmt.Sprintf("DNSLookup: %v, TCPConnect: %v, TLSHandshake: %v, TTFB: %v, TotalTime: %v",
-		m.DNSLookup, m.TCPConnect, m.TLSHandshake, m.TTFB, m.TotalTime)
+	var b strings.Builder
+	optional := func(name string, d time.Duration) {
+		if d > 0 {
+			fmt.Fprintf(&b, "%s: %v, ", name, d)
+		}
+	}
+	optional("PoolWait", m.PoolWait)
+	fmt.Fprintf(&b, "DNSLookup: %v, TCPConnect: %v, ", m.DNSLookup, m.TCPConnect)
+	optional("ProxyConnect", m.ProxyConnect)
+	fmt.Fprintf(&b, "TLSHandshake: %v, ", m.TLSHandshake)
+	optional("SettingsExchange", m.SettingsExchange)
+	optional("RequestWrite", m.RequestWrite)
+	fmt.Fprintf(&b, "TTFB: %v, ", m.TTFB)
+	optional("FirstBodyByte", m.FirstBodyByte)
+	optional("BodyTransfer", m.BodyTransfer)
+	fmt.Fprintf(&b, "TotalTime: %v", m.TotalTime)
+	return b.String()
 }
@@ -0,0 +1,134 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/WhileEndless/go-rawhttp/pkg/errors"
+	netproxy "golang.org/x/net/proxy"
+)
+
+// DialContextFunc dials a connection, with the signature of net.Dialer.DialContext.
+// It lets callers route connections through in-memory pipes, network namespaces
+// or their own network stack.
+type DialContextFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// Resolver looks up the IP addresses of a host. *net.Resolver implements it.
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// Dialer opens connections for the HTTP/1.1 and HTTP/2 transports and for every
+// proxy connector, honoring the DialContext and Resolver hooks. The zero value
+// dials with net.Dialer and lets it resolve host names.
+type Dialer struct {
+	DialContext DialContextFunc // Custom dial function (nil = net.Dialer)
+	Resolver    Resolver        // Custom resolver for host names in addr (nil = system)
+	Timeout     time.Duration   // Bound on the lookup and dial (0 = none)
+	Trace       *ClientTrace    // Fires the DNS and Connect hooks (nil = none)
+}
+
+// Dial connects to addr ("host:port"). With a Resolver, a host name is resolved
+// first and the first address is dialed, so DialContext receives an IP address.
+func (d Dialer) Dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	if d.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.Timeout)
+		defer cancel()
+	}
+
+	if d.Resolver != nil {
+		if host, port, err := net.SplitHostPort(addr); err == nil && net.ParseIP(host) == nil {
+			ips, err := d.LookupIP(ctx, host)
+			if err != nil {
+				return nil, err
+			}
+			addr = net.JoinHostPort(ips[0].String(), port)
+		}
+	}
+
+	return d.dialContext(ctx, network, addr)
+}
+
+// dialContext dials addr as given, with DialContext or net.Dialer.
+func (d Dialer) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	d.Trace.TraceConnectStart(network, addr)
+	var conn net.Conn
+	var err error
+	if d.DialContext != nil {
+		conn, err = d.DialContext(ctx, network, addr)
+	} else {
+		var nd net.Dialer
+		conn, err = nd.DialContext(ctx, network, addr)
+	}
+	d.Trace.TraceConnectDone(network, addr, err)
+	return conn, err
+}
+
+// LookupIP resolves host with the Resolver (or the system resolver). It never
+// returns an empty list without an error.
+func (d Dialer) LookupIP(ctx context.Context, host string) ([]net.IP, error) {
+	// IP literals are not lookups; keep them out of the trace
+	trace := d.Trace
+	if net.ParseIP(host) != nil {
+		trace = nil
+	}
+	trace.TraceDNSStart(host)
+	addrs, err := d.resolver().LookupIPAddr(ctx, host)
+	if err == nil && len(addrs) == 0 {
+		err = errors.NewValidationError(fmt.Sprintf("no IP addresses found for %s", host))
+	}
+	if err != nil {
+		trace.TraceDNSDone(host, nil, err)
+		return nil, err
+	}
+	ips := make([]net.IP, len(addrs))
+	for i, a := range addrs {
+		ips[i] = a.IP
+	}
+	trace.TraceDNSDone(host, ips, nil)
+	return ips, nil
+}
+
+// resolver returns the Resolver, or the system resolver.
+func (d Dialer) resolver() Resolver {
+	if d.Resolver != nil {
+		return d.Resolver
+	}
+	return net.DefaultResolver
+}
+
+// Forward returns d as a forward dialer for golang.org/x/net/proxy. It also
+// implements proxy.ContextDialer, so the SOCKS handshake honors the context.
+func (d Dialer) Forward() netproxy.Dialer {
+	return forwardDialer{d}
+}
+
+type forwardDialer struct{ d Dialer }
+
+func (f forwardDialer) Dial(network, addr string) (net.Conn, error) {
+	return f.d.Dial(context.Background(), network, addr)
+}
+
+func (f forwardDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return f.d.Dial(ctx, network, addr)
+}
+
+// ConnectedForward returns a forward dialer for golang.org/x/net/proxy that hands
+// out conn, already connected to the proxy, instead of dialing. It lets the SOCKS5
+// connectors time the connection to the proxy apart from the handshake.
+func ConnectedForward(conn net.Conn) netproxy.Dialer {
+	return connectedForward{conn}
+}
+
+type connectedForward struct{ conn net.Conn }
+
+func (f connectedForward) Dial(network, addr string) (net.Conn, error) {
+	return f.conn, nil
+}
+
+func (f connectedForward) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return f.conn, nil
+}
@@ -0,0 +1,366 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/WhileEndless/go-rawhttp/pkg/errors"
+	"github.com/WhileEndless/go-rawhttp/pkg/timing"
+)
+
+// DefaultFallbackDelay is the RFC 8305 "Connection Attempt Delay": how long a
+// dual-stack race waits for an attempt before starting the next one.
+const DefaultFallbackDelay = 250 * time.Millisecond
+
+// resolutionDelay is how long a dual-stack lookup waits for the AAAA answer once
+// the A answer has arrived (RFC 8305 section 3).
+const resolutionDelay = 50 * time.Millisecond
+
+// Outcomes of a DialAttempt.
+const (
+	DialConnected = "connected" // The attempt won the race (or was the only one)
+	DialFailed    = "failed"    // The attempt returned an error
+	DialCancelled = "cancelled" // The attempt was abandoned when another one won
+)
+
+// DialAttempt records one connection attempt to a resolved address.
+type DialAttempt struct {
+	Address  string        // Dialed address ("ip:port", or "host:port" when the dialer resolves)
+	Network  string        // Address family: "tcp4" or "tcp6" ("tcp" when unknown)
+	Offset   time.Duration // Start of the attempt, relative to the first attempt
+	Duration time.Duration // Time until the attempt connected, failed or was cancelled
+	Outcome  string        // DialConnected, DialFailed or DialCancelled
+	Err      error         // Failure cause (nil unless Outcome is DialFailed)
+}
+
+// familyResolver is implemented by resolvers that can query one address family,
+// like *net.Resolver. Dual-stack lookups use it to send the A and AAAA queries in
+// parallel; other resolvers answer both families with a single lookup.
+type familyResolver interface {
+	LookupIP(ctx context.Context, network, host string) ([]net.IP, error)
+}
+
+// ValidateNetwork checks a dial network option: "" and "tcp" allow both address
+// families, "tcp4" and "tcp6" force one.
+func ValidateNetwork(network string) error {
+	switch network {
+	case "", "tcp", "tcp4", "tcp6":
+		return nil
+	}
+	return errors.NewValidationError(fmt.Sprintf("unsupported network %q (must be tcp, tcp4 or tcp6)", network))
+}
+
+// LookupNetwork resolves host like LookupIP but only returns addresses usable on
+// network ("tcp4" keeps IPv4, "tcp6" keeps IPv6, anything else keeps all).
+func (d Dialer) LookupNetwork(ctx context.Context, network, host string) ([]net.IP, error) {
+	ips, err := d.LookupIP(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	return filterNetwork(ips, network, host)
+}
+
+// LookupDualStack resolves host for a Happy Eyeballs race (RFC 8305). With a
+// family-aware resolver the A and AAAA queries run in parallel; if the A answer
+// arrives first, the AAAA answer is awaited for at most the 50 ms resolution
+// delay. The addresses are interleaved by family, IPv6 first, so a race
+// alternates between the two. A failure of one family is ignored as long as the
+// other one answers. Forcing "tcp4" or "tcp6" queries only that family.
+func (d Dialer) LookupDualStack(ctx context.Context, network, host string) ([]net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return filterNetwork([]net.IP{ip}, network, host)
+	}
+
+	fr, ok := d.resolver().(familyResolver)
+	if !ok {
+		ips, err := d.LookupNetwork(ctx, network, host)
+		if err != nil {
+			return nil, err
+		}
+		return interleaveFamilies(ips), nil
+	}
+
+	d.Trace.TraceDNSStart(host)
+	ips, err := lookupDualStack(ctx, fr, network, host)
+	d.Trace.TraceDNSDone(host, ips, err)
+	return ips, err
+}
+
+// lookupDualStack queries the families allowed by network with fr.
+func lookupDualStack(ctx context.Context, fr familyResolver, network, host string) ([]net.IP, error) {
+	switch network {
+	case "tcp4":
+		return lookupFamily(ctx, fr, "ip4", host)
+	case "tcp6":
+		return lookupFamily(ctx, fr, "ip6", host)
+	}
+
+	type answer struct {
+		ips []net.IP
+		err error
+	}
+	v4, v6 := make(chan answer, 1), make(chan answer, 1)
+	go func() {
+		ips, err := lookupFamily(ctx, fr, "ip4", host)
+		v4 <- answer{ips, err}
+	}()
+	go func() {
+		ips, err := lookupFamily(ctx, fr, "ip6", host)
+		v6 <- answer{ips, err}
+	}()
+
+	var a4, a6 answer
+	select {
+	case a6 = <-v6:
+		a4 = <-v4
+	case a4 = <-v4:
+		if a4.err != nil {
+			a6 = <-v6
+			break
+		}
+		wait := time.NewTimer(resolutionDelay)
+		defer wait.Stop()
+		select {
+		case a6 = <-v6:
+		case <-wait.C:
+			a6.err = fmt.Errorf("AAAA lookup for %s exceeded the resolution delay", host)
+		}
+	}
+
+	if a4.err != nil && a6.err != nil {
+		return nil, a4.err
+	}
+	return interleaveFamilies(append(a6.ips, a4.ips...)), nil
+}
+
+// DialParallel races connection attempts to addrs ("ip:port", in preference
+// order) as described by RFC 8305: an attempt is started every delay (the
+// Connection Attempt Delay, DefaultFallbackDelay when zero), or immediately when
+// the previous one fails. The first connection to succeed is returned and the
+// others are cancelled; connections that complete after the winner are closed.
+// Every attempt is reported, including on failure. A single address is simply
+// dialed.
+func (d Dialer) DialParallel(ctx context.Context, network string, addrs []string, delay time.Duration) (net.Conn, []DialAttempt, error) {
+	if len(addrs) == 0 {
+		return nil, nil, errors.NewValidationError("no addresses to dial")
+	}
+	if delay <= 0 {
+		delay = DefaultFallbackDelay
+	}
+	if d.Timeout > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, d.Timeout)
+		defer cancelTimeout()
+	}
+	start := time.Now()
+	if len(addrs) == 1 {
+		conn, err := d.dialContext(ctx, network, addrs[0])
+		attempt := DialAttempt{Address: addrs[0], Network: addrNetwork(addrs[0]), Duration: time.Since(start), Outcome: DialConnected}
+		if err != nil {
+			attempt.Outcome, attempt.Err = DialFailed, err
+		}
+		return conn, []DialAttempt{attempt}, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		index int
+		conn  net.Conn
+		err   error
+		at    time.Time
+	}
+	// Buffered so that attempts still running after the race is decided never block.
+	results := make(chan result, len(addrs))
+
+	attempts := make([]DialAttempt, 0, len(addrs))
+	inFlight := 0
+	launch := func() {
+		i := len(attempts)
+		attempts = append(attempts, DialAttempt{
+			Address: addrs[i],
+			Network: addrNetwork(addrs[i]),
+			Offset:  time.Since(start),
+		})
+		inFlight++
+		go func() {
+			conn, err := d.dialContext(ctx, network, addrs[i])
+			results <- result{i, conn, err, time.Now()}
+		}()
+	}
+
+	// abandon marks the attempts still in flight as cancelled and closes any
+	// connection they manage to open anyway.
+	abandon := func() {
+		now := time.Since(start)
+		for i := range attempts {
+			if attempts[i].Outcome == "" {
+				attempts[i].Outcome = DialCancelled
+				attempts[i].Duration = now - attempts[i].Offset
+			}
+		}
+		go func(n int) {
+			for ; n > 0; n-- {
+				if r := <-results; r.conn != nil {
+					r.conn.Close()
+				}
+			}
+		}(inFlight)
+	}
+
+	launch()
+	next := time.NewTimer(delay)
+	defer next.Stop()
+
+	var lastErr error
+	for {
+		select {
+		case r := <-results:
+			inFlight--
+			a := &attempts[r.index]
+			a.Duration = r.at.Sub(start) - a.Offset
+			if r.err == nil {
+				a.Outcome = DialConnected
+				cancel()
+				abandon()
+				return r.conn, attempts, nil
+			}
+			a.Outcome, a.Err = DialFailed, r.err
+			lastErr = r.err
+			if len(attempts) < len(addrs) {
+				launch()
+				next.Reset(delay)
+			} else if inFlight == 0 {
+				return nil, attempts, lastErr
+			}
+
+		case <-next.C:
+			if len(attempts) < len(addrs) {
+				launch()
+				next.Reset(delay)
+			}
+
+		case <-ctx.Done():
+			abandon()
+			if lastErr != nil {
+				return nil, attempts, fmt.Errorf("%w (last attempt error: %v)", ctx.Err(), lastErr)
+			}
+			return nil, attempts, ctx.Err()
+		}
+	}
+}
+
+// DialTarget opens a direct connection to addr ("host:port") on network. With
+// happyEyeballs the host is resolved with LookupDualStack and the addresses are
+// raced with DialParallel; otherwise the first address usable on network is
+// dialed (a single attempt), as the HTTP/1.1 transport does. timer (may be nil)
+// records the DNS and TCP phases.
+func (d Dialer) DialTarget(ctx context.Context, network, addr string, happyEyeballs bool, delay time.Duration, timer *timing.Timer) (net.Conn, []DialAttempt, error) {
+	if network == "" {
+		network = "tcp"
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	lookupCtx := ctx
+	if d.Timeout > 0 {
+		var cancel context.CancelFunc
+		lookupCtx, cancel = context.WithTimeout(ctx, d.Timeout)
+		defer cancel()
+	}
+	timer.StartDNS()
+	var ips []net.IP
+	if happyEyeballs || net.ParseIP(host) != nil {
+		ips, err = d.LookupDualStack(lookupCtx, network, host)
+	} else if ips, err = d.LookupNetwork(lookupCtx, network, host); err == nil {
+		ips = ips[:1]
+	}
+	timer.EndDNS()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	timer.StartTCP()
+	defer timer.EndTCP()
+	return d.DialParallel(ctx, network, JoinAddrs(ips, port), delay)
+}
+
+// JoinAddrs combines each IP with port into a dialable "ip:port" address.
+func JoinAddrs(ips []net.IP, port string) []string {
+	addrs := make([]string, len(ips))
+	for i, ip := range ips {
+		addrs[i] = net.JoinHostPort(ip.String(), port)
+	}
+	return addrs
+}
+
+// lookupFamily queries one address family ("ip4" or "ip6").
+func lookupFamily(ctx context.Context, fr familyResolver, family, host string) ([]net.IP, error) {
+	ips, err := fr.LookupIP(ctx, family, host)
+	if err == nil && len(ips) == 0 {
+		err = errors.NewValidationError(fmt.Sprintf("no %s addresses found for %s", family, host))
+	}
+	return ips, err
+}
+
+// filterNetwork keeps the addresses usable on network.
+func filterNetwork(ips []net.IP, network, host string) ([]net.IP, error) {
+	if network != "tcp4" && network != "tcp6" {
+		return ips, nil
+	}
+	var kept []net.IP
+	for _, ip := range ips {
+		if (ip.To4() != nil) == (network == "tcp4") {
+			kept = append(kept, ip)
+		}
+	}
+	if len(kept) == 0 {
+		return nil, errors.NewValidationError(fmt.Sprintf("no %s addresses found for %s", network, host))
+	}
+	return kept, nil
+}
+
+// interleaveFamilies orders addresses IPv6, IPv4, IPv6, ... keeping the
+// resolver's order within each family (RFC 8305 section 4).
+func interleaveFamilies(ips []net.IP) []net.IP {
+	var v4, v6 []net.IP
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			v4 = append(v4, ip)
+		} else {
+			v6 = append(v6, ip)
+		}
+	}
+	out := make([]net.IP, 0, len(ips))
+	for i := 0; i < len(v4) || i < len(v6); i++ {
+		if i < len(v6) {
+			out = append(out, v6[i])
+		}
+		if i < len(v4) {
+			out = append(out, v4[i])
+		}
+	}
+	return out
+}
+
+// addrNetwork returns the address family of an "ip:port" address.
+func addrNetwork(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "tcp"
+	}
+	ip := net.ParseIP(host)
+	switch {
+	case ip == nil:
+		return "tcp"
+	case ip.To4() != nil:
+		return "tcp4"
+	default:
+		return "tcp6"
+	}
+}
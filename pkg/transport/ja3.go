@@ -0,0 +1,239 @@
+package transport
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/cryptobyte"
+)
+
+// ClientHelloFingerprint is the JA3 and JA4 fingerprint of a ClientHello.
+type ClientHelloFingerprint struct {
+	JA3     string // "version,ciphers,extensions,groups,point formats", GREASE removed
+	JA3Hash string // MD5 of JA3, hex-encoded
+	JA4     string // JA4 fingerprint, e.g. "t13d1516h2_8daaf6152771_e5627efa2ab1"
+}
+
+// TLS extension types the fingerprints look into.
+const (
+	extServerName          = 0
+	extSupportedGroups     = 10
+	extPointFormats        = 11
+	extSignatureAlgorithms = 13
+	extALPN                = 16
+	extSupportedVersions   = 43
+)
+
+// FingerprintClientHello computes the fingerprints of a ClientHello given as
+// the TLS record that carries it.
+func FingerprintClientHello(record []byte) (ClientHelloFingerprint, error) {
+	var fp ClientHelloFingerprint
+	s := cryptobyte.String(record)
+	var contentType uint8
+	var recordVersion uint16
+	var handshake cryptobyte.String
+	if !s.ReadUint8(&contentType) || contentType != 22 || !s.ReadUint16(&recordVersion) ||
+		!s.ReadUint16LengthPrefixed(&handshake) {
+		return fp, fmt.Errorf("not a TLS handshake record")
+	}
+
+	var msgType uint8
+	var body cryptobyte.String
+	if !handshake.ReadUint8(&msgType) || msgType != 1 || !handshake.ReadUint24LengthPrefixed(&body) {
+		return fp, fmt.Errorf("not a ClientHello")
+	}
+
+	var version uint16
+	var random, sessionID, ciphersRaw, compression, extensionsRaw cryptobyte.String
+	if !body.ReadUint16(&version) || !body.ReadBytes((*[]byte)(&random), 32) ||
+		!body.ReadUint8LengthPrefixed(&sessionID) || !body.ReadUint16LengthPrefixed(&ciphersRaw) ||
+		!body.ReadUint8LengthPrefixed(&compression) {
+		return fp, fmt.Errorf("malformed ClientHello")
+	}
+	if !body.Empty() && !body.ReadUint16LengthPrefixed(&extensionsRaw) {
+		return fp, fmt.Errorf("malformed ClientHello extensions")
+	}
+
+	var ciphers []uint16
+	for !ciphersRaw.Empty() {
+		var c uint16
+		if !ciphersRaw.ReadUint16(&c) {
+			return fp, fmt.Errorf("malformed ClientHello cipher suites")
+		}
+		if !isGREASE(c) {
+			ciphers = append(ciphers, c)
+		}
+	}
+
+	var extensions, groups, sigAlgs, versions []uint16
+	var points []uint8
+	var alpn string
+	for !extensionsRaw.Empty() {
+		var id uint16
+		var data cryptobyte.String
+		if !extensionsRaw.ReadUint16(&id) || !extensionsRaw.ReadUint16LengthPrefixed(&data) {
+			return fp, fmt.Errorf("malformed ClientHello extension")
+		}
+		if isGREASE(id) {
+			continue
+		}
+		extensions = append(extensions, id)
+
+		var list cryptobyte.String
+		switch id {
+		case extSupportedGroups:
+			if data.ReadUint16LengthPrefixed(&list) {
+				groups = readUint16s(list)
+			}
+		case extPointFormats:
+			if data.ReadUint8LengthPrefixed(&list) {
+				points = list
+			}
+		case extSignatureAlgorithms:
+			if data.ReadUint16LengthPrefixed(&list) {
+				sigAlgs = readUint16s(list)
+			}
+		case extSupportedVersions:
+			if data.ReadUint8LengthPrefixed(&list) {
+				versions = readUint16s(list)
+			}
+		case extALPN:
+			var proto cryptobyte.String
+			if data.ReadUint16LengthPrefixed(&list) && list.ReadUint8LengthPrefixed(&proto) {
+				alpn = string(proto)
+			}
+		}
+	}
+
+	pointValues := make([]uint16, len(points))
+	for i, p := range points {
+		pointValues[i] = uint16(p)
+	}
+	fp.JA3 = fmt.Sprintf("%d,%s,%s,%s,%s", version, joinDecimal(ciphers), joinDecimal(extensions),
+		joinDecimal(groups), joinDecimal(pointValues))
+	sum := md5.Sum([]byte(fp.JA3))
+	fp.JA3Hash = hex.EncodeToString(sum[:])
+
+	fp.JA4 = ja4(version, versions, ciphers, extensions, sigAlgs, alpn)
+	return fp, nil
+}
+
+// ja4 computes the JA4 fingerprint (TCP) from the parsed ClientHello, GREASE
+// values removed.
+func ja4(version uint16, versions, ciphers, extensions, sigAlgs []uint16, alpn string) string {
+	for _, v := range versions {
+		if v > version {
+			version = v
+		}
+	}
+	sni := "i"
+	if containsUint16(extensions, extServerName) {
+		sni = "d"
+	}
+	a := fmt.Sprintf("t%s%s%02d%02d%s", ja4Version(version), sni, min(len(ciphers), 99),
+		min(len(extensions), 99), ja4ALPN(alpn))
+
+	b := ja4Hash(sortedHex(ciphers))
+
+	var exts []uint16
+	for _, e := range extensions {
+		if e != extServerName && e != extALPN {
+			exts = append(exts, e)
+		}
+	}
+	c := "000000000000"
+	if len(exts) > 0 {
+		s := sortedHex(exts)
+		if len(sigAlgs) > 0 {
+			s += "_" + joinHex(sigAlgs)
+		}
+		c = ja4Hash(s)
+	}
+	return a + "_" + b + "_" + c
+}
+
+// ja4Version returns the JA4 label of a TLS version.
+func ja4Version(v uint16) string {
+	switch v {
+	case 0x0304:
+		return "13"
+	case 0x0303:
+		return "12"
+	case 0x0302:
+		return "11"
+	case 0x0301:
+		return "10"
+	case 0x0300:
+		return "s3"
+	}
+	return "00"
+}
+
+// ja4ALPN returns the first and last character of the first ALPN protocol, or
+// hex digits of its first and last byte when either is not alphanumeric.
+func ja4ALPN(alpn string) string {
+	if alpn == "" {
+		return "00"
+	}
+	first, last := alpn[0], alpn[len(alpn)-1]
+	if isAlphanumeric(first) && isAlphanumeric(last) {
+		return string([]byte{first, last})
+	}
+	return hex.EncodeToString([]byte{first})[:1] + hex.EncodeToString([]byte{last})[1:]
+}
+
+func isAlphanumeric(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+// ja4Hash returns the first 12 hex digits of the SHA-256 of s, or zeros for an
+// empty s.
+func ja4Hash(s string) string {
+	if s == "" {
+		return "000000000000"
+	}
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+func sortedHex(values []uint16) string {
+	sorted := append([]uint16(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return joinHex(sorted)
+}
+
+func joinHex(values []uint16) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = fmt.Sprintf("%04x", v)
+	}
+	return strings.Join(parts, ",")
+}
+
+func joinDecimal(values []uint16) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = strconv.Itoa(int(v))
+	}
+	return strings.Join(parts, "-")
+}
+
+// readUint16s reads a list of uint16 values, dropping GREASE values.
+func readUint16s(s cryptobyte.String) []uint16 {
+	var values []uint16
+	for !s.Empty() {
+		var v uint16
+		if !s.ReadUint16(&v) {
+			break
+		}
+		if !isGREASE(v) {
+			values = append(values, v)
+		}
+	}
+	return values
+}
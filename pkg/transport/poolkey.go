@@ -0,0 +1,227 @@
+package transport
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unsafe"
+)
+
+// PoolKey identifies which connections may be shared between requests. Besides the
+// target, the upstream proxy and the dial hooks it records every TLS parameter that changes what
+// the server sees or what the client accepts, so a connection opened with one SNI,
+// client certificate or verification mode is never handed to a request configured
+// differently. Two requests share pooled connections only when their keys are equal.
+type PoolKey struct {
+	Scheme  string // "http" or "https"
+	Host    string // Target host
+	Port    int    // Target port
+	Proxy   string // Upstream proxy as "type:host:port" (empty for direct connections)
+	Network string // Forced address family, "tcp4" or "tcp6" (empty when both are allowed)
+	Dialer  string // Identity of the DialContext and Resolver hooks (empty for the defaults)
+
+	// HTTP2Fingerprint identifies the HTTP/2 fingerprint profile the connection
+	// was set up with (empty for the default and for HTTP/1.x). Set by the HTTP/2
+	// transport.
+	HTTP2Fingerprint string
+
+	// TLS dimensions (zero for plain connections)
+	SNI           string // Effective server name (empty when SNI is disabled)
+	ClientCert    string // Fingerprint of the client certificate (empty without mTLS)
+	Verification  string // "insecure", "verify", or "verify" plus a custom trust anchor
+	ALPN          string // Offered ALPN protocols, comma-separated
+	MinTLSVersion uint16 // Configured minimum TLS version (0 = library default)
+	MaxTLSVersion uint16 // Configured maximum TLS version (0 = library default)
+	CipherSuites  string // Configured cipher suites as hex IDs (empty = default)
+	ClientHello   string // TLS fingerprint shaping the ClientHello (empty = crypto/tls)
+}
+
+// NewPoolKey derives the pool key of a connection configuration. alpn is the list
+// of protocols the caller offers in the TLS handshake.
+func NewPoolKey(config Config, alpn []string) PoolKey {
+	key := PoolKey{Scheme: "http", Host: config.Host, Port: config.Port}
+	if config.Proxy != nil {
+		key.Proxy = fmt.Sprintf("%s:%s:%d", config.Proxy.Type, config.Proxy.Host, defaultProxyPort(config.Proxy))
+	}
+	if config.Network == "tcp4" || config.Network == "tcp6" {
+		key.Network = config.Network
+	}
+	if config.DialContext != nil || config.Resolver != nil {
+		key.Dialer = fmt.Sprintf("dial:%s,resolver:%p", funcIdentity(config.DialContext), config.Resolver)
+	}
+	if !strings.EqualFold(config.Scheme, "https") {
+		return key
+	}
+	key.Scheme = "https"
+
+	// Configuration in TLSConfig takes precedence over the individual options, the
+	// same way upgradeTLS applies them.
+	tc := config.TLSConfig
+	if tc == nil {
+		tc = &tls.Config{}
+	}
+
+	switch {
+	case tc.ServerName != "":
+		key.SNI = tc.ServerName
+	case config.DisableSNI:
+	case config.SNI != "":
+		key.SNI = config.SNI
+	default:
+		key.SNI = config.Host
+	}
+
+	var certs [][]byte
+	if len(config.ClientCertPEM) > 0 {
+		certs = append(certs, []byte("pem"), config.ClientCertPEM)
+	}
+	if config.ClientCertFile != "" {
+		certs = append(certs, []byte("file"), []byte(config.ClientCertFile))
+	}
+	for _, c := range tc.Certificates {
+		if len(c.Certificate) > 0 {
+			certs = append(certs, []byte("der"), c.Certificate[0])
+		}
+	}
+	if tc.GetClientCertificate != nil {
+		certs = append(certs, []byte(fmt.Sprintf("callback:%p", tc.GetClientCertificate)))
+	}
+	if len(certs) > 0 {
+		key.ClientCert = fingerprint(certs...)
+	}
+
+	if config.InsecureTLS || tc.InsecureSkipVerify {
+		key.Verification = "insecure"
+	} else {
+		key.Verification = "verify"
+		if len(config.CustomCACerts) > 0 {
+			key.Verification += "+ca:" + fingerprint(config.CustomCACerts...)
+		}
+		if tc.RootCAs != nil {
+			key.Verification += fmt.Sprintf("+roots:%p", tc.RootCAs)
+		}
+	}
+
+	key.ALPN = strings.Join(alpn, ",")
+
+	key.MinTLSVersion = tc.MinVersion
+	if key.MinTLSVersion == 0 {
+		key.MinTLSVersion = config.MinTLSVersion
+	}
+	key.MaxTLSVersion = tc.MaxVersion
+	if key.MaxTLSVersion == 0 {
+		key.MaxTLSVersion = config.MaxTLSVersion
+	}
+
+	suites := tc.CipherSuites
+	if len(suites) == 0 {
+		suites = config.CipherSuites
+	}
+	ids := make([]string, len(suites))
+	for i, id := range suites {
+		ids[i] = fmt.Sprintf("%04x", id)
+	}
+	key.CipherSuites = strings.Join(ids, ",")
+
+	if config.TLSFingerprint != nil {
+		key.ClientHello = config.TLSFingerprint.String()
+	}
+
+	return key
+}
+
+// String returns the key in the form used for ConnectionMetadata.PoolKey and
+// PoolStats.HostStats, e.g.
+// "https://example.com:443|sni=example.com|alpn=http/1.1|verify=verify|tls=*-*".
+func (k PoolKey) String() string {
+	var b strings.Builder
+	if k.Proxy != "" {
+		b.WriteString(k.Proxy + "->")
+	}
+	if k.Scheme != "" {
+		b.WriteString(k.Scheme + "://")
+	}
+	fmt.Fprintf(&b, "%s:%d", k.Host, k.Port)
+	if k.Network != "" {
+		b.WriteString("|net=" + k.Network)
+	}
+	if k.Dialer != "" {
+		b.WriteString("|dialer=" + k.Dialer)
+	}
+	if k.HTTP2Fingerprint != "" {
+		b.WriteString("|h2=" + k.HTTP2Fingerprint)
+	}
+	if k.Scheme != "https" {
+		return b.String()
+	}
+	fmt.Fprintf(&b, "|sni=%s|alpn=%s|verify=%s|tls=%s-%s",
+		k.SNI, k.ALPN, k.Verification, tlsVersionLabel(k.MinTLSVersion), tlsVersionLabel(k.MaxTLSVersion))
+	if k.ClientCert != "" {
+		b.WriteString("|cert=" + k.ClientCert)
+	}
+	if k.CipherSuites != "" {
+		b.WriteString("|ciphers=" + k.CipherSuites)
+	}
+	if k.ClientHello != "" {
+		b.WriteString("|hello=" + k.ClientHello)
+	}
+	return b.String()
+}
+
+// funcIdentity identifies a DialContext hook. fmt's %p gives a function's code
+// pointer, shared by every closure of one function literal; the closure pointer
+// also tells apart closures capturing different state (e.g. one per namespace).
+func funcIdentity(fn DialContextFunc) string {
+	if fn == nil {
+		return ""
+	}
+	return fmt.Sprintf("%p", *(*unsafe.Pointer)(unsafe.Pointer(&fn)))
+}
+
+// defaultProxyPort returns the proxy port, applying the default for its type.
+func defaultProxyPort(p *ProxyConfig) int {
+	if p.Port != 0 {
+		return p.Port
+	}
+	switch p.Type {
+	case "http":
+		return 8080
+	case "https":
+		return 443
+	case "socks4", "socks5":
+		return 1080
+	}
+	return 0
+}
+
+// fingerprint returns a short, stable hash of the given values.
+func fingerprint(parts ...[]byte) string {
+	h := sha256.New()
+	for _, p := range parts {
+		var n [8]byte
+		binary.BigEndian.PutUint64(n[:], uint64(len(p)))
+		h.Write(n[:])
+		h.Write(p)
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// tlsVersionLabel formats a TLS version bound for a pool key ("*" when unset).
+func tlsVersionLabel(v uint16) string {
+	switch v {
+	case 0:
+		return "*"
+	case tls.VersionTLS10:
+		return "1.0"
+	case tls.VersionTLS11:
+		return "1.1"
+	case tls.VersionTLS12:
+		return "1.2"
+	case tls.VersionTLS13:
+		return "1.3"
+	}
+	return fmt.Sprintf("0x%04x", v)
+}
@@ -0,0 +1,423 @@
+package transport
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	utls "github.com/refraction-networking/utls"
+)
+
+// TLSFingerprint shapes the TLS ClientHello so that it matches a browser's
+// instead of crypto/tls's: cipher suites, extensions and their order, GREASE,
+// supported groups, signature algorithms and padding. It is taken either from
+// a built-in browser profile or from a JA3 string.
+//
+// The ALPN extension always offers the protocols of the transport ("http/1.1"
+// for HTTP/1.1, "h2" and "http/1.1" for HTTP/2), and the profile decides which
+// cipher suites and TLS versions are offered: CipherSuites, MinTLSVersion and
+// MaxTLSVersion do not apply. TLSConfig's server name, verification settings,
+// root CAs and client certificates do; its callbacks other than
+// VerifyPeerCertificate are ignored.
+type TLSFingerprint struct {
+	// Profile names a built-in ClientHello: "chrome", "firefox", "safari",
+	// "edge", "ios" or "android".
+	Profile string
+
+	// JA3 builds the ClientHello from a JA3 string
+	// ("version,ciphers,extensions,groups,point formats") when Profile is empty.
+	// Extensions are filled with the values browsers send.
+	JA3 string
+
+	// SignatureAlgorithms are sent in the signature_algorithms extension of a
+	// JA3 ClientHello, which JA3 does not record (default: Chrome's).
+	SignatureAlgorithms []tls.SignatureScheme
+
+	// GREASE adds GREASE values to the cipher suites, extensions, supported
+	// groups, versions and key shares of a JA3 ClientHello, as Chromium does.
+	// JA3 ignores GREASE values, so the JA3 string stays the same.
+	GREASE bool
+}
+
+// tlsProfiles are the built-in ClientHellos, tracking the latest versions
+// parroted by uTLS.
+var tlsProfiles = map[string]utls.ClientHelloID{
+	"chrome":  utls.HelloChrome_Auto,
+	"firefox": utls.HelloFirefox_Auto,
+	"safari":  utls.HelloSafari_Auto,
+	"edge":    utls.HelloEdge_Auto,
+	"ios":     utls.HelloIOS_Auto,
+	"android": utls.HelloAndroid_11_OkHttp,
+}
+
+// defaultSignatureAlgorithms are Chrome's, sent by JA3 ClientHellos.
+var defaultSignatureAlgorithms = []tls.SignatureScheme{
+	tls.ECDSAWithP256AndSHA256,
+	tls.PSSWithSHA256,
+	tls.PKCS1WithSHA256,
+	tls.ECDSAWithP384AndSHA384,
+	tls.PSSWithSHA384,
+	tls.PKCS1WithSHA384,
+	tls.PSSWithSHA512,
+	tls.PKCS1WithSHA512,
+}
+
+// ParseTLSFingerprint returns the fingerprint for a built-in profile name or a
+// JA3 string.
+func ParseTLSFingerprint(s string) (*TLSFingerprint, error) {
+	fp := &TLSFingerprint{Profile: strings.ToLower(strings.TrimSpace(s))}
+	if strings.Contains(s, ",") {
+		fp = &TLSFingerprint{JA3: strings.TrimSpace(s)}
+	}
+	if _, err := fp.spec(nil); err != nil {
+		return nil, err
+	}
+	return fp, nil
+}
+
+// String identifies the fingerprint in pool keys: the profile name, or "ja3:"
+// followed by the JA3 string and the options that change the ClientHello.
+func (f *TLSFingerprint) String() string {
+	if f.Profile != "" {
+		return f.Profile
+	}
+	s := "ja3:" + f.JA3
+	if f.GREASE {
+		s += "+grease"
+	}
+	if len(f.SignatureAlgorithms) > 0 {
+		ids := make([]string, len(f.SignatureAlgorithms))
+		for i, alg := range f.SignatureAlgorithms {
+			ids[i] = fmt.Sprintf("%04x", uint16(alg))
+		}
+		s += "+sigalgs=" + strings.Join(ids, "-")
+	}
+	return s
+}
+
+// spec returns the ClientHello to send, offering alpn in the ALPN extension.
+func (f *TLSFingerprint) spec(alpn []string) (*utls.ClientHelloSpec, error) {
+	var spec utls.ClientHelloSpec
+	switch {
+	case f.Profile != "":
+		id, ok := tlsProfiles[strings.ToLower(f.Profile)]
+		if !ok {
+			return nil, fmt.Errorf("unknown TLS fingerprint profile %q", f.Profile)
+		}
+		var err error
+		if spec, err = utls.UTLSIdToSpec(id); err != nil {
+			return nil, fmt.Errorf("TLS fingerprint profile %q: %w", f.Profile, err)
+		}
+	case f.JA3 != "":
+		var err error
+		if spec, err = f.ja3Spec(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("TLS fingerprint needs a Profile or a JA3 string")
+	}
+
+	for _, ext := range spec.Extensions {
+		if e, ok := ext.(*utls.ALPNExtension); ok && alpn != nil {
+			e.AlpnProtocols = append([]string(nil), alpn...)
+		}
+	}
+	return &spec, nil
+}
+
+// ja3Spec builds the ClientHello described by the JA3 string.
+func (f *TLSFingerprint) ja3Spec() (utls.ClientHelloSpec, error) {
+	var spec utls.ClientHelloSpec
+	fields := strings.Split(f.JA3, ",")
+	if len(fields) != 5 {
+		return spec, fmt.Errorf("JA3 %q: expected 5 comma-separated fields", f.JA3)
+	}
+	version, err := strconv.ParseUint(fields[0], 10, 16)
+	if err != nil || version < tls.VersionTLS10 || version > tls.VersionTLS13 {
+		return spec, fmt.Errorf("JA3: invalid TLS version %q", fields[0])
+	}
+	ciphers, err := parseJA3List(fields[1], 16)
+	if err != nil {
+		return spec, fmt.Errorf("JA3 cipher suites: %w", err)
+	}
+	extensions, err := parseJA3List(fields[2], 16)
+	if err != nil {
+		return spec, fmt.Errorf("JA3 extensions: %w", err)
+	}
+	groups, err := parseJA3List(fields[3], 16)
+	if err != nil {
+		return spec, fmt.Errorf("JA3 supported groups: %w", err)
+	}
+	points, err := parseJA3List(fields[4], 8)
+	if err != nil {
+		return spec, fmt.Errorf("JA3 point formats: %w", err)
+	}
+
+	if f.GREASE {
+		ciphers = append([]uint16{utls.GREASE_PLACEHOLDER}, ciphers...)
+	}
+	spec.CipherSuites = ciphers
+	spec.CompressionMethods = []uint8{0}
+
+	var curves []utls.CurveID
+	if f.GREASE {
+		curves = append(curves, utls.GREASE_PLACEHOLDER)
+	}
+	for _, g := range groups {
+		curves = append(curves, utls.CurveID(g))
+	}
+	pointFormats := make([]uint8, len(points))
+	for i, p := range points {
+		pointFormats[i] = uint8(p)
+	}
+	sigAlgs := f.SignatureAlgorithms
+	if len(sigAlgs) == 0 {
+		sigAlgs = defaultSignatureAlgorithms
+	}
+	schemes := make([]utls.SignatureScheme, len(sigAlgs))
+	for i, alg := range sigAlgs {
+		schemes[i] = utls.SignatureScheme(alg)
+	}
+
+	var versions []uint16
+	if f.GREASE {
+		versions = append(versions, utls.GREASE_PLACEHOLDER)
+	}
+	versions = append(versions, tls.VersionTLS13, tls.VersionTLS12)
+	spec.TLSVersMin, spec.TLSVersMax = tls.VersionTLS10, uint16(version)
+
+	if f.GREASE {
+		spec.Extensions = append(spec.Extensions, &utls.UtlsGREASEExtension{})
+	}
+	for _, id := range extensions {
+		var ext utls.TLSExtension
+		switch id {
+		case 0:
+			ext = &utls.SNIExtension{}
+		case 5:
+			ext = &utls.StatusRequestExtension{}
+		case 10:
+			ext = &utls.SupportedCurvesExtension{Curves: curves}
+		case 11:
+			ext = &utls.SupportedPointsExtension{SupportedPoints: pointFormats}
+		case 13:
+			ext = &utls.SignatureAlgorithmsExtension{SupportedSignatureAlgorithms: schemes}
+		case 16:
+			ext = &utls.ALPNExtension{AlpnProtocols: []string{"h2", "http/1.1"}}
+		case 18:
+			ext = &utls.SCTExtension{}
+		case 21:
+			if f.GREASE {
+				// Chromium's second GREASE extension precedes the padding
+				spec.Extensions = append(spec.Extensions, &utls.UtlsGREASEExtension{})
+			}
+			ext = &utls.UtlsPaddingExtension{GetPaddingLen: utls.BoringPaddingStyle}
+		case 23:
+			ext = &utls.ExtendedMasterSecretExtension{}
+		case 27:
+			ext = &utls.UtlsCompressCertExtension{Algorithms: []utls.CertCompressionAlgo{utls.CertCompressionBrotli}}
+		case 28:
+			ext = &utls.FakeRecordSizeLimitExtension{Limit: 0x4001}
+		case 34:
+			ext = &utls.FakeDelegatedCredentialsExtension{SupportedSignatureAlgorithms: []utls.SignatureScheme{
+				utls.ECDSAWithP256AndSHA256, utls.ECDSAWithP384AndSHA384, utls.ECDSAWithP521AndSHA512, utls.ECDSAWithSHA1,
+			}}
+		case 35:
+			ext = &utls.SessionTicketExtension{}
+		case 41:
+			return spec, fmt.Errorf("JA3 extension 41 (pre_shared_key) needs a resumed session and is not supported")
+		case 43:
+			ext = &utls.SupportedVersionsExtension{Versions: versions}
+			spec.TLSVersMin, spec.TLSVersMax = tls.VersionTLS12, tls.VersionTLS13
+		case 45:
+			ext = &utls.PSKKeyExchangeModesExtension{Modes: []uint8{utls.PskModeDHE}}
+		case 50:
+			ext = &utls.SignatureAlgorithmsCertExtension{SupportedSignatureAlgorithms: schemes}
+		case 51:
+			ext = &utls.KeyShareExtension{KeyShares: keyShares(curves)}
+		case 17513:
+			ext = &utls.ApplicationSettingsExtension{SupportedProtocols: []string{"h2"}}
+		case 17613:
+			ext = &utls.ApplicationSettingsExtensionNew{SupportedProtocols: []string{"h2"}}
+		case 65037:
+			ext = utls.BoringGREASEECH()
+		case 65281:
+			ext = &utls.RenegotiationInfoExtension{Renegotiation: utls.RenegotiateOnceAsClient}
+		default:
+			if isGREASE(id) {
+				ext = &utls.UtlsGREASEExtension{}
+			} else {
+				ext = &utls.GenericExtension{Id: id}
+			}
+		}
+		spec.Extensions = append(spec.Extensions, ext)
+	}
+	if f.GREASE && !containsUint16(extensions, 21) {
+		spec.Extensions = append(spec.Extensions, &utls.UtlsGREASEExtension{})
+	}
+	return spec, nil
+}
+
+// keyShares returns the key shares for the preferred groups: a GREASE share if
+// the groups start with one, then the first supported group, plus X25519 when
+// that is the post-quantum hybrid (as Chrome does).
+func keyShares(curves []utls.CurveID) []utls.KeyShare {
+	var shares []utls.KeyShare
+	for _, c := range curves {
+		switch {
+		case isGREASE(uint16(c)):
+			if len(shares) == 0 {
+				shares = append(shares, utls.KeyShare{Group: utls.CurveID(utls.GREASE_PLACEHOLDER), Data: []byte{0}})
+			}
+			continue
+		case c == utls.X25519MLKEM768:
+			return append(shares, utls.KeyShare{Group: c}, utls.KeyShare{Group: utls.X25519})
+		case c == utls.X25519, c == utls.CurveP256, c == utls.CurveP384, c == utls.CurveP521:
+			return append(shares, utls.KeyShare{Group: c})
+		}
+	}
+	return shares
+}
+
+// parseJA3List parses a "-"-separated list of decimal values of the given bit size.
+func parseJA3List(s string, bits int) ([]uint16, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var values []uint16
+	for _, part := range strings.Split(s, "-") {
+		v, err := strconv.ParseUint(part, 10, bits)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q", part)
+		}
+		values = append(values, uint16(v))
+	}
+	return values, nil
+}
+
+// isGREASE reports whether v is a GREASE value (RFC 8701).
+func isGREASE(v uint16) bool {
+	return v&0x0f0f == 0x0a0a && v>>8 == v&0xff
+}
+
+func containsUint16(values []uint16, v uint16) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// HandshakeTLS runs the client TLS handshake on conn with config. With a
+// fingerprint the ClientHello is the fingerprint's (sent with uTLS), otherwise
+// crypto/tls's own. The returned ClientHelloFingerprint describes the
+// ClientHello actually written; use TLSConnectionState for the connection state.
+// conn is not closed on failure.
+func HandshakeTLS(ctx context.Context, conn net.Conn, config *tls.Config, fp *TLSFingerprint) (net.Conn, ClientHelloFingerprint, error) {
+	rec := &helloRecorder{Conn: conn}
+	var tlsConn interface {
+		net.Conn
+		HandshakeContext(context.Context) error
+	}
+	if fp == nil {
+		tlsConn = tls.Client(rec, config)
+	} else {
+		spec, err := fp.spec(config.NextProtos)
+		if err != nil {
+			return nil, ClientHelloFingerprint{}, err
+		}
+		uconn := utls.UClient(rec, utlsConfig(config), utls.HelloCustom)
+		if err := uconn.ApplyPreset(spec); err != nil {
+			return nil, ClientHelloFingerprint{}, fmt.Errorf("applying TLS fingerprint: %w", err)
+		}
+		tlsConn = uconn
+	}
+
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return nil, ClientHelloFingerprint{}, err
+	}
+	hello, _ := FingerprintClientHello(rec.hello)
+	rec.hello = nil
+	return tlsConn, hello, nil
+}
+
+// TLSConnectionState returns the state of a TLS connection returned by
+// HandshakeTLS (false for other connections).
+func TLSConnectionState(conn net.Conn) (tls.ConnectionState, bool) {
+	switch c := conn.(type) {
+	case *tls.Conn:
+		return c.ConnectionState(), true
+	case *utls.UConn:
+		s := c.ConnectionState()
+		return tls.ConnectionState{
+			Version:                     s.Version,
+			HandshakeComplete:           s.HandshakeComplete,
+			DidResume:                   s.DidResume,
+			CipherSuite:                 s.CipherSuite,
+			NegotiatedProtocol:          s.NegotiatedProtocol,
+			ServerName:                  s.ServerName,
+			PeerCertificates:            s.PeerCertificates,
+			VerifiedChains:              s.VerifiedChains,
+			SignedCertificateTimestamps: s.SignedCertificateTimestamps,
+			OCSPResponse:                s.OCSPResponse,
+			TLSUnique:                   s.TLSUnique,
+			ECHAccepted:                 s.ECHAccepted,
+		}, true
+	}
+	return tls.ConnectionState{}, false
+}
+
+// utlsConfig translates the settings of config that apply to a fingerprinted
+// ClientHello.
+func utlsConfig(config *tls.Config) *utls.Config {
+	uc := &utls.Config{
+		Rand:                  config.Rand,
+		Time:                  config.Time,
+		RootCAs:               config.RootCAs,
+		NextProtos:            config.NextProtos,
+		ServerName:            config.ServerName,
+		InsecureSkipVerify:    config.InsecureSkipVerify,
+		VerifyPeerCertificate: config.VerifyPeerCertificate,
+		MinVersion:            config.MinVersion,
+		MaxVersion:            config.MaxVersion,
+		Renegotiation:         utls.RenegotiationSupport(config.Renegotiation),
+		KeyLogWriter:          config.KeyLogWriter,
+	}
+	for _, c := range config.Certificates {
+		cert := utls.Certificate{
+			Certificate:                 c.Certificate,
+			PrivateKey:                  c.PrivateKey,
+			OCSPStaple:                  c.OCSPStaple,
+			SignedCertificateTimestamps: c.SignedCertificateTimestamps,
+			Leaf:                        c.Leaf,
+		}
+		for _, alg := range c.SupportedSignatureAlgorithms {
+			cert.SupportedSignatureAlgorithms = append(cert.SupportedSignatureAlgorithms, utls.SignatureScheme(alg))
+		}
+		uc.Certificates = append(uc.Certificates, cert)
+	}
+	return uc
+}
+
+// helloRecorder keeps the first TLS record written to the connection, which
+// holds the ClientHello. Writes after the handshake only check the done flag;
+// they never run concurrently with the handshake.
+type helloRecorder struct {
+	net.Conn
+	hello []byte
+	done  bool
+}
+
+func (r *helloRecorder) Write(p []byte) (int, error) {
+	if !r.done {
+		r.hello = append(r.hello, p...)
+		if len(r.hello) >= 5 && len(r.hello) >= 5+(int(r.hello[3])<<8|int(r.hello[4])) {
+			r.done = true
+		}
+	}
+	return r.Conn.Write(p)
+}
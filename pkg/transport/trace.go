@@ -0,0 +1,191 @@
+package transport
+
+import (
+	"crypto/tls"
+	"net"
+	"time"
+)
+
+// ClientTrace is a set of hooks fired at each stage of a request and of the
+// connection it uses, in the spirit of net/http/httptrace. Any field may be nil.
+// Both the HTTP/1.1 and the HTTP/2 paths fire them; hooks must not block and may
+// be called concurrently (connection attempts race, and HTTP/2 frames are read on
+// the connection's read loop).
+//
+// Connection hooks (DNS, Connect, TLS) only fire when a new connection is opened;
+// GotConn fires for every request, pooled or not.
+type ClientTrace struct {
+	// DNSStart and DNSDone surround a host name lookup done by the library (the
+	// target, a proxy, or a SOCKS4 target). Names resolved inside DialContext or
+	// net.Dialer are not reported.
+	DNSStart func(host string)
+	DNSDone  func(info DNSDoneInfo)
+
+	// ConnectStart and ConnectDone surround each dial, to the target or to a
+	// proxy. With HappyEyeballs they fire once per raced address.
+	ConnectStart func(network, addr string)
+	ConnectDone  func(network, addr string, err error)
+
+	// ProxyConnectDone fires once the tunnel through an upstream proxy is
+	// established (CONNECT answered, SOCKS handshake done) or has failed.
+	ProxyConnectDone func(proxyType, proxyAddr string, err error)
+
+	// TLSHandshakeStart and TLSHandshakeDone surround the TLS handshake with the
+	// target. The state is zero when the handshake failed.
+	TLSHandshakeStart func()
+	TLSHandshakeDone  func(state tls.ConnectionState, err error)
+
+	// GotConn fires when the request has a connection, new or pooled.
+	GotConn func(info GotConnInfo)
+
+	// WroteHeaders fires once the request head has been written (the HEADERS
+	// frame on HTTP/2) and WroteRequest once the whole request has been written
+	// or writing it failed.
+	WroteHeaders func()
+	WroteRequest func(err error)
+
+	// GotFirstResponseByte fires when the first byte (HTTP/2: the first frame)
+	// of the response arrives.
+	GotFirstResponseByte func()
+
+	// Got1xxResponse fires for each interim 1xx response before the final one.
+	Got1xxResponse func(code int, headers map[string][]string)
+
+	// FrameRead and FrameWritten fire for each HTTP/2 frame of the request's
+	// stream, and for connection-level frames on a connection the request opened.
+	FrameRead    func(frame FrameInfo)
+	FrameWritten func(frame FrameInfo)
+}
+
+// DNSDoneInfo describes the result of a lookup.
+type DNSDoneInfo struct {
+	Host  string   // Host name that was looked up
+	Addrs []net.IP // Resolved addresses (nil on error)
+	Err   error    // Lookup error
+}
+
+// GotConnInfo describes the connection a request obtained.
+type GotConnInfo struct {
+	Reused       bool          // Connection came from the pool (or is a shared HTTP/2 connection)
+	IdleTime     time.Duration // How long the connection was idle before this request (reused only)
+	LocalAddr    string        // Local socket address
+	RemoteAddr   string        // Remote socket address
+	ConnectionID uint64        // Connection identifier (HTTP/1.1 only)
+}
+
+// FrameInfo describes an HTTP/2 frame for FrameRead and FrameWritten.
+type FrameInfo struct {
+	Type     string // Frame type, e.g. "HEADERS", "DATA", "SETTINGS"
+	StreamID uint32 // Stream identifier (0 for connection-level frames)
+	Flags    uint8  // Frame flags
+	Length   uint32 // Payload length
+}
+
+// The Trace methods fire the matching hook when it is set; they are safe to call
+// on a nil *ClientTrace, so callers never check the trace themselves.
+
+// TraceDNSStart fires DNSStart.
+func (t *ClientTrace) TraceDNSStart(host string) {
+	if t != nil && t.DNSStart != nil {
+		t.DNSStart(host)
+	}
+}
+
+// TraceDNSDone fires DNSDone.
+func (t *ClientTrace) TraceDNSDone(host string, addrs []net.IP, err error) {
+	if t != nil && t.DNSDone != nil {
+		t.DNSDone(DNSDoneInfo{Host: host, Addrs: addrs, Err: err})
+	}
+}
+
+// TraceConnectStart fires ConnectStart.
+func (t *ClientTrace) TraceConnectStart(network, addr string) {
+	if t != nil && t.ConnectStart != nil {
+		t.ConnectStart(network, addr)
+	}
+}
+
+// TraceConnectDone fires ConnectDone.
+func (t *ClientTrace) TraceConnectDone(network, addr string, err error) {
+	if t != nil && t.ConnectDone != nil {
+		t.ConnectDone(network, addr, err)
+	}
+}
+
+// TraceProxyConnectDone fires ProxyConnectDone.
+func (t *ClientTrace) TraceProxyConnectDone(proxyType, proxyAddr string, err error) {
+	if t != nil && t.ProxyConnectDone != nil {
+		t.ProxyConnectDone(proxyType, proxyAddr, err)
+	}
+}
+
+// TraceTLSHandshakeStart fires TLSHandshakeStart.
+func (t *ClientTrace) TraceTLSHandshakeStart() {
+	if t != nil && t.TLSHandshakeStart != nil {
+		t.TLSHandshakeStart()
+	}
+}
+
+// TraceTLSHandshakeDone fires TLSHandshakeDone.
+func (t *ClientTrace) TraceTLSHandshakeDone(state tls.ConnectionState, err error) {
+	if t != nil && t.TLSHandshakeDone != nil {
+		t.TLSHandshakeDone(state, err)
+	}
+}
+
+// TraceGotConn fires GotConn with the socket addresses of conn.
+func (t *ClientTrace) TraceGotConn(conn net.Conn, reused bool, idle time.Duration, id uint64) {
+	if t == nil || t.GotConn == nil {
+		return
+	}
+	info := GotConnInfo{Reused: reused, IdleTime: idle, ConnectionID: id}
+	if a := conn.LocalAddr(); a != nil {
+		info.LocalAddr = a.String()
+	}
+	if a := conn.RemoteAddr(); a != nil {
+		info.RemoteAddr = a.String()
+	}
+	t.GotConn(info)
+}
+
+// TraceWroteHeaders fires WroteHeaders.
+func (t *ClientTrace) TraceWroteHeaders() {
+	if t != nil && t.WroteHeaders != nil {
+		t.WroteHeaders()
+	}
+}
+
+// TraceWroteRequest fires WroteRequest.
+func (t *ClientTrace) TraceWroteRequest(err error) {
+	if t != nil && t.WroteRequest != nil {
+		t.WroteRequest(err)
+	}
+}
+
+// TraceGotFirstResponseByte fires GotFirstResponseByte.
+func (t *ClientTrace) TraceGotFirstResponseByte() {
+	if t != nil && t.GotFirstResponseByte != nil {
+		t.GotFirstResponseByte()
+	}
+}
+
+// TraceGot1xxResponse fires Got1xxResponse.
+func (t *ClientTrace) TraceGot1xxResponse(code int, headers map[string][]string) {
+	if t != nil && t.Got1xxResponse != nil {
+		t.Got1xxResponse(code, headers)
+	}
+}
+
+// TraceFrameRead fires FrameRead.
+func (t *ClientTrace) TraceFrameRead(frame FrameInfo) {
+	if t != nil && t.FrameRead != nil {
+		t.FrameRead(frame)
+	}
+}
+
+// TraceFrameWritten fires FrameWritten.
+func (t *ClientTrace) TraceFrameWritten(frame FrameInfo) {
+	if t != nil && t.FrameWritten != nil {
+		t.FrameWritten(frame)
+	}
+}
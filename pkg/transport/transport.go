@@ -69,6 +69,27 @@ type Config struct {
 	// Connection pooling
 	ReuseConnection bool
 
+	// DialContext replaces net.Dialer for every connection, including the ones to
+	// proxies. Resolver replaces the transport's resolver for target, proxy and
+	// SOCKS4 lookups.
+	DialContext DialContextFunc
+	Resolver    Resolver
+
+	// Network restricts direct connections to one address family: "tcp4" or
+	// "tcp6" ("" and "tcp" allow both).
+	Network string
+
+	// HappyEyeballs resolves A and AAAA records in parallel and races direct
+	// connection attempts across the addresses (RFC 8305), starting the next
+	// attempt every FallbackDelay (DefaultFallbackDelay when zero) or as soon as
+	// one fails. Without it only the first resolved address is dialed.
+	HappyEyeballs bool
+	FallbackDelay time.Duration
+
+	// Trace receives the connection lifecycle hooks (DNS, Connect, proxy, TLS,
+	// GotConn). The client fires the request hooks.
+	Trace *ClientTrace
+
 	// ForceNewConn forces a brand new connection, bypassing the idle pool (v2.2.0+).
 	// Used on retry attempts so a stale-connection failure is not retried on another
 	// (potentially equally stale) idle connection. The per-host connection cap
@@ -99,6 +120,10 @@ type Config struct {
 	MaxTLSVersion    uint16                   // Maximum SSL/TLS version
 	TLSRenegotiation tls.RenegotiationSupport // TLS renegotiation support
 	CipherSuites     []uint16                 // Allowed cipher suites
+
+	// TLSFingerprint shapes the ClientHello like a browser's (nil: crypto/tls's
+	// own ClientHello).
+	TLSFingerprint *TLSFingerprint
 }
 
 // ConnectionMetadata holds metadata about the established connection
@@ -123,6 +148,11 @@ type ConnectionMetadata struct {
 	TLSSessionID string // TLS session ID (hex-encoded)
 	TLSResumed   bool   // Whether TLS session was resumed
 
+	// ClientHello fingerprints of the handshake (see ClientHelloFingerprint)
+	JA3     string
+	JA3Hash string
+	JA4     string
+
 	// Proxy metadata (v2.0.0+)
 	ProxyUsed bool   // Whether request went through proxy
 	ProxyType string // Proxy type: "http", "https", "socks4", "socks5"
@@ -130,6 +160,14 @@ type ConnectionMetadata struct {
 
 	// Connection pooling (v2.0.3+)
 	PoolKey string // Pool key used for this connection (includes proxy info)
+
+	// IdleTime is how long a reused connection sat idle in the pool before being
+	// handed out (zero for new connections).
+	IdleTime time.Duration
+
+	// DialAttempts lists every address tried for a direct connection and its
+	// outcome, in the order the attempts started (one entry without HappyEyeballs).
+	DialAttempts []DialAttempt
 }
 
 // PoolConfig holds connection pool configuration.
@@ -190,8 +228,9 @@ type pooledConnection struct {
 	createdAt time.Time // v2.1.0+: for connection age tracking
 }
 
-// hostPool manages connections for a single host:port key.
+// hostPool manages connections for a single pool key.
 type hostPool struct {
+	key       PoolKey
 	mu        sync.Mutex
 	idle      []*pooledConnection // slice of idle connections (LIFO)
 	numActive int                 // count of connections currently in use
@@ -199,8 +238,9 @@ type hostPool struct {
 }
 
 // newHostPool creates a new host pool.
-func newHostPool() *hostPool {
+func newHostPool(key PoolKey) *hostPool {
 	hp := &hostPool{
+		key:  key,
 		idle: make([]*pooledConnection, 0, 4),
 	}
 	hp.cond = sync.NewCond(&hp.mu)
@@ -209,8 +249,8 @@ func newHostPool() *hostPool {
 
 // Transport handles the network connection and protocol negotiation.
 type Transport struct {
-	resolver            *net.Resolver
-	hostPools           sync.Map   // map[string]*hostPool (key: "host:port" or proxy-aware key)
+	resolver            Resolver
+	hostPools           sync.Map   // map[string]*hostPool (key: PoolKey.String())
 	poolConfig          PoolConfig // Pool configuration
 	connectionIDCounter uint64     // Atomic counter for unique connection IDs
 
@@ -238,6 +278,7 @@ type PoolStats struct {
 type HostPoolStats struct {
 	ActiveConns int
 	IdleConns   int
+	Key         PoolKey // Target, proxy and TLS dimensions isolating this pool
 }
 
 // New creates a new Transport instance with default pool configuration.
@@ -318,46 +359,34 @@ func (t *Transport) Connect(ctx context.Context, config Config, timer *timing.Ti
 
 	metadata := &ConnectionMetadata{}
 
-	// Create a connection pool key that includes proxy information if present
-	// This ensures different proxies use different pooled connections
-	var poolKey string
-	if config.Proxy != nil {
-		proxyPort := config.Proxy.Port
-		if proxyPort == 0 {
-			// Apply default port
-			switch config.Proxy.Type {
-			case "http":
-				proxyPort = 8080
-			case "https":
-				proxyPort = 443
-			case "socks4", "socks5":
-				proxyPort = 1080
-			}
-		}
-		// Format: "proxy_type:proxy_host:proxy_port->target_host:target_port"
-		poolKey = fmt.Sprintf("%s:%s:%d->%s:%d", config.Proxy.Type, config.Proxy.Host, proxyPort, config.Host, config.Port)
-	} else {
-		// Direct connection: just use target address
-		poolKey = fmt.Sprintf("%s:%d", config.Host, config.Port)
-	}
+	// The pool key covers the target, the proxy and the TLS parameters, so only
+	// requests with an identical connection setup share pooled connections. The
+	// HTTP/1.1 transport always offers ALPN "http/1.1" (see upgradeTLS).
+	key := NewPoolKey(config, []string{"http/1.1"})
+	poolKey := key.String()
 
 	// Try to get connection from pool if ReuseConnection is enabled
 	if config.ReuseConnection {
+		timer.StartPoolWait()
 		if config.ForceNewConn {
 			// Retry path: skip the idle pool entirely and dial a fresh connection,
 			// but still respect the per-host connection cap via slot reservation.
-			if !t.reserveSlot(poolKey) {
+			reserved := t.reserveSlot(key)
+			timer.EndPoolWait()
+			if !reserved {
 				return nil, nil, errors.NewConnectionError(config.Host, config.Port,
 					fmt.Errorf("connection pool exhausted for %s (max: %d, timeout: %v)",
 						poolKey, t.poolConfig.MaxConnsPerHost, t.poolConfig.WaitTimeout))
 			}
 			// Slot reserved, fall through to create a new connection.
 		} else {
-			conn, meta, canProceed := t.getFromPool(poolKey)
+			conn, meta, canProceed := t.getFromPool(key)
+			timer.EndPoolWait()
 			if conn != nil && meta != nil {
 				// Got an existing connection from pool
 				meta.ConnectionReused = true
 				meta.PoolKey = poolKey
+				config.Trace.TraceGotConn(conn, true, meta.IdleTime, meta.ConnectionID)
 				return conn, meta, nil
 			}
 			if !canProceed {
@@ -377,32 +406,33 @@ func (t *Transport) Connect(ctx context.Context, config Config, timer *timing.Ti
 	}
 
 	// Resolve DNS if needed
-	dialAddr, _, err := t.resolveAddress(ctx, config, timer)
+	dialAddrs, err := t.resolveAddress(ctx, config, timer)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	// Store resolved IP in metadata
-	host, portStr, _ := net.SplitHostPort(dialAddr)
-	metadata.ConnectedIP = host
-	if port, err := strconv.Atoi(portStr); err == nil {
-		metadata.ConnectedPort = port
-	}
+	setConnectedAddr(metadata, dialAddrs[0])
 
 	var conn net.Conn
 
 	// Connect through proxy if configured
 	if config.Proxy != nil {
-		conn, metadata, err = t.connectViaProxy(ctx, config, dialAddr, connTimeout, timer, metadata)
+		conn, metadata, err = t.connectViaProxy(ctx, config, dialAddrs[0], connTimeout, timer, metadata)
 		if err != nil {
 			return nil, nil, err // Error already wrapped by connectViaProxy
 		}
 	} else {
-		// Direct TCP connection
-		conn, err = t.connectTCP(ctx, dialAddr, connTimeout, timer)
+		// Direct TCP connection (a Happy Eyeballs race over several addresses)
+		conn, metadata.DialAttempts, err = t.connectTCP(ctx, config, dialAddrs, connTimeout, timer)
 		if err != nil {
 			return nil, nil, errors.NewConnectionError(config.Host, config.Port, err)
 		}
+		for _, attempt := range metadata.DialAttempts {
+			if attempt.Outcome == DialConnected {
+				setConnectedAddr(metadata, attempt.Address)
+			}
+		}
 	}
 
 	// Populate socket-level metadata
@@ -437,6 +467,7 @@ func (t *Transport) Connect(ctx context.Context, config Config, timer *timing.Ti
 		atomic.AddUint64(&t.statsConnectionsCreated, 1)
 	}
 
+	config.Trace.TraceGotConn(conn, false, 0, metadata.ConnectionID)
 	return conn, metadata, nil
 }
 
@@ -456,14 +487,18 @@ func (t *Transport) validateConfig(config Config) error {
 		return errors.NewValidationError("cannot set both DisableSNI=true and SNI (conflicting options)")
 	}
 
-	return nil
+	return ValidateNetwork(config.Network)
 }
 
-func (t *Transport) resolveAddress(ctx context.Context, config Config, timer *timing.Timer) (dialAddr string, resolvedIP string, err error) {
+// resolveAddress returns the "ip:port" addresses to dial, in order: ConnectIP,
+// every address of both families for a Happy Eyeballs race, or the first address
+// usable on config.Network.
+func (t *Transport) resolveAddress(ctx context.Context, config Config, timer *timing.Timer) ([]string, error) {
+	port := strconv.Itoa(config.Port)
+
 	// If ConnectIP is specified, use it directly
 	if config.ConnectIP != "" {
-		dialAddr = net.JoinHostPort(config.ConnectIP, strconv.Itoa(config.Port))
-		return dialAddr, config.ConnectIP, nil
+		return []string{net.JoinHostPort(config.ConnectIP, port)}, nil
 	}
 
 	// Perform DNS resolution with separate timeout
@@ -481,29 +516,36 @@ func (t *Transport) resolveAddress(ctx context.Context, config Config, timer *ti
 	ctxLookup, cancel := context.WithTimeout(ctx, dnsTimeout)
 	defer cancel()
 
-	addrs, err := t.resolver.LookupIPAddr(ctxLookup, config.Host)
-	if err != nil {
-		return "", "", errors.NewDNSError(config.Host, err)
+	dialer := t.dialer(config, 0)
+	if config.HappyEyeballs && config.Proxy == nil {
+		addrs, err := dialer.LookupDualStack(ctxLookup, config.Network, config.Host)
+		if err != nil {
+			return nil, errors.NewDNSError(config.Host, err)
+		}
+		return JoinAddrs(addrs, port), nil
 	}
 
-	if len(addrs) == 0 {
-		return "", "", errors.NewDNSError(config.Host, errors.NewValidationError("no IP addresses found"))
+	addrs, err := dialer.LookupNetwork(ctxLookup, config.Network, config.Host)
+	if err != nil {
+		return nil, errors.NewDNSError(config.Host, err)
 	}
 
 	// Use the first address
-	ip := addrs[0].IP.String()
-	dialAddr = net.JoinHostPort(ip, strconv.Itoa(config.Port))
-	return dialAddr, ip, nil
+	return JoinAddrs(addrs[:1], port), nil
 }
 
-func (t *Transport) connectTCP(ctx context.Context, dialAddr string, timeout time.Duration, timer *timing.Timer) (net.Conn, error) {
+// connectTCP dials the target, racing the addresses when there are several.
+func (t *Transport) connectTCP(ctx context.Context, config Config, dialAddrs []string, timeout time.Duration, timer *timing.Timer) (net.Conn, []DialAttempt, error) {
 	timer.StartTCP()
 	defer timer.EndTCP()
 
-	dialer := &net.Dialer{Timeout: timeout}
-	conn, err := dialer.DialContext(ctx, "tcp", dialAddr)
+	network := config.Network
+	if network == "" {
+		network = "tcp"
+	}
+	conn, attempts, err := t.dialer(config, timeout).DialParallel(ctx, network, dialAddrs, config.FallbackDelay)
 	if err != nil {
-		return nil, err
+		return nil, attempts, err
 	}
 
 	// Enable TCP Keep-Alive if configured (v2.1.1+)
@@ -514,7 +556,26 @@ func (t *Transport) connectTCP(ctx context.Context, dialAddr string, timeout tim
 		}
 	}
 
-	return conn, nil
+	return conn, attempts, nil
+}
+
+// setConnectedAddr records an "ip:port" address as the connected address.
+func setConnectedAddr(metadata *ConnectionMetadata, addr string) {
+	host, portStr, _ := net.SplitHostPort(addr)
+	metadata.ConnectedIP = host
+	if port, err := strconv.Atoi(portStr); err == nil {
+		metadata.ConnectedPort = port
+	}
+}
+
+// dialer returns the Dialer for a connection, honoring the DialContext and
+// Resolver hooks of config (the transport's resolver otherwise).
+func (t *Transport) dialer(config Config, timeout time.Duration) Dialer {
+	d := Dialer{DialContext: config.DialContext, Resolver: config.Resolver, Timeout: timeout, Trace: config.Trace}
+	if d.Resolver == nil && t.resolver != net.DefaultResolver {
+		d.Resolver = t.resolver
+	}
+	return d
 }
 
 func (t *Transport) upgradeTLS(ctx context.Context, conn net.Conn, config Config, timer *timing.Timer, metadata *ConnectionMetadata) (net.Conn, error) {
@@ -610,14 +671,20 @@ func (t *Transport) upgradeTLS(ctx context.Context, conn net.Conn, config Config
 		metadata.TLSServerName = config.Host
 	}
 
-	tlsConn := tls.Client(conn, tlsConfig)
-	if err := tlsConn.HandshakeContext(tlsCtx); err != nil {
+	config.Trace.TraceTLSHandshakeStart()
+	tlsConn, hello, err := HandshakeTLS(tlsCtx, conn, tlsConfig, config.TLSFingerprint)
+	if err != nil {
+		config.Trace.TraceTLSHandshakeDone(tls.ConnectionState{}, err)
 		conn.Close() // Close original TCP connection to prevent resource leak
 		return nil, err
 	}
 
 	// Fill TLS metadata
-	state := tlsConn.ConnectionState()
+	state, _ := TLSConnectionState(tlsConn)
+	config.Trace.TraceTLSHandshakeDone(state, nil)
+	metadata.JA3 = hello.JA3
+	metadata.JA3Hash = hello.JA3Hash
+	metadata.JA4 = hello.JA4
 	metadata.TLSVersion = t.tlsVersionString(state.Version)
 	metadata.TLSCipherSuite = tls.CipherSuiteName(state.CipherSuite)
 	metadata.NegotiatedProtocol = state.NegotiatedProtocol
@@ -667,11 +734,12 @@ func (t *Transport) tlsVersionString(version uint16) string {
 }
 
 // getOrCreateHostPool retrieves or creates a host pool for the given key.
-func (t *Transport) getOrCreateHostPool(key string) *hostPool {
-	val, loaded := t.hostPools.LoadOrStore(key, newHostPool())
-	if !loaded {
-		// New pool created
+func (t *Transport) getOrCreateHostPool(key PoolKey) *hostPool {
+	name := key.String()
+	if val, ok := t.hostPools.Load(name); ok {
+		return val.(*hostPool)
 	}
+	val, _ := t.hostPools.LoadOrStore(name, newHostPool(key))
 	return val.(*hostPool)
 }
 
@@ -680,7 +748,7 @@ func (t *Transport) getOrCreateHostPool(key string) *hostPool {
 //   - (conn, metadata, true) if a reusable connection was found
 //   - (nil, nil, true) if no connection available but slot reserved for new one
 //   - (nil, nil, false) if pool is exhausted and wait timed out
-func (t *Transport) getFromPool(key string) (net.Conn, *ConnectionMetadata, bool) {
+func (t *Transport) getFromPool(key PoolKey) (net.Conn, *ConnectionMetadata, bool) {
 	hp := t.getOrCreateHostPool(key)
 
 	hp.mu.Lock()
@@ -710,6 +778,7 @@ func (t *Transport) getFromPool(key string) (net.Conn, *ConnectionMetadata, bool
 		atomic.AddUint64(&t.statsConnectionsReused, 1)
 
 		metaCopy := pc.metadata
+		metaCopy.IdleTime = time.Since(pc.lastUsed)
 		return pc.conn, &metaCopy, true
 	}
 
@@ -748,6 +817,7 @@ func (t *Transport) getFromPool(key string) (net.Conn, *ConnectionMetadata, bool
 					hp.numActive++
 					atomic.AddUint64(&t.statsConnectionsReused, 1)
 					metaCopy := pc.metadata
+					metaCopy.IdleTime = time.Since(pc.lastUsed)
 					return pc.conn, &metaCopy, true
 				}
 
@@ -774,7 +844,7 @@ func (t *Transport) getFromPool(key string) (net.Conn, *ConnectionMetadata, bool
 // when configured). Returns true if a slot was reserved (caller must create and later
 // release/close a connection carrying this poolKey), false if the pool is exhausted
 // and the wait timed out. Used by the ForceNewConn retry path (v2.2.0+).
-func (t *Transport) reserveSlot(key string) bool {
+func (t *Transport) reserveSlot(key PoolKey) bool {
 	hp := t.getOrCreateHostPool(key)
 
 	hp.mu.Lock()
@@ -813,12 +883,13 @@ func (t *Transport) ReleaseConnection(host string, port int, conn net.Conn) {
 
 // ReleaseConnectionWithMetadata marks a connection as available for reuse using metadata pool key
 func (t *Transport) ReleaseConnectionWithMetadata(host string, port int, conn net.Conn, metadata *ConnectionMetadata) {
-	// Use pool key from metadata if available (v2.0.3+), otherwise fallback to old format
+	// Use pool key from metadata if available (v2.0.3+), otherwise fall back to the
+	// key of a direct plain-HTTP connection
 	var key string
 	if metadata != nil && metadata.PoolKey != "" {
 		key = metadata.PoolKey
 	} else {
-		key = fmt.Sprintf("%s:%d", host, port)
+		key = PoolKey{Scheme: "http", Host: host, Port: port}.String()
 	}
 
 	val, ok := t.hostPools.Load(key)
@@ -869,12 +940,13 @@ func (t *Transport) CloseConnection(host string, port int, conn net.Conn) {
 
 // CloseConnectionWithMetadata closes and removes a connection from the pool using metadata pool key
 func (t *Transport) CloseConnectionWithMetadata(host string, port int, conn net.Conn, metadata *ConnectionMetadata) {
-	// Use pool key from metadata if available (v2.0.3+), otherwise fallback to old format
+	// Use pool key from metadata if available (v2.0.3+), otherwise fall back to the
+	// key of a direct plain-HTTP connection
 	var key string
 	if metadata != nil && metadata.PoolKey != "" {
 		key = metadata.PoolKey
 	} else {
-		key = fmt.Sprintf("%s:%d", host, port)
+		key = PoolKey{Scheme: "http", Host: host, Port: port}.String()
 	}
 
 	val, ok := t.hostPools.Load(key)
@@ -951,6 +1023,7 @@ func (t *Transport) PoolStats() PoolStats {
 		hostStats := HostPoolStats{
 			ActiveConns: activeCount,
 			IdleConns:   idleCount,
+			Key:         hp.key,
 		}
 
 		stats.ActiveConns += activeCount
@@ -1050,8 +1123,9 @@ func (t *Transport) connectViaProxy(ctx context.Context, config Config, targetAd
 	metadata.ProxyType = proxy.Type
 	metadata.ProxyAddr = proxyAddr
 
+	// TCP covers the connection to the proxy; each connector then starts the
+	// proxy phase for the tunnel setup.
 	timer.StartTCP()
-	defer timer.EndTCP()
 
 	var conn net.Conn
 	var err error
@@ -1059,15 +1133,17 @@ func (t *Transport) connectViaProxy(ctx context.Context, config Config, targetAd
 	// Route to appropriate proxy handler
 	switch proxy.Type {
 	case "http", "https":
-		conn, err = t.connectViaHTTPProxy(ctx, proxy, proxyAddr, config, targetAddr, proxyTimeout)
+		conn, err = t.connectViaHTTPProxy(ctx, proxy, proxyAddr, config, targetAddr, proxyTimeout, timer)
 	case "socks4":
-		conn, err = t.connectViaSOCKS4Proxy(ctx, proxy, proxyAddr, targetAddr, proxyTimeout)
+		conn, err = t.connectViaSOCKS4Proxy(ctx, proxy, proxyAddr, config, targetAddr, proxyTimeout, timer)
 	case "socks5":
-		conn, err = t.connectViaSOCKS5Proxy(ctx, proxy, proxyAddr, targetAddr, proxyTimeout)
+		conn, err = t.connectViaSOCKS5Proxy(ctx, proxy, proxyAddr, config, targetAddr, proxyTimeout, timer)
 	default:
 		return nil, nil, errors.NewValidationError(fmt.Sprintf("unsupported proxy type: %s", proxy.Type))
 	}
+	timer.EndProxy()
 
+	config.Trace.TraceProxyConnectDone(proxy.Type, proxyAddr, err)
 	if err != nil {
 		// Wrap error as ProxyError
 		return nil, nil, errors.NewProxyError(proxy.Type, proxyAddr, "connect", err)
@@ -1096,13 +1172,14 @@ func (t *Transport) connectViaProxy(ctx context.Context, config Config, targetAd
 // The target scheme (http vs https) determines traffic THROUGH the tunnel.
 // Example: http://proxy:8080 can proxy HTTPS requests - the tunnel is cleartext
 // but the target traffic inside is TLS-encrypted.
-func (t *Transport) connectViaHTTPProxy(ctx context.Context, proxy *ProxyConfig, proxyAddr string, config Config, targetAddr string, timeout time.Duration) (net.Conn, error) {
+func (t *Transport) connectViaHTTPProxy(ctx context.Context, proxy *ProxyConfig, proxyAddr string, config Config, targetAddr string, timeout time.Duration, timer *timing.Timer) (net.Conn, error) {
 	// Connect to proxy server
-	dialer := &net.Dialer{Timeout: timeout}
-	conn, err := dialer.DialContext(ctx, "tcp", proxyAddr)
+	conn, err := t.dialer(config, timeout).Dial(ctx, "tcp", proxyAddr)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to proxy: %w", err)
 	}
+	timer.EndTCP()
+	timer.StartProxy()
 
 	// If proxy type is HTTPS, upgrade connection to TLS
 	if proxy.Type == "https" {
@@ -1198,7 +1275,7 @@ func (t *Transport) connectViaHTTPProxy(ctx context.Context, proxy *ProxyConfig,
 //   - 0x5B: Request rejected or failed
 //   - 0x5C: Request failed (identd not running)
 //   - 0x5D: Request failed (identd auth failed)
-func (t *Transport) connectViaSOCKS4Proxy(ctx context.Context, proxy *ProxyConfig, proxyAddr string, targetAddr string, timeout time.Duration) (net.Conn, error) {
+func (t *Transport) connectViaSOCKS4Proxy(ctx context.Context, proxy *ProxyConfig, proxyAddr string, config Config, targetAddr string, timeout time.Duration, timer *timing.Timer) (net.Conn, error) {
 	// Parse target address
 	host, portStr, err := net.SplitHostPort(targetAddr)
 	if err != nil {
@@ -1210,7 +1287,8 @@ func (t *Transport) connectViaSOCKS4Proxy(ctx context.Context, proxy *ProxyConfi
 	}
 
 	// SOCKS4 requires IPv4 address - resolve hostname
-	ips, err := net.LookupIP(host)
+	dialer := t.dialer(config, timeout)
+	ips, err := dialer.LookupIP(ctx, host)
 	if err != nil {
 		return nil, fmt.Errorf("DNS resolution failed for %s: %w", host, err)
 	}
@@ -1227,11 +1305,12 @@ func (t *Transport) connectViaSOCKS4Proxy(ctx context.Context, proxy *ProxyConfi
 	}
 
 	// Connect to SOCKS4 proxy
-	dialer := &net.Dialer{Timeout: timeout}
-	conn, err := dialer.DialContext(ctx, "tcp", proxyAddr)
+	conn, err := dialer.Dial(ctx, "tcp", proxyAddr)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to SOCKS4 proxy: %w", err)
 	}
+	timer.EndTCP()
+	timer.StartProxy()
 
 	// Build SOCKS4 request
 	// Format: [VER(0x04)][CMD(0x01=CONNECT)][PORT(2 bytes)][IP(4 bytes)][USERID][NULL]
@@ -1292,7 +1371,7 @@ func (t *Transport) connectViaSOCKS4Proxy(ctx context.Context, proxy *ProxyConfi
 //
 // We use the proven golang.org/x/net/proxy library for SOCKS5 instead of
 // manual implementation for reliability and RFC compliance.
-func (t *Transport) connectViaSOCKS5Proxy(ctx context.Context, proxy *ProxyConfig, proxyAddr string, targetAddr string, timeout time.Duration) (net.Conn, error) {
+func (t *Transport) connectViaSOCKS5Proxy(ctx context.Context, proxy *ProxyConfig, proxyAddr string, config Config, targetAddr string, timeout time.Duration, timer *timing.Timer) (net.Conn, error) {
 	// Create SOCKS5 authentication if credentials provided
 	var auth *netproxy.Auth
 	if proxy.Username != "" {
@@ -1302,15 +1381,24 @@ func (t *Transport) connectViaSOCKS5Proxy(ctx context.Context, proxy *ProxyConfi
 		}
 	}
 
+	// Connect to the SOCKS5 proxy; the handshake then runs over this connection
+	proxyConn, err := t.dialer(config, timeout).Dial(ctx, "tcp", proxyAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to SOCKS5 proxy: %w", err)
+	}
+	timer.EndTCP()
+	timer.StartProxy()
+
 	// Create SOCKS5 dialer
-	dialer, err := netproxy.SOCKS5("tcp", proxyAddr, auth, &net.Dialer{Timeout: timeout})
+	dialer, err := netproxy.SOCKS5("tcp", proxyAddr, auth, ConnectedForward(proxyConn))
 	if err != nil {
+		proxyConn.Close()
 		return nil, fmt.Errorf("failed to create SOCKS5 dialer: %w", err)
 	}
 
 	// Dial target through SOCKS5 proxy
 	// Note: golang.org/x/net/proxy automatically resolves DNS via proxy by default
-	conn, err := dialer.Dial("tcp", targetAddr)
+	conn, err := dialer.(netproxy.ContextDialer).DialContext(ctx, "tcp", targetAddr)
 	if err != nil {
 		return nil, fmt.Errorf("SOCKS5 connection failed: %w", err)
 	}
@@ -6,6 +6,7 @@ package rawhttp
 import (
 	"context"
 	"fmt"
+	"io"
 	"strings"
 	"time"
 
@@ -15,6 +16,7 @@ import (
 	"github.com/WhileEndless/go-rawhttp/pkg/http2"
 	"github.com/WhileEndless/go-rawhttp/pkg/timing"
 	"github.com/WhileEndless/go-rawhttp/pkg/transport"
+	"golang.org/x/net/http2/hpack"
 )
 
 // Version is the current version of the rawhttp library
@@ -33,6 +35,31 @@ type (
 	// Response represents a parsed HTTP response.
 	Response = client.Response
 
+	// InterimResponse is an informational 1xx response received before the final one.
+	InterimResponse = client.InterimResponse
+
+	// HeaderField is a single response header line as it appeared on the wire.
+	HeaderField = client.HeaderField
+
+	// PushedResponse is a response pushed by an HTTP/2 server.
+	PushedResponse = client.PushedResponse
+
+	// PipelineResult is the outcome of DoPipelined.
+	PipelineResult = client.PipelineResult
+
+	// Session is a raw HTTP/1.1 exchange on one connection (OpenSession).
+	Session = client.Session
+
+	// ConnectionMetadata describes an established connection (Session.ConnectionMetadata)
+	ConnectionMetadata = transport.ConnectionMetadata
+
+	// ParseMode selects strict or lenient HTTP/1.1 parsing (Options.ParseMode)
+	ParseMode = client.ParseMode
+
+	// Anomaly is a deviation from RFC 9112 observed in a response (Response.Anomalies)
+	Anomaly     = client.Anomaly
+	AnomalyType = client.AnomalyType
+
 	// Buffer provides memory-efficient storage with disk spilling.
 	Buffer = buffer.Buffer
 
@@ -57,6 +84,29 @@ type (
 	// HostPoolStats provides per-host pool statistics (v2.1.0+)
 	HostPoolStats = transport.HostPoolStats
 
+	// PoolKey identifies the connections a request may share (target, proxy, TLS setup)
+	PoolKey = transport.PoolKey
+
+	// DialContextFunc dials a connection (Options.DialContext)
+	DialContextFunc = transport.DialContextFunc
+
+	// Resolver resolves host names (Options.Resolver); *net.Resolver implements it
+	Resolver = transport.Resolver
+
+	// DialAttempt records one connection attempt (Response.DialAttempts)
+	DialAttempt = transport.DialAttempt
+
+	// TLSFingerprint shapes the TLS ClientHello (Options.TLSFingerprint)
+	TLSFingerprint = transport.TLSFingerprint
+
+	// ClientTrace holds request and connection lifecycle hooks (Options.Trace)
+	ClientTrace = transport.ClientTrace
+
+	// DNSDoneInfo, GotConnInfo and FrameInfo are passed to ClientTrace hooks
+	DNSDoneInfo = transport.DNSDoneInfo
+	GotConnInfo = transport.GotConnInfo
+	FrameInfo   = transport.FrameInfo
+
 	// ProxyConfig contains upstream proxy configuration (v2.0.0+)
 	ProxyConfig = client.ProxyConfig
 
@@ -64,6 +114,28 @@ type (
 	ProxyError = errors.ProxyError
 )
 
+// Re-export parse modes and anomaly types
+const (
+	ParseLenient = client.ParseLenient
+	ParseStrict  = client.ParseStrict
+
+	AnomalyBareLF                 = client.AnomalyBareLF
+	AnomalyInvalidStatusLine      = client.AnomalyInvalidStatusLine
+	AnomalyObsFold                = client.AnomalyObsFold
+	AnomalyMalformedHeader        = client.AnomalyMalformedHeader
+	AnomalyWhitespaceBeforeColon  = client.AnomalyWhitespaceBeforeColon
+	AnomalyDuplicateContentLength = client.AnomalyDuplicateContentLength
+	AnomalyInvalidContentLength   = client.AnomalyInvalidContentLength
+	AnomalyConflictingFraming     = client.AnomalyConflictingFraming
+	AnomalyUnexpectedBody         = client.AnomalyUnexpectedBody
+	AnomalyChunkExtension         = client.AnomalyChunkExtension
+	AnomalyInvalidChunkSize       = client.AnomalyInvalidChunkSize
+	AnomalyOversizeChunkLine      = client.AnomalyOversizeChunkLine
+	AnomalyMissingChunkCRLF       = client.AnomalyMissingChunkCRLF
+	AnomalyBodyTooShort           = client.AnomalyBodyTooShort
+	AnomalyBodyTooLong            = client.AnomalyBodyTooLong
+)
+
 // Re-export error types for convenience
 const (
 	ErrorTypeDNS        = errors.ErrorTypeDNS
@@ -74,6 +146,7 @@ const (
 	ErrorTypeIO         = errors.ErrorTypeIO
 	ErrorTypeValidation = errors.ErrorTypeValidation
 	ErrorTypeProxy      = errors.ErrorTypeProxy // v2.0.0+
+	ErrorTypeLimit      = errors.ErrorTypeLimit
 )
 
 // Sender implements raw HTTP transport for both HTTP/1.1 and HTTP/2.
@@ -163,13 +236,52 @@ func ParseProxyURL(proxyURL string) *ProxyConfig {
 // Do executes the HTTP request using raw sockets.
 // Automatically detects protocol from request or options.
 func (s *Sender) Do(ctx context.Context, req []byte, opts Options) (*Response, error) {
+	return s.do(ctx, req, opts, false)
+}
+
+// DoStream executes the HTTP request and returns as soon as the response head has
+// been parsed, exposing the body as Response.BodyStream instead of buffering it.
+// Chunked HTTP/1.1 bodies are de-chunked on the fly and HTTP/2 DATA frames are
+// read on demand, which makes it suitable for server-sent events, long-polls and
+// very large downloads. Response.Body stays empty and Response.Raw only holds the
+// response head. Response.Timings gains the body timings once BodyStream
+// reaches EOF.
+//
+// The caller MUST close Response.BodyStream. With ReuseConnection enabled, the
+// HTTP/1.1 connection is returned to the pool only after the stream was read to
+// EOF and closed; closing early discards the connection.
+//
+// Example:
+//
+//	resp, err := sender.DoStream(ctx, req, opts)
+//	if err != nil {
+//	    return err
+//	}
+//	defer resp.BodyStream.Close()
+//	io.Copy(dst, resp.BodyStream)
+func (s *Sender) DoStream(ctx context.Context, req []byte, opts Options) (*Response, error) {
+	return s.do(ctx, req, opts, true)
+}
+
+// do implements Do and DoStream.
+func (s *Sender) do(ctx context.Context, req []byte, opts Options, stream bool) (*Response, error) {
+	doHTTP1 := s.client.Do
+	doHTTP2 := s.http2Client.DoWithOptions
+	if stream {
+		doHTTP1 = s.client.DoStream
+		doHTTP2 = s.http2Client.DoStreamWithOptions
+	}
+
 	// Detect protocol from request line or options
 	protocol := s.detectProtocol(req, opts)
 	protocolExplicit := opts.Protocol != ""
 
 	if protocol == "http/2" {
 		// Convert client.Options to http2.Options
-		http2Opts := s.convertToHTTP2Options(opts)
+		http2Opts, err := s.convertToHTTP2Options(opts)
+		if err != nil {
+			return nil, err
+		}
 		http2Opts.ProtocolExplicit = protocolExplicit
 
 		// Stale-connection retry loop (v2.2.0+), mirroring the HTTP/1.1 client.
@@ -179,12 +291,15 @@ func (s *Sender) Do(ctx context.Context, req []byte, opts Options) (*Response, e
 		// opens a fresh connection.
 		const maxH2Retries = 1
 		var resp *http2.Response
-		var err error
 		for attempt := 0; attempt <= maxH2Retries; attempt++ {
-			resp, err = s.http2Client.DoWithOptions(ctx, req, opts.Host, opts.Port, opts.Scheme, http2Opts)
+			resp, err = doHTTP2(ctx, req, opts.Host, opts.Port, opts.Scheme, http2Opts)
 			if err == nil {
 				break
 			}
+			if resp != nil && errors.IsLimitError(err) {
+				// Over a size limit: the response as read so far comes with the error.
+				return s.convertHTTP2Response(resp), err
+			}
 			if attempt < maxH2Retries && http2Opts.ReuseConnection && http2.IsStaleConnError(err) {
 				continue
 			}
@@ -194,17 +309,115 @@ func (s *Sender) Do(ctx context.Context, req []byte, opts Options) (*Response, e
 			//   2. Protocol was explicit BUT EnableProtocolFallback is true
 			shouldFallback := !protocolExplicit || opts.EnableProtocolFallback
 			if shouldFallback && s.shouldFallbackToHTTP1(err) {
-				return s.client.Do(ctx, req, opts)
+				return doHTTP1(ctx, req, opts)
 			}
 			return nil, err
 		}
 
 		// Convert HTTP/2 response to common Response format
-		return s.convertHTTP2Response(resp), nil
+		response := s.convertHTTP2Response(resp)
+		client.DecodeResponse(response, opts)
+		return response, nil
 	}
 
 	// Use HTTP/1.1 client (default)
-	return s.client.Do(ctx, req, opts)
+	return doHTTP1(ctx, req, opts)
+}
+
+// BatchResult is the outcome of one request sent with DoBatch.
+type BatchResult struct {
+	Index      int       // Position of the request in the batch
+	StreamID   uint32    // HTTP/2 stream the request was sent on (0 if never opened)
+	Response   *Response // Response (nil if Err is set, unless Err is a limit error)
+	Err        error     // Error for this request only
+	SentAt     time.Time // When the last frame of the request was written
+	ReceivedAt time.Time // When the first response frame arrived
+}
+
+// DoBatch sends all requests as concurrent streams of one HTTP/2 connection,
+// regardless of the version in their request lines. The HEADERS frames are
+// written back-to-back; with HTTP2Settings.SinglePacket the final frame of every
+// request is released in a single TCP write, so the server sees the requests
+// complete at the same moment (race-condition testing).
+//
+// Results are in request order; Response.Timings holds per-stream timings. The
+// returned error is only set when the connection cannot be established. There is
+// no HTTP/1.1 fallback and no retry, so every request is sent at most once.
+func (s *Sender) DoBatch(ctx context.Context, reqs [][]byte, opts Options) ([]BatchResult, error) {
+	http2Opts, err := s.convertToHTTP2Options(opts)
+	if err != nil {
+		return nil, err
+	}
+	http2Opts.ProtocolExplicit = true
+
+	results, err := s.http2Client.DoMulti(ctx, reqs, opts.Host, opts.Port, opts.Scheme, http2Opts)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]BatchResult, len(results))
+	for i, r := range results {
+		out[i] = BatchResult{
+			Index:      r.Index,
+			StreamID:   r.StreamID,
+			Err:        r.Err,
+			SentAt:     r.SentAt,
+			ReceivedAt: r.ReceivedAt,
+		}
+		if r.Response != nil {
+			out[i].Response = s.convertHTTP2Response(r.Response)
+			if r.Err == nil {
+				client.DecodeResponse(out[i].Response, opts)
+			}
+		}
+	}
+	return out, nil
+}
+
+// DoPipelined sends all requests on one new HTTP/1.1 connection without waiting
+// for responses (pipelining) and parses the responses in order. Each response
+// carries its own Raw bytes and Timings; received bytes that cannot be attributed
+// to a response are returned in PipelineResult.Unattributed. With
+// Options.PipelineSinglePacket all requests are written in a single write.
+//
+// The requests are sent verbatim over HTTP/1.1 whatever their request line says,
+// and the connection is closed afterwards. The returned error is only set when
+// the connection cannot be established.
+//
+// Example:
+//
+//	result, err := sender.DoPipelined(ctx, [][]byte{req1, req2}, opts)
+//	if err != nil {
+//	    return err
+//	}
+//	for _, resp := range result.Responses {
+//	    fmt.Println(resp.StatusCode)
+//	}
+func (s *Sender) DoPipelined(ctx context.Context, reqs [][]byte, opts Options) (*PipelineResult, error) {
+	return s.client.DoPipelined(ctx, reqs, opts)
+}
+
+// OpenSession opens a new HTTP/1.1 connection to the target in opts (TLS and
+// proxy included) and returns a Session bound to it. Unlike Do, which picks a
+// connection from the pool, every request written through the session goes to
+// exactly this connection; a read error wrapping io.EOF tells that the server
+// closed it. The caller must Close the session.
+//
+// Example:
+//
+//	session, err := sender.OpenSession(ctx, opts)
+//	if err != nil {
+//	    return err
+//	}
+//	defer session.Close()
+//	for _, req := range reqs {
+//	    resp, err := session.Do(req)
+//	    if errors.Is(err, io.EOF) {
+//	        break // server closed the connection
+//	    }
+//	    ...
+//	}
+func (s *Sender) OpenSession(ctx context.Context, opts Options) (*Session, error) {
+	return s.client.OpenSession(ctx, opts)
 }
 
 // shouldFallbackToHTTP1 determines if an error warrants protocol fallback (DEF-16).
@@ -284,8 +497,9 @@ func (s *Sender) detectProtocol(req []byte, opts Options) string {
 	return "http/1.1"
 }
 
-// convertToHTTP2Options converts client.Options to http2.Options
-func (s *Sender) convertToHTTP2Options(opts Options) *http2.Options {
+// convertToHTTP2Options converts client.Options to http2.Options. It fails only
+// on an invalid HTTP2Settings.Fingerprint.
+func (s *Sender) convertToHTTP2Options(opts Options) (*http2.Options, error) {
 	var h2opts *http2.Options
 
 	if opts.HTTP2Settings == nil {
@@ -302,12 +516,23 @@ func (s *Sender) convertToHTTP2Options(opts Options) *http2.Options {
 			HeaderTableSize:      opts.HTTP2Settings.HeaderTableSize,
 			DisableServerPush:    opts.HTTP2Settings.DisableServerPush,
 			EnableCompression:    opts.HTTP2Settings.EnableCompression,
+			SinglePacket:         opts.HTTP2Settings.SinglePacket,
+			PingTimeout:          opts.HTTP2Settings.PingTimeout,
+			MaxConnsPerHost:      opts.HTTP2Settings.MaxConnsPerHost,
 		}
 		// Copy Debug fields manually due to different struct tags
 		h2opts.Debug.LogFrames = opts.HTTP2Settings.Debug.LogFrames
 		h2opts.Debug.LogSettings = opts.HTTP2Settings.Debug.LogSettings
 		h2opts.Debug.LogHeaders = opts.HTTP2Settings.Debug.LogHeaders
 		h2opts.Debug.LogData = opts.HTTP2Settings.Debug.LogData
+
+		if opts.HTTP2Settings.Fingerprint != "" {
+			fp, err := http2.ParseFingerprint(opts.HTTP2Settings.Fingerprint)
+			if err != nil {
+				return nil, errors.NewValidationError("invalid HTTP/2 fingerprint: " + err.Error())
+			}
+			h2opts.Fingerprint = fp
+		}
 	}
 
 	// Always override TLS settings from main options
@@ -328,6 +553,7 @@ func (s *Sender) convertToHTTP2Options(opts Options) *http2.Options {
 	h2opts.MaxTLSVersion = opts.MaxTLSVersion
 	h2opts.TLSRenegotiation = opts.TLSRenegotiation
 	h2opts.CipherSuites = opts.CipherSuites
+	h2opts.TLSFingerprint = opts.TLSFingerprint
 
 	// Pass proxy configuration (v2.0.3+)
 	if opts.Proxy != nil {
@@ -344,6 +570,16 @@ func (s *Sender) convertToHTTP2Options(opts Options) *http2.Options {
 		}
 	}
 
+	// Pass dial and resolver hooks
+	h2opts.DialContext = opts.DialContext
+	h2opts.Resolver = opts.Resolver
+	h2opts.Network = opts.Network
+	h2opts.HappyEyeballs = opts.HappyEyeballs
+	h2opts.FallbackDelay = opts.FallbackDelay
+
+	// Pass lifecycle trace hooks
+	h2opts.Trace = opts.Trace
+
 	// Pass connection pooling setting (v2.0.3+)
 	h2opts.ReuseConnection = opts.ReuseConnection
 
@@ -351,10 +587,78 @@ func (s *Sender) convertToHTTP2Options(opts Options) *http2.Options {
 	// bound how long it waits for response frames.
 	h2opts.ReadTimeout = opts.ReadTimeout
 
+	// Pass response size limits
+	h2opts.MaxHeaderBytes = opts.MaxHeaderBytes
+	h2opts.MaxHeaderCount = opts.MaxHeaderCount
+	h2opts.MaxBodyBytes = opts.MaxBodyBytes
+
 	// Pass protocol fallback setting (DEF-16, v2.1.4+)
 	h2opts.EnableProtocolFallback = opts.EnableProtocolFallback
 
-	return h2opts
+	return h2opts, nil
+}
+
+// convertHTTP2Interim converts interim HTTP/2 responses to the common format.
+func convertHTTP2Interim(in []http2.InterimResponse) []InterimResponse {
+	if len(in) == 0 {
+		return nil
+	}
+	out := make([]InterimResponse, 0, len(in))
+	for _, ir := range in {
+		out = append(out, InterimResponse{
+			StatusLine: fmt.Sprintf("HTTP/2 %d %s", ir.Status, ir.StatusText),
+			StatusCode: ir.Status,
+			Headers:    ir.Headers,
+			HeaderList: convertHTTP2HeaderList(ir.HeaderList),
+			ReceivedAt: ir.ReceivedAt,
+		})
+	}
+	return out
+}
+
+// convertHTTP2Pushes converts received server pushes, including their pushed
+// responses.
+func (s *Sender) convertHTTP2Pushes(in []*http2.PushPromise) []PushedResponse {
+	if len(in) == 0 {
+		return nil
+	}
+	out := make([]PushedResponse, 0, len(in))
+	for _, pp := range in {
+		pushed := PushedResponse{
+			PromisedStreamID: pp.PromisedStreamID,
+			ReceivedAt:       pp.ReceivedAt,
+			Err:              pp.Err,
+		}
+		for _, f := range pp.HeaderList {
+			pushed.RequestHeaders = append(pushed.RequestHeaders, HeaderField{
+				Name:    f.Name,
+				Value:   f.Value,
+				RawLine: f.Name + ": " + f.Value + "\r\n",
+			})
+		}
+		if pp.Response != nil {
+			pushed.Response = s.convertHTTP2Response(pp.Response)
+		}
+		out = append(out, pushed)
+	}
+	return out
+}
+
+// convertHTTP2HeaderList converts decoded HTTP/2 fields to header lines, skipping
+// pseudo-headers (the status is reported separately).
+func convertHTTP2HeaderList(fields []hpack.HeaderField) []HeaderField {
+	var list []HeaderField
+	for _, f := range fields {
+		if strings.HasPrefix(f.Name, ":") {
+			continue
+		}
+		list = append(list, HeaderField{
+			Name:    f.Name,
+			Value:   f.Value,
+			RawLine: f.Name + ": " + f.Value + "\r\n",
+		})
+	}
+	return list
 }
 
 // convertHTTP2Response converts HTTP/2 response to common Response format
@@ -394,10 +698,17 @@ func (s *Sender) convertHTTP2Response(resp *http2.Response) *Response {
 		metricsPtr = &timingMetrics
 	}
 
-	return &Response{
+	var pushes []PushedResponse
+	if resp.BodyStream == nil {
+		pushes = s.convertHTTP2Pushes(resp.ServerPush)
+	}
+
+	out := &Response{
 		StatusCode:  resp.Status,
 		StatusLine:  statusLine,
 		Headers:     headers,
+		HeaderList:  convertHTTP2HeaderList(resp.HeaderList),
+		Trailers:    resp.Trailers, // Shared: trailers read via BodyStream show up here
 		Body:        buffer.NewWithData(resp.Body),
 		Raw:         rawBuf,
 		HTTPVersion: resp.HTTPVersion,
@@ -417,11 +728,53 @@ func (s *Sender) convertHTTP2Response(resp *http2.Response) *Response {
 		TLSServerName:      resp.TLSServerName,
 		ConnectionReused:   resp.ConnectionReused,
 
+		// ClientHello fingerprint
+		JA3:     resp.JA3,
+		JA3Hash: resp.JA3Hash,
+		JA4:     resp.JA4,
+
 		// Proxy metadata
 		ProxyUsed: resp.ProxyUsed,
 		ProxyType: resp.ProxyType,
 		ProxyAddr: resp.ProxyAddr,
+
+		// Dial attempts of the connection
+		DialAttempts: resp.DialAttempts,
+
+		// Interim 1xx responses
+		Informational: convertHTTP2Interim(resp.Informational),
+
+		// HTTP/2 server push (pushed responses are only complete for buffered bodies)
+		ServerPush: pushes,
+
+		// Streaming body (DoStream only)
+		BodyStream: resp.BodyStream,
+	}
+	if resp.BodyStream != nil {
+		out.BodyStream = &http2TimedStream{ReadCloser: resp.BodyStream, response: out}
+	}
+	return out
+}
+
+// http2TimedStream copies the HTTP/2 metrics, completed with the body timings
+// once the stream reaches EOF, into Response.Timings.
+type http2TimedStream struct {
+	io.ReadCloser
+	response *Response
+}
+
+func (s *http2TimedStream) Read(p []byte) (int, error) {
+	n, err := s.ReadCloser.Read(p)
+	if err == io.EOF && s.response.Metrics != nil {
+		s.response.Timings = *s.response.Metrics
 	}
+	return n, err
+}
+
+// ParseTLSFingerprint returns the TLS fingerprint for a built-in browser profile
+// ("chrome", "firefox", "safari", "edge", "ios", "android") or a JA3 string.
+func ParseTLSFingerprint(s string) (*TLSFingerprint, error) {
+	return transport.ParseTLSFingerprint(s)
 }
 
 // NewBuffer creates a new buffer with the specified memory limit.
@@ -439,6 +792,12 @@ func IsTemporaryError(err error) bool {
 	return errors.IsTemporaryError(err)
 }
 
+// IsLimitError checks if an error is due to a response exceeding a size limit
+// (Options.MaxHeaderBytes, MaxHeaderCount, MaxBodyBytes or MaxChunkLineLength).
+func IsLimitError(err error) bool {
+	return errors.IsLimitError(err)
+}
+
 // GetErrorType returns the error type if it's a structured error.
 func GetErrorType(err error) string {
 	return string(errors.GetErrorType(err))
@@ -0,0 +1,264 @@
+package unit
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/WhileEndless/go-rawhttp"
+	"github.com/WhileEndless/go-rawhttp/pkg/errors"
+)
+
+// fixedResponseServer answers every connection with raw and closes it.
+func fixedResponseServer(t *testing.T, raw string) int {
+	return startRawServer(t, func(conn net.Conn) {
+		defer conn.Close()
+		if err := readRequestHead(bufio.NewReader(conn)); err != nil {
+			return
+		}
+		io.WriteString(conn, raw)
+	})
+}
+
+func TestParseMode_Anomalies(t *testing.T) {
+	tests := []struct {
+		name      string
+		method    string
+		response  string
+		body      string
+		anomalies []rawhttp.AnomalyType
+		legal     bool // not an error in strict mode either
+	}{
+		{
+			name:     "clean",
+			response: "HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\nok",
+			body:     "ok",
+			legal:    true,
+		},
+		{
+			name:      "bare LF",
+			response:  "HTTP/1.1 200 OK\nContent-Length: 2\n\nok",
+			body:      "ok",
+			anomalies: []rawhttp.AnomalyType{rawhttp.AnomalyBareLF, rawhttp.AnomalyBareLF, rawhttp.AnomalyBareLF},
+		},
+		{
+			name:      "status line without reason",
+			response:  "HTTP/1.1 200\r\nContent-Length: 2\r\n\r\nok",
+			body:      "ok",
+			anomalies: []rawhttp.AnomalyType{rawhttp.AnomalyInvalidStatusLine},
+		},
+		{
+			name:      "obs-fold",
+			response:  "HTTP/1.1 200 OK\r\nX-Folded: a\r\n b\r\nContent-Length: 2\r\n\r\nok",
+			body:      "ok",
+			anomalies: []rawhttp.AnomalyType{rawhttp.AnomalyObsFold},
+		},
+		{
+			name:      "header without colon",
+			response:  "HTTP/1.1 200 OK\r\nNoColon\r\nContent-Length: 2\r\n\r\nok",
+			body:      "ok",
+			anomalies: []rawhttp.AnomalyType{rawhttp.AnomalyMalformedHeader},
+		},
+		{
+			name:      "whitespace before colon",
+			response:  "HTTP/1.1 200 OK\r\nContent-Length : 2\r\n\r\nok",
+			body:      "ok",
+			anomalies: []rawhttp.AnomalyType{rawhttp.AnomalyWhitespaceBeforeColon},
+		},
+		{
+			name:      "duplicate Content-Length",
+			response:  "HTTP/1.1 200 OK\r\nContent-Length: 2\r\nContent-Length: 2, 3\r\n\r\nok",
+			body:      "ok",
+			anomalies: []rawhttp.AnomalyType{rawhttp.AnomalyDuplicateContentLength},
+		},
+		{
+			name:      "signed Content-Length",
+			response:  "HTTP/1.1 200 OK\r\nContent-Length: +2\r\n\r\nok",
+			body:      "ok",
+			anomalies: []rawhttp.AnomalyType{rawhttp.AnomalyInvalidContentLength},
+		},
+		{
+			name:      "Content-Length with Transfer-Encoding",
+			response:  "HTTP/1.1 200 OK\r\nContent-Length: 100\r\nTransfer-Encoding: chunked\r\n\r\n2\r\nok\r\n0\r\n\r\n",
+			body:      "ok",
+			anomalies: []rawhttp.AnomalyType{rawhttp.AnomalyConflictingFraming},
+		},
+		{
+			name:      "body for HEAD",
+			method:    "HEAD",
+			response:  "HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\nok",
+			body:      "ok",
+			anomalies: []rawhttp.AnomalyType{rawhttp.AnomalyUnexpectedBody},
+		},
+		{
+			name:      "chunk extension",
+			response:  "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n2;name=value\r\nok\r\n0\r\n\r\n",
+			body:      "ok",
+			anomalies: []rawhttp.AnomalyType{rawhttp.AnomalyChunkExtension},
+			legal:     true,
+		},
+		{
+			name:      "padded chunk size",
+			response:  "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n 2\r\nok\r\n0\r\n\r\n",
+			body:      "ok",
+			anomalies: []rawhttp.AnomalyType{rawhttp.AnomalyInvalidChunkSize},
+		},
+		{
+			name:      "signed chunk size",
+			response:  "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n+2\r\nok\r\n0\r\n\r\n",
+			body:      "ok",
+			anomalies: []rawhttp.AnomalyType{rawhttp.AnomalyInvalidChunkSize},
+		},
+		{
+			name:      "oversize chunk line",
+			response:  "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n2;" + strings.Repeat("x", 5000) + "\r\nok\r\n0\r\n\r\n",
+			body:      "ok",
+			anomalies: []rawhttp.AnomalyType{rawhttp.AnomalyOversizeChunkLine, rawhttp.AnomalyChunkExtension},
+		},
+		{
+			name:      "chunk data without CRLF",
+			response:  "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n2\r\nok0\r\n\r\n",
+			body:      "ok",
+			anomalies: []rawhttp.AnomalyType{rawhttp.AnomalyMissingChunkCRLF},
+		},
+		{
+			name:      "body shorter than Content-Length",
+			response:  "HTTP/1.1 200 OK\r\nContent-Length: 10\r\n\r\nok",
+			body:      "ok",
+			anomalies: []rawhttp.AnomalyType{rawhttp.AnomalyBodyTooShort},
+		},
+		{
+			name:      "body longer than Content-Length",
+			response:  "HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\nokEXTRA",
+			body:      "ok",
+			anomalies: []rawhttp.AnomalyType{rawhttp.AnomalyBodyTooLong},
+		},
+		{
+			name:      "chunked body without last chunk",
+			response:  "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n2\r\nok\r\n",
+			body:      "ok",
+			anomalies: []rawhttp.AnomalyType{rawhttp.AnomalyBodyTooShort},
+		},
+	}
+
+	sender := rawhttp.NewSender()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			port := fixedResponseServer(t, tt.response)
+			method := tt.method
+			if method == "" {
+				method = "GET"
+			}
+			req := []byte(method + " / HTTP/1.1\r\nHost: localhost\r\n\r\n")
+
+			// Lenient (default): parsed, anomalies recorded, raw bytes kept as sent
+			resp, err := sender.Do(context.Background(), req, streamOpts(port))
+			if err != nil {
+				t.Fatalf("lenient parse failed: %v", err)
+			}
+			defer resp.Body.Close()
+			defer resp.Raw.Close()
+			if string(resp.Body.Bytes()) != tt.body {
+				t.Errorf("expected body %q, got %q", tt.body, resp.Body.Bytes())
+			}
+			var got []rawhttp.AnomalyType
+			for _, a := range resp.Anomalies {
+				got = append(got, a.Type)
+			}
+			if strings.Join(asStrings(got), ",") != strings.Join(asStrings(tt.anomalies), ",") {
+				t.Errorf("expected anomalies %v, got %v", tt.anomalies, resp.Anomalies)
+			}
+			if raw := string(resp.Raw.Bytes()); !strings.HasPrefix(tt.response, raw) || raw == "" {
+				t.Errorf("raw bytes %q are not a prefix of the response", raw)
+			}
+
+			// Strict: every violation is a protocol error
+			opts := streamOpts(port)
+			opts.ParseMode = rawhttp.ParseStrict
+			resp, err = sender.Do(context.Background(), req, opts)
+			if resp != nil {
+				defer resp.Body.Close()
+				defer resp.Raw.Close()
+			}
+			if tt.legal {
+				if err != nil {
+					t.Errorf("strict parse failed: %v", err)
+				}
+				return
+			}
+			if errors.GetErrorType(err) != errors.ErrorTypeProtocol {
+				t.Fatalf("expected a protocol error in strict mode, got %v", err)
+			}
+			if !strings.Contains(err.Error(), string(tt.anomalies[0])) {
+				t.Errorf("expected the error to name %s, got %v", tt.anomalies[0], err)
+			}
+		})
+	}
+}
+
+func asStrings(types []rawhttp.AnomalyType) []string {
+	out := make([]string, len(types))
+	for i, t := range types {
+		out[i] = string(t)
+	}
+	return out
+}
+
+// DoStream de-chunks with the same checks as the buffered reader: the anomalies
+// are recorded as the body is read, and fail the read in strict mode.
+func TestParseMode_StreamChunkAnomalies(t *testing.T) {
+	tests := []struct {
+		name    string
+		chunks  string
+		anomaly rawhttp.AnomalyType
+	}{
+		{name: "chunk extension", chunks: "2;name=value\r\nok\r\n0\r\n\r\n", anomaly: rawhttp.AnomalyChunkExtension},
+		{name: "padded chunk size", chunks: " 2\r\nok\r\n0\r\n\r\n", anomaly: rawhttp.AnomalyInvalidChunkSize},
+		{name: "signed chunk size", chunks: "+2\r\nok\r\n0\r\n\r\n", anomaly: rawhttp.AnomalyInvalidChunkSize},
+		{name: "chunk data without CRLF", chunks: "2\r\nok0\r\n\r\n", anomaly: rawhttp.AnomalyMissingChunkCRLF},
+		{name: "bare LF after chunk data", chunks: "2\r\nok\n0\r\n\r\n", anomaly: rawhttp.AnomalyBareLF},
+		{name: "trailer without colon", chunks: "2\r\nok\r\n0\r\nNoColon\r\n\r\n", anomaly: rawhttp.AnomalyMalformedHeader},
+	}
+
+	sender := rawhttp.NewSender()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			port := fixedResponseServer(t, "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n"+tt.chunks)
+			req := []byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n")
+
+			resp, err := sender.DoStream(context.Background(), req, streamOpts(port))
+			if err != nil {
+				t.Fatalf("lenient DoStream failed: %v", err)
+			}
+			body, err := io.ReadAll(resp.BodyStream)
+			resp.BodyStream.Close()
+			if err != nil || string(body) != "ok" {
+				t.Fatalf("expected body \"ok\", got %q (%v)", body, err)
+			}
+			if len(resp.Anomalies) != 1 || resp.Anomalies[0].Type != tt.anomaly {
+				t.Errorf("expected anomaly %s, got %v", tt.anomaly, resp.Anomalies)
+			}
+
+			opts := streamOpts(port)
+			opts.ParseMode = rawhttp.ParseStrict
+			resp, err = sender.DoStream(context.Background(), req, opts)
+			if err != nil {
+				t.Fatalf("strict DoStream failed on the head: %v", err)
+			}
+			_, err = io.ReadAll(resp.BodyStream)
+			resp.BodyStream.Close()
+			if tt.anomaly == rawhttp.AnomalyChunkExtension {
+				if err != nil {
+					t.Errorf("strict read failed: %v", err)
+				}
+				return
+			}
+			if errors.GetErrorType(err) != errors.ErrorTypeProtocol || !strings.Contains(err.Error(), string(tt.anomaly)) {
+				t.Errorf("expected a protocol error naming %s, got %v", tt.anomaly, err)
+			}
+		})
+	}
+}
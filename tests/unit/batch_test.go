@@ -0,0 +1,68 @@
+package unit
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/WhileEndless/go-rawhttp"
+	"github.com/WhileEndless/go-rawhttp/pkg/client"
+)
+
+func TestSender_DoBatch(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		peers = map[string]bool{}
+	)
+	srv := newHTTP2Server(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		peers[r.RemoteAddr] = true
+		mu.Unlock()
+		body, _ := io.ReadAll(r.Body)
+		io.WriteString(w, r.URL.Path+":"+string(body))
+	})
+	defer srv.Close()
+
+	opts := h2Opts(srv)
+	opts.HTTP2Settings = &client.HTTP2Settings{
+		MaxConcurrentStreams: 100,
+		InitialWindowSize:    4194304,
+		MaxFrameSize:         16384,
+		MaxHeaderListSize:    10485760,
+		HeaderTableSize:      4096,
+		EnableCompression:    true,
+		DisableServerPush:    true,
+		SinglePacket:         true,
+	}
+
+	reqs := [][]byte{
+		[]byte("POST /one HTTP/1.1\r\nHost: localhost\r\nContent-Length: 3\r\n\r\nabc"),
+		[]byte("GET /two HTTP/2\r\nHost: localhost\r\n\r\n"),
+		[]byte("POST /three HTTP/2\r\nHost: localhost\r\nContent-Length: 1\r\n\r\nz"),
+	}
+	want := []string{"/one:abc", "/two:", "/three:z"}
+
+	results, err := rawhttp.NewSender().DoBatch(context.Background(), reqs, opts)
+	if err != nil {
+		t.Fatalf("DoBatch failed: %v", err)
+	}
+	for i, r := range results {
+		if r.Err != nil {
+			t.Fatalf("request %d failed: %v", i, r.Err)
+		}
+		body := r.Response.Body.Bytes()
+		if string(body) != want[i] {
+			t.Errorf("request %d: expected body %q, got %q", i, want[i], body)
+		}
+		if r.Response.HTTPVersion != "HTTP/2" || r.StreamID == 0 {
+			t.Errorf("request %d: expected an HTTP/2 stream, got %s on stream %d", i, r.Response.HTTPVersion, r.StreamID)
+		}
+		r.Response.Body.Close()
+		r.Response.Raw.Close()
+	}
+	if len(peers) != 1 {
+		t.Errorf("expected one connection, got %d", len(peers))
+	}
+}
@@ -0,0 +1,266 @@
+package unit
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/WhileEndless/go-rawhttp"
+	"github.com/WhileEndless/go-rawhttp/pkg/buffer"
+	"github.com/WhileEndless/go-rawhttp/pkg/client"
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// encode applies codings to data in order, as a server would for
+// "Content-Encoding: <codings joined by comma>".
+func encode(t *testing.T, data []byte, codings ...string) []byte {
+	t.Helper()
+	for _, coding := range codings {
+		var buf bytes.Buffer
+		var w io.WriteCloser
+		switch coding {
+		case "gzip":
+			w = gzip.NewWriter(&buf)
+		case "deflate":
+			w = zlib.NewWriter(&buf)
+		case "raw-deflate":
+			w, _ = flate.NewWriter(&buf, flate.DefaultCompression)
+		case "br":
+			w = brotli.NewWriter(&buf)
+		case "zstd":
+			w, _ = zstd.NewWriter(&buf)
+		default:
+			t.Fatalf("unknown coding %q", coding)
+		}
+		w.Write(data)
+		w.Close()
+		data = buf.Bytes()
+	}
+	return data
+}
+
+func encodedResponse(encoding string, body []byte) string {
+	return fmt.Sprintf("HTTP/1.1 200 OK\r\nContent-Encoding: %s\r\nContent-Length: %d\r\n\r\n%s", encoding, len(body), body)
+}
+
+func TestDecodeContent(t *testing.T) {
+	plain := []byte(strings.Repeat("decoded content ", 64))
+	tests := []struct {
+		name    string
+		header  string
+		body    []byte
+		decoded []string
+		wantErr string // expected in DecodeError
+	}{
+		{name: "gzip", header: "gzip", body: encode(t, plain, "gzip"), decoded: []string{"gzip"}},
+		{name: "deflate", header: "deflate", body: encode(t, plain, "deflate"), decoded: []string{"deflate"}},
+		{name: "raw deflate", header: "deflate", body: encode(t, plain, "raw-deflate"), decoded: []string{"deflate"}},
+		{name: "br", header: "br", body: encode(t, plain, "br"), decoded: []string{"br"}},
+		{name: "zstd", header: "zstd", body: encode(t, plain, "zstd"), decoded: []string{"zstd"}},
+		{name: "stacked", header: "gzip, identity, zstd", body: encode(t, plain, "gzip", "zstd"), decoded: []string{"gzip", "zstd"}},
+		{name: "unsupported", header: "compress", body: plain, wantErr: "unsupported content coding"},
+		{name: "corrupt", header: "gzip", body: plain, wantErr: "gzip"},
+	}
+
+	sender := rawhttp.NewSender()
+	req := []byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := encodedResponse(tt.header, tt.body)
+			port := fixedResponseServer(t, raw)
+			opts := streamOpts(port)
+			opts.DecodeContent = true
+
+			resp, err := sender.Do(context.Background(), req, opts)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			defer resp.Raw.Close()
+
+			if !bytes.Equal(resp.Body.Bytes(), tt.body) || string(resp.Raw.Bytes()) != raw {
+				t.Error("Body and Raw must keep the bytes as received")
+			}
+			if strings.Join(resp.ContentDecoded, ",") != strings.Join(tt.decoded, ",") {
+				t.Errorf("expected ContentDecoded %v, got %v", tt.decoded, resp.ContentDecoded)
+			}
+			if tt.wantErr != "" {
+				if resp.DecodeError == nil || !strings.Contains(resp.DecodeError.Error(), tt.wantErr) {
+					t.Errorf("expected a DecodeError mentioning %q, got %v", tt.wantErr, resp.DecodeError)
+				}
+				return
+			}
+			if resp.DecodeError != nil {
+				t.Fatalf("unexpected DecodeError: %v", resp.DecodeError)
+			}
+			defer resp.DecodedBody.Close()
+			if !bytes.Equal(resp.DecodedBody.Bytes(), plain) {
+				t.Errorf("decoded body mismatch: %q", resp.DecodedBody.Bytes())
+			}
+		})
+	}
+}
+
+// Content-Encoding names that differ only in case are decoded in wire order.
+func TestDecodeContent_HeaderOrder(t *testing.T) {
+	plain := []byte(strings.Repeat("decoded content ", 64))
+	body := encode(t, plain, "gzip", "zstd")
+	opts := rawhttp.Options{DecodeContent: true}
+
+	for i := 0; i < 20; i++ {
+		resp := &rawhttp.Response{
+			Headers: map[string][]string{"Content-Encoding": {"gzip"}, "content-encoding": {"zstd"}},
+			HeaderList: []rawhttp.HeaderField{
+				{Name: "Content-Encoding", Value: "gzip"},
+				{Name: "content-encoding", Value: "zstd"},
+			},
+			Body: buffer.NewWithData(body),
+		}
+		client.DecodeResponse(resp, opts)
+		if got := strings.Join(resp.ContentDecoded, ","); got != "gzip,zstd" || resp.DecodeError != nil {
+			t.Fatalf("expected gzip,zstd decoded, got %q (%v)", got, resp.DecodeError)
+		}
+		if !bytes.Equal(resp.DecodedBody.Bytes(), plain) {
+			t.Fatalf("decoded body mismatch: %q", resp.DecodedBody.Bytes())
+		}
+		resp.DecodedBody.Close()
+	}
+}
+
+func TestDecodeContent_Disabled(t *testing.T) {
+	port := fixedResponseServer(t, encodedResponse("gzip", encode(t, []byte("x"), "gzip")))
+
+	resp, err := rawhttp.NewSender().Do(context.Background(), []byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n"), streamOpts(port))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	defer resp.Raw.Close()
+	if resp.DecodedBody != nil || resp.ContentDecoded != nil {
+		t.Error("nothing must be decoded without DecodeContent")
+	}
+}
+
+func TestDecodeContent_Bomb(t *testing.T) {
+	port := fixedResponseServer(t, encodedResponse("gzip", encode(t, make([]byte, 1<<20), "gzip")))
+	opts := streamOpts(port)
+	opts.DecodeContent = true
+	opts.MaxDecodedBytes = 1000
+
+	resp, err := rawhttp.NewSender().Do(context.Background(), []byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n"), opts)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	defer resp.Raw.Close()
+	if !rawhttp.IsLimitError(resp.DecodeError) {
+		t.Errorf("expected a limit error, got %v", resp.DecodeError)
+	}
+	if resp.DecodedBody == nil || resp.DecodedBody.Size() != 1000 {
+		t.Errorf("expected 1000 decoded bytes, got %v", resp.DecodedBody)
+	}
+}
+
+// A zstd frame declaring a huge window is refused before its window is
+// allocated, while an ordinary frame is decoded up to MaxDecodedBytes.
+func TestDecodeContent_ZstdWindow(t *testing.T) {
+	frame := func(window int) []byte {
+		var buf bytes.Buffer
+		w, _ := zstd.NewWriter(&buf, zstd.WithWindowSize(window))
+		w.Write(make([]byte, 16<<20))
+		w.Close()
+		return buf.Bytes()
+	}
+
+	for _, tt := range []struct {
+		name    string
+		window  int
+		decoded int64
+	}{
+		{name: "huge window", window: 256 << 20, decoded: 0},
+		{name: "8MB window", window: 8 << 20, decoded: 1000},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			port := fixedResponseServer(t, encodedResponse("zstd", frame(tt.window)))
+			opts := streamOpts(port)
+			opts.DecodeContent = true
+			opts.MaxDecodedBytes = 1000
+
+			var before, after runtime.MemStats
+			runtime.GC()
+			runtime.ReadMemStats(&before)
+			resp, err := rawhttp.NewSender().Do(context.Background(), []byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n"), opts)
+			runtime.ReadMemStats(&after)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			defer resp.Raw.Close()
+
+			if !rawhttp.IsLimitError(resp.DecodeError) {
+				t.Errorf("expected a limit error, got %v", resp.DecodeError)
+			}
+			if resp.DecodedBody == nil {
+				t.Fatal("expected a DecodedBody")
+			}
+			if got := resp.DecodedBody.Size(); got != tt.decoded {
+				t.Errorf("expected %d decoded bytes, got %d", tt.decoded, got)
+			}
+			if alloc := after.TotalAlloc - before.TotalAlloc; alloc > 64<<20 {
+				t.Errorf("decoding allocated %d MB", alloc>>20)
+			}
+		})
+	}
+}
+
+func TestDecodeContent_Stream(t *testing.T) {
+	plain := []byte(strings.Repeat("streamed ", 100))
+	port := fixedResponseServer(t, encodedResponse("br", encode(t, plain, "br")))
+	opts := streamOpts(port)
+	opts.DecodeContent = true
+
+	resp, err := rawhttp.NewSender().DoStream(context.Background(), []byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n"), opts)
+	if err != nil {
+		t.Fatalf("DoStream failed: %v", err)
+	}
+	defer resp.BodyStream.Close()
+
+	body, err := io.ReadAll(resp.BodyStream)
+	if err != nil {
+		t.Fatalf("reading the stream failed: %v", err)
+	}
+	if !bytes.Equal(body, plain) {
+		t.Errorf("decoded stream mismatch: %q", body)
+	}
+	if strings.Join(resp.ContentDecoded, ",") != "br" {
+		t.Errorf("expected ContentDecoded [br], got %v", resp.ContentDecoded)
+	}
+}
+
+func TestDecodeContent_HTTP2(t *testing.T) {
+	plain := []byte(strings.Repeat("h2 ", 100))
+	srv := newHTTP2Server(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "zstd")
+		w.Write(encode(t, plain, "zstd"))
+	})
+	defer srv.Close()
+
+	opts := h2Opts(srv)
+	opts.DecodeContent = true
+	resp, err := rawhttp.NewSender().Do(context.Background(), []byte("GET / HTTP/2\r\nHost: localhost\r\n\r\n"), opts)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.DecodedBody == nil || !bytes.Equal(resp.DecodedBody.Bytes(), plain) {
+		t.Errorf("expected the decoded body, got %v (DecodeError %v)", resp.DecodedBody, resp.DecodeError)
+	}
+}
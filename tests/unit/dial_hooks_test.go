@@ -0,0 +1,240 @@
+package unit
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/WhileEndless/go-rawhttp"
+)
+
+// staticResolver answers every lookup with one address and records the hosts.
+type staticResolver struct {
+	ip    string
+	mu    sync.Mutex
+	hosts []string
+}
+
+func (r *staticResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	r.mu.Lock()
+	r.hosts = append(r.hosts, host)
+	r.mu.Unlock()
+	return []net.IPAddr{{IP: net.ParseIP(r.ip)}}, nil
+}
+
+// pipeDialer serves every dialed connection in memory with serve and records the
+// dialed addresses.
+func pipeDialer(serve func(net.Conn)) (rawhttp.DialContextFunc, *[]string) {
+	var addrs []string
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		addrs = append(addrs, addr)
+		client, server := net.Pipe()
+		go serve(server)
+		return client, nil
+	}, &addrs
+}
+
+func TestDialHooks_HTTP1InMemory(t *testing.T) {
+	dial, addrs := pipeDialer(func(conn net.Conn) {
+		defer conn.Close()
+		if err := readRequestHead(bufio.NewReader(conn)); err != nil {
+			return
+		}
+		io.WriteString(conn, "HTTP/1.1 200 OK\r\nContent-Length: 6\r\nConnection: close\r\n\r\npiped!")
+	})
+	resolver := &staticResolver{ip: "10.1.2.3"}
+
+	opts := rawhttp.Options{
+		Scheme:      "http",
+		Host:        "service.internal",
+		Port:        8080,
+		DialContext: dial,
+		Resolver:    resolver,
+		ConnTimeout: 5 * time.Second,
+		ReadTimeout: 5 * time.Second,
+	}
+	resp, err := rawhttp.NewSender().Do(context.Background(), []byte("GET / HTTP/1.1\r\nHost: service.internal\r\n\r\n"), opts)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	defer resp.Raw.Close()
+
+	if body := resp.Body.Bytes(); string(body) != "piped!" {
+		t.Errorf("unexpected body %q", body)
+	}
+	if len(resolver.hosts) != 1 || resolver.hosts[0] != "service.internal" {
+		t.Errorf("expected the custom resolver to be used, got %v", resolver.hosts)
+	}
+	if len(*addrs) != 1 || (*addrs)[0] != "10.1.2.3:8080" {
+		t.Errorf("expected a dial to the resolved address, got %v", *addrs)
+	}
+}
+
+// The hooks also open the connection to an upstream proxy.
+func TestDialHooks_HTTPProxy(t *testing.T) {
+	var connectLine string
+	dial, addrs := pipeDialer(func(conn net.Conn) {
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		connectLine, _ = r.ReadString('\n')
+		if err := readRequestHead(r); err != nil {
+			return
+		}
+		io.WriteString(conn, "HTTP/1.1 200 Connection Established\r\n\r\n")
+		if err := readRequestHead(r); err != nil {
+			return
+		}
+		io.WriteString(conn, "HTTP/1.1 204 No Content\r\nConnection: close\r\n\r\n")
+	})
+	resolver := &staticResolver{ip: "192.0.2.10"}
+
+	opts := rawhttp.Options{
+		Scheme:      "http",
+		Host:        "target.internal",
+		Port:        80,
+		Proxy:       &rawhttp.ProxyConfig{Type: "http", Host: "proxy.internal", Port: 3128},
+		DialContext: dial,
+		Resolver:    resolver,
+		ConnTimeout: 5 * time.Second,
+		ReadTimeout: 5 * time.Second,
+	}
+	resp, err := rawhttp.NewSender().Do(context.Background(), []byte("GET / HTTP/1.1\r\nHost: target.internal\r\n\r\n"), opts)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	resp.Raw.Close()
+
+	if resp.StatusCode != 204 {
+		t.Errorf("expected 204, got %d", resp.StatusCode)
+	}
+	if len(*addrs) != 1 || (*addrs)[0] != "192.0.2.10:3128" {
+		t.Errorf("expected one dial to the resolved proxy, got %v", *addrs)
+	}
+	if !strings.HasPrefix(connectLine, "CONNECT ") {
+		t.Errorf("expected a CONNECT through the hooked connection, got %q", connectLine)
+	}
+}
+
+func TestDialHooks_HTTP2(t *testing.T) {
+	srv := newHTTP2Server(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "h2")
+	})
+	defer srv.Close()
+	port := srv.Listener.Addr().(*net.TCPAddr).Port
+
+	var (
+		mu     sync.Mutex
+		dialed []string
+	)
+	opts := h2Opts(srv)
+	opts.Host = "h2.internal"
+	opts.Resolver = &staticResolver{ip: "127.0.0.1"}
+	opts.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		mu.Lock()
+		dialed = append(dialed, addr)
+		mu.Unlock()
+		var d net.Dialer
+		return d.DialContext(ctx, network, addr)
+	}
+
+	resp, err := rawhttp.NewSender().Do(context.Background(), []byte("GET / HTTP/2\r\nHost: h2.internal\r\n\r\n"), opts)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	defer resp.Raw.Close()
+
+	if body := resp.Body.Bytes(); string(body) != "h2" {
+		t.Errorf("unexpected body %q", body)
+	}
+	want := "127.0.0.1:" + strconv.Itoa(port)
+	if len(dialed) != 1 || dialed[0] != want {
+		t.Errorf("expected one dial to %s, got %v", want, dialed)
+	}
+}
+
+// Connections dialed by one hook are never reused by a request with another.
+func TestDialHooks_PoolIsolation(t *testing.T) {
+	serve := func(conn net.Conn) {
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for readRequestHead(r) == nil {
+			io.WriteString(conn, "HTTP/1.1 204 No Content\r\n\r\n")
+		}
+	}
+	dialA, addrsA := pipeDialer(serve)
+	dialB, addrsB := pipeDialer(serve)
+
+	sender := rawhttp.NewSender()
+	req := []byte("GET / HTTP/1.1\r\nHost: service.internal\r\n\r\n")
+	for i, dial := range []rawhttp.DialContextFunc{dialA, dialA, dialB} {
+		opts := rawhttp.Options{
+			Scheme:          "http",
+			Host:            "127.0.0.1",
+			Port:            8080,
+			DialContext:     dial,
+			ReuseConnection: true,
+			ConnTimeout:     5 * time.Second,
+			ReadTimeout:     5 * time.Second,
+		}
+		resp, err := sender.Do(context.Background(), req, opts)
+		if err != nil {
+			t.Fatalf("request %d failed: %v", i, err)
+		}
+		resp.Body.Close()
+		resp.Raw.Close()
+		if want := i == 1; resp.ConnectionReused != want {
+			t.Errorf("request %d: ConnectionReused = %v, want %v", i, resp.ConnectionReused, want)
+		}
+	}
+	if len(*addrsA) != 1 || len(*addrsB) != 1 {
+		t.Errorf("expected one dial per hook, got %v and %v", *addrsA, *addrsB)
+	}
+}
+
+// The HTTP/2 pool keeps connections dialed by different hooks apart too.
+func TestDialHooks_HTTP2PoolIsolation(t *testing.T) {
+	srv := newHTTP2Server(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "h2")
+	})
+	defer srv.Close()
+
+	var mu sync.Mutex
+	dials := map[string]int{}
+	dialer := func(name string) rawhttp.DialContextFunc {
+		return func(ctx context.Context, network, addr string) (net.Conn, error) {
+			mu.Lock()
+			dials[name]++
+			mu.Unlock()
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		}
+	}
+	dialA, dialB := dialer("a"), dialer("b")
+
+	sender := rawhttp.NewSender()
+	req := []byte("GET / HTTP/2\r\nHost: localhost\r\n\r\n")
+	for i, dial := range []rawhttp.DialContextFunc{dialA, dialA, dialB} {
+		opts := h2Opts(srv)
+		opts.ReuseConnection = true
+		opts.DialContext = dial
+		resp, err := sender.Do(context.Background(), req, opts)
+		if err != nil {
+			t.Fatalf("request %d failed: %v", i, err)
+		}
+		resp.Body.Close()
+		resp.Raw.Close()
+	}
+	if dials["a"] != 1 || dials["b"] != 1 {
+		t.Errorf("expected one dial per hook, got %v", dials)
+	}
+}
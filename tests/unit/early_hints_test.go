@@ -0,0 +1,191 @@
+package unit
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/WhileEndless/go-rawhttp"
+)
+
+// earlyHintsHandler sends 102 Processing and 103 Early Hints before the final response.
+func earlyHintsHandler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusProcessing)
+	w.Header().Set("Link", "</style.css>; rel=preload; as=style")
+	w.WriteHeader(http.StatusEarlyHints)
+	time.Sleep(20 * time.Millisecond)
+	w.Header().Del("Link")
+	w.Header().Set("X-Final", "1")
+	w.WriteHeader(http.StatusOK)
+	io.WriteString(w, "final")
+}
+
+func checkEarlyHints(t *testing.T, resp *rawhttp.Response) {
+	t.Helper()
+	if resp.StatusCode != 200 || string(resp.Body.Bytes()) != "final" {
+		t.Fatalf("expected final 200 %q, got %d %q", "final", resp.StatusCode, resp.Body.Bytes())
+	}
+	if len(resp.Informational) != 2 {
+		t.Fatalf("expected 2 interim responses, got %+v", resp.Informational)
+	}
+	processing, hints := resp.Informational[0], resp.Informational[1]
+	if processing.StatusCode != 102 || hints.StatusCode != 103 {
+		t.Fatalf("expected interim 102 then 103, got %d then %d", processing.StatusCode, hints.StatusCode)
+	}
+	var link []string
+	for k, v := range hints.Headers {
+		if strings.EqualFold(k, "Link") {
+			link = v
+		}
+	}
+	if len(link) != 1 || !strings.Contains(link[0], "rel=preload") {
+		t.Errorf("expected Link header on 103, got %v", hints.Headers)
+	}
+	if hints.ReceivedAt.IsZero() || hints.ReceivedAt.Before(processing.ReceivedAt) {
+		t.Errorf("unexpected arrival times: 102 at %v, 103 at %v", processing.ReceivedAt, hints.ReceivedAt)
+	}
+	if !strings.Contains(hints.StatusLine, "103") {
+		t.Errorf("unexpected interim status line %q", hints.StatusLine)
+	}
+}
+
+func TestEarlyHints_HTTP1(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(earlyHintsHandler))
+	defer srv.Close()
+
+	port := srv.Listener.Addr().(*net.TCPAddr).Port
+	req := []byte("GET / HTTP/1.1\r\nHost: 127.0.0.1\r\n\r\n")
+	resp, err := rawhttp.NewSender().Do(context.Background(), req, streamOpts(port))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	defer resp.Raw.Close()
+
+	checkEarlyHints(t, resp)
+	if !strings.HasPrefix(string(resp.Raw.Bytes()), "HTTP/1.1 102 Processing\r\n") {
+		t.Errorf("interim responses not preserved in Raw: %q", resp.Raw.Bytes())
+	}
+}
+
+func TestEarlyHints_HTTP2(t *testing.T) {
+	srv := newHTTP2Server(earlyHintsHandler)
+	defer srv.Close()
+
+	req := []byte("GET / HTTP/2\r\nHost: localhost\r\n\r\n")
+	resp, err := rawhttp.NewSender().Do(context.Background(), req, h2Opts(srv))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	defer resp.Raw.Close()
+
+	checkEarlyHints(t, resp)
+	if got := resp.Headers["link"]; len(got) != 0 {
+		t.Errorf("interim headers leaked into the final response: %v", got)
+	}
+	raw := string(resp.Raw.Bytes())
+	if !strings.HasPrefix(raw, "HTTP/2 102 Processing\r\n") || !strings.Contains(raw, "HTTP/2 103 Early Hints\r\n") {
+		t.Errorf("interim responses missing from formatted Raw: %q", raw)
+	}
+}
+
+func TestEarlyHints_HTTP2Stream(t *testing.T) {
+	srv := newHTTP2Server(earlyHintsHandler)
+	defer srv.Close()
+
+	req := []byte("GET / HTTP/2\r\nHost: localhost\r\n\r\n")
+	resp, err := rawhttp.NewSender().DoStream(context.Background(), req, h2Opts(srv))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.BodyStream.Close()
+
+	body, err := io.ReadAll(resp.BodyStream)
+	if err != nil {
+		t.Fatalf("reading body: %v", err)
+	}
+	if resp.StatusCode != 200 || string(body) != "final" {
+		t.Fatalf("expected final 200 %q, got %d %q", "final", resp.StatusCode, body)
+	}
+	if len(resp.Informational) != 2 {
+		t.Errorf("expected 2 interim responses, got %+v", resp.Informational)
+	}
+}
+
+// An interim response followed by the server closing the connection is the last
+// response there is: it stands as the final one. A truncated status line after it
+// is still an error.
+func TestEarlyHints_HTTP1_CloseAfterInterim(t *testing.T) {
+	for _, tt := range []struct {
+		name, after string
+		wantErr     bool
+	}{
+		{"clean close", "", false},
+		{"truncated status line", "HTTP/1.1 2", true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			port := startRawServer(t, func(conn net.Conn) {
+				defer conn.Close()
+				readRequestHead(bufio.NewReader(conn))
+				io.WriteString(conn, "HTTP/1.1 102 Processing\r\n\r\nHTTP/1.1 103 Early Hints\r\nLink: </a.css>\r\n\r\n"+tt.after)
+			})
+
+			req := []byte("GET / HTTP/1.1\r\nHost: 127.0.0.1\r\n\r\n")
+			resp, err := rawhttp.NewSender().Do(context.Background(), req, streamOpts(port))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error for a truncated status line")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			defer resp.Raw.Close()
+
+			if resp.StatusCode != 103 || resp.BodyBytes != 0 {
+				t.Errorf("expected a final 103 without body, got %d with %d bytes", resp.StatusCode, resp.BodyBytes)
+			}
+			if len(resp.Informational) != 1 || resp.Informational[0].StatusCode != 102 {
+				t.Errorf("expected only 102 as interim, got %+v", resp.Informational)
+			}
+		})
+	}
+
+	// DoStream: the closed connection must not go back to the pool either.
+	t.Run("stream", func(t *testing.T) {
+		port := startRawServer(t, func(conn net.Conn) {
+			defer conn.Close()
+			readRequestHead(bufio.NewReader(conn))
+			io.WriteString(conn, "HTTP/1.1 102 Processing\r\n\r\nHTTP/1.1 103 Early Hints\r\nLink: </a.css>\r\n\r\n")
+		})
+
+		sender := rawhttp.NewSender()
+		opts := streamOpts(port)
+		opts.ReuseConnection = true
+		req := []byte("GET / HTTP/1.1\r\nHost: 127.0.0.1\r\n\r\n")
+		resp, err := sender.DoStream(context.Background(), req, opts)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		if body, err := io.ReadAll(resp.BodyStream); err != nil || len(body) != 0 {
+			t.Fatalf("expected an empty body, got %q (%v)", body, err)
+		}
+		resp.BodyStream.Close()
+
+		if resp.StatusCode != 103 {
+			t.Errorf("expected a final 103, got %d", resp.StatusCode)
+		}
+		if stats := sender.PoolStats(); stats.IdleConns != 0 {
+			t.Errorf("the closed connection was pooled: %d idle", stats.IdleConns)
+		}
+	})
+}
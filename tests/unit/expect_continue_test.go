@@ -0,0 +1,164 @@
+package unit
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/WhileEndless/go-rawhttp"
+)
+
+const expectRequest = "POST /upload HTTP/1.1\r\nHost: 127.0.0.1\r\nContent-Length: 5\r\nExpect: 100-continue\r\n\r\nhello"
+
+// bodyArrives reports whether any request body bytes arrive within wait.
+func bodyArrives(conn net.Conn, r *bufio.Reader, wait time.Duration) bool {
+	conn.SetReadDeadline(time.Now().Add(wait))
+	defer conn.SetReadDeadline(time.Time{})
+	_, err := r.Peek(1)
+	return err == nil
+}
+
+func TestExpectContinue_BodySentAfter100(t *testing.T) {
+	early := make(chan bool, 1)
+	port := startRawServer(t, func(conn net.Conn) {
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		if err := readRequestHead(r); err != nil {
+			return
+		}
+		early <- bodyArrives(conn, r, 200*time.Millisecond)
+		io.WriteString(conn, "HTTP/1.1 100 Continue\r\n\r\n")
+		body := make([]byte, 5)
+		if _, err := io.ReadFull(r, body); err != nil {
+			return
+		}
+		io.WriteString(conn, "HTTP/1.1 201 Created\r\nContent-Length: 5\r\n\r\n"+string(body))
+	})
+
+	opts := streamOpts(port)
+	opts.ExpectContinueTimeout = 5 * time.Second
+
+	resp, err := rawhttp.NewSender().Do(context.Background(), []byte(expectRequest), opts)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	defer resp.Raw.Close()
+
+	if <-early {
+		t.Error("body was sent before the server answered 100 Continue")
+	}
+	if resp.StatusCode != 201 || string(resp.Body.Bytes()) != "hello" {
+		t.Fatalf("unexpected final response: %d %q", resp.StatusCode, resp.Body.Bytes())
+	}
+	if len(resp.Informational) != 1 || resp.Informational[0].StatusCode != 100 {
+		t.Fatalf("expected one 100 interim response, got %+v", resp.Informational)
+	}
+	if !strings.HasPrefix(string(resp.Raw.Bytes()), "HTTP/1.1 100 Continue\r\n\r\nHTTP/1.1 201 Created\r\n") {
+		t.Errorf("interim response not preserved in Raw: %q", resp.Raw.Bytes())
+	}
+}
+
+func TestExpectContinue_FinalStatusAbortsBody(t *testing.T) {
+	sent := make(chan bool, 1)
+	port := startRawServer(t, func(conn net.Conn) {
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		if err := readRequestHead(r); err != nil {
+			return
+		}
+		io.WriteString(conn, "HTTP/1.1 417 Expectation Failed\r\nContent-Length: 0\r\n\r\n")
+		sent <- bodyArrives(conn, r, 300*time.Millisecond)
+	})
+
+	sender := rawhttp.NewSender()
+	opts := streamOpts(port)
+	opts.ExpectContinueTimeout = 5 * time.Second
+	opts.ReuseConnection = true
+
+	resp, err := sender.Do(context.Background(), []byte(expectRequest), opts)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	defer resp.Raw.Close()
+
+	if resp.StatusCode != 417 {
+		t.Fatalf("expected 417, got %d", resp.StatusCode)
+	}
+	if len(resp.Informational) != 0 {
+		t.Errorf("expected no interim responses, got %+v", resp.Informational)
+	}
+	if <-sent {
+		t.Error("body must not be sent after a final status")
+	}
+	if stats := sender.PoolStats(); stats.IdleConns != 0 {
+		t.Errorf("connection with an aborted request body must not be pooled, got %d idle", stats.IdleConns)
+	}
+}
+
+func TestExpectContinue_TimeoutSendsBody(t *testing.T) {
+	port := startRawServer(t, func(conn net.Conn) {
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		if err := readRequestHead(r); err != nil {
+			return
+		}
+		// Never answer 100: the client must send the body on its own.
+		body := make([]byte, 5)
+		if _, err := io.ReadFull(r, body); err != nil {
+			return
+		}
+		io.WriteString(conn, "HTTP/1.1 200 OK\r\nContent-Length: 5\r\n\r\n"+string(body))
+	})
+
+	opts := streamOpts(port)
+	opts.ExpectContinueTimeout = 100 * time.Millisecond
+
+	start := time.Now()
+	resp, err := rawhttp.NewSender().Do(context.Background(), []byte(expectRequest), opts)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	defer resp.Raw.Close()
+
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("body sent before the expect timeout elapsed (%v)", elapsed)
+	}
+	if resp.StatusCode != 200 || string(resp.Body.Bytes()) != "hello" {
+		t.Fatalf("unexpected response: %d %q", resp.StatusCode, resp.Body.Bytes())
+	}
+}
+
+func TestInterimResponses_RecordedWithoutHandshake(t *testing.T) {
+	port := startRawServer(t, func(conn net.Conn) {
+		defer conn.Close()
+		if err := readRequestHead(bufio.NewReader(conn)); err != nil {
+			return
+		}
+		io.WriteString(conn, "HTTP/1.1 100 Continue\r\n\r\n"+
+			"HTTP/1.1 102 Processing\r\n\r\n"+
+			"HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\nok")
+	})
+
+	req := []byte("GET / HTTP/1.1\r\nHost: 127.0.0.1\r\n\r\n")
+	resp, err := rawhttp.NewSender().Do(context.Background(), req, streamOpts(port))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	defer resp.Raw.Close()
+
+	if resp.StatusCode != 200 || string(resp.Body.Bytes()) != "ok" {
+		t.Fatalf("expected final 200 \"ok\", got %d %q", resp.StatusCode, resp.Body.Bytes())
+	}
+	if len(resp.Informational) != 2 ||
+		resp.Informational[0].StatusCode != 100 || resp.Informational[1].StatusCode != 102 {
+		t.Fatalf("expected interim 100 and 102, got %+v", resp.Informational)
+	}
+}
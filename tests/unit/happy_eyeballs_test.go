@@ -0,0 +1,279 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/WhileEndless/go-rawhttp"
+	"github.com/WhileEndless/go-rawhttp/pkg/transport"
+)
+
+// dualStackResolver answers every lookup with the same IPv6 and IPv4 addresses.
+type dualStackResolver []string
+
+func (r dualStackResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	addrs := make([]net.IPAddr, len(r))
+	for i, ip := range r {
+		addrs[i] = net.IPAddr{IP: net.ParseIP(ip)}
+	}
+	return addrs, nil
+}
+
+// familyResolver answers A and AAAA queries separately, each after a delay.
+type familyResolver struct {
+	v4, v6           string
+	v4Delay, v6Delay time.Duration
+}
+
+func (r familyResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	return nil, errors.New("unexpected combined lookup")
+}
+
+func (r familyResolver) LookupIP(ctx context.Context, network, host string) ([]net.IP, error) {
+	ip, delay := r.v4, r.v4Delay
+	if network == "ip6" {
+		ip, delay = r.v6, r.v6Delay
+	}
+	time.Sleep(delay)
+	return []net.IP{net.ParseIP(ip)}, nil
+}
+
+// brokenIPv6Dialer dials IPv4 addresses normally and handles IPv6 ones with
+// broken: nil blackholes them until the attempt is cancelled.
+func brokenIPv6Dialer(broken error) (rawhttp.DialContextFunc, func() []string) {
+	var (
+		mu     sync.Mutex
+		dialed []string
+	)
+	dial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		mu.Lock()
+		dialed = append(dialed, network+" "+addr)
+		mu.Unlock()
+		if strings.HasPrefix(addr, "[") {
+			if broken != nil {
+				return nil, broken
+			}
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		var d net.Dialer
+		return d.DialContext(ctx, network, addr)
+	}
+	return dial, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), dialed...)
+	}
+}
+
+func startPlainServer(t *testing.T) (*httptest.Server, int) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	return srv, srv.Listener.Addr().(*net.TCPAddr).Port
+}
+
+func sendDualStack(t *testing.T, opts rawhttp.Options) *rawhttp.Response {
+	t.Helper()
+	resp, err := rawhttp.NewSender().Do(context.Background(), []byte("GET / HTTP/1.1\r\nHost: dual.example\r\n\r\n"), opts)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	resp.Raw.Close()
+	return resp
+}
+
+// A blackholed IPv6 address costs one fallback delay, not the connection timeout.
+func TestHappyEyeballs_BlackholedIPv6(t *testing.T) {
+	srv, port := startPlainServer(t)
+	defer srv.Close()
+	dial, _ := brokenIPv6Dialer(nil)
+
+	start := time.Now()
+	resp := sendDualStack(t, rawhttp.Options{
+		Scheme:        "http",
+		Host:          "dual.example",
+		Port:          port,
+		Resolver:      dualStackResolver{"127.0.0.1", "2001:db8::1"},
+		DialContext:   dial,
+		HappyEyeballs: true,
+		FallbackDelay: 50 * time.Millisecond,
+		ConnTimeout:   10 * time.Second,
+		ReadTimeout:   5 * time.Second,
+	})
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("fallback to IPv4 took %v", elapsed)
+	}
+
+	if resp.ConnectedIP != "127.0.0.1" || resp.ConnectedPort != port {
+		t.Errorf("expected to be connected to 127.0.0.1:%d, got %s:%d", port, resp.ConnectedIP, resp.ConnectedPort)
+	}
+	attempts := resp.DialAttempts
+	if len(attempts) != 2 {
+		t.Fatalf("expected 2 dial attempts, got %+v", attempts)
+	}
+	v6, v4 := attempts[0], attempts[1]
+	if v6.Address != "[2001:db8::1]:"+strconv.Itoa(port) || v6.Network != "tcp6" || v6.Outcome != transport.DialCancelled {
+		t.Errorf("unexpected IPv6 attempt %+v", v6)
+	}
+	if v4.Address != "127.0.0.1:"+strconv.Itoa(port) || v4.Network != "tcp4" || v4.Outcome != transport.DialConnected || v4.Err != nil {
+		t.Errorf("unexpected IPv4 attempt %+v", v4)
+	}
+	if v4.Offset < 50*time.Millisecond {
+		t.Errorf("IPv4 attempt started after %v, before the fallback delay", v4.Offset)
+	}
+}
+
+// A failed attempt starts the next one immediately instead of waiting.
+func TestHappyEyeballs_FailedAttemptFallsBackImmediately(t *testing.T) {
+	srv, port := startPlainServer(t)
+	defer srv.Close()
+	refused := errors.New("network unreachable")
+	dial, _ := brokenIPv6Dialer(refused)
+
+	resp := sendDualStack(t, rawhttp.Options{
+		Scheme:        "http",
+		Host:          "dual.example",
+		Port:          port,
+		Resolver:      dualStackResolver{"2001:db8::1", "2001:db8::2", "127.0.0.1"},
+		DialContext:   dial,
+		HappyEyeballs: true,
+		FallbackDelay: 5 * time.Second,
+		ConnTimeout:   10 * time.Second,
+		ReadTimeout:   5 * time.Second,
+	})
+
+	var got []string
+	for _, a := range resp.DialAttempts {
+		got = append(got, a.Address+" "+a.Outcome)
+		if a.Outcome == transport.DialFailed && a.Err != refused {
+			t.Errorf("attempt %s: expected the dial error, got %v", a.Address, a.Err)
+		}
+	}
+	want := []string{
+		fmt.Sprintf("[2001:db8::1]:%d failed", port),
+		fmt.Sprintf("127.0.0.1:%d connected", port),
+	}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("expected attempts %v (families interleaved), got %v", want, got)
+	}
+	if last := resp.DialAttempts[len(resp.DialAttempts)-1]; last.Offset > time.Second {
+		t.Errorf("IPv4 attempt waited %v for the fallback delay", last.Offset)
+	}
+}
+
+func TestHappyEyeballs_ForcedNetwork(t *testing.T) {
+	srv, port := startPlainServer(t)
+	defer srv.Close()
+	dial, dialed := brokenIPv6Dialer(nil)
+
+	// tcp4 skips the IPv6 address even without HappyEyeballs
+	resp := sendDualStack(t, rawhttp.Options{
+		Scheme:      "http",
+		Host:        "dual.example",
+		Port:        port,
+		Resolver:    dualStackResolver{"2001:db8::1", "127.0.0.1"},
+		DialContext: dial,
+		Network:     "tcp4",
+		ConnTimeout: 5 * time.Second,
+		ReadTimeout: 5 * time.Second,
+	})
+	want := fmt.Sprintf("tcp4 127.0.0.1:%d", port)
+	if got := dialed(); len(got) != 1 || got[0] != want {
+		t.Errorf("expected only %q to be dialed, got %v", want, got)
+	}
+	if len(resp.DialAttempts) != 1 || resp.DialAttempts[0].Outcome != transport.DialConnected {
+		t.Errorf("expected one successful attempt, got %+v", resp.DialAttempts)
+	}
+
+	// tcp6 with only an IPv4 answer is a DNS error
+	_, err := rawhttp.NewSender().Do(context.Background(), []byte("GET / HTTP/1.1\r\nHost: dual.example\r\n\r\n"), rawhttp.Options{
+		Scheme:        "http",
+		Host:          "dual.example",
+		Port:          port,
+		Resolver:      dualStackResolver{"127.0.0.1"},
+		Network:       "tcp6",
+		HappyEyeballs: true,
+		ConnTimeout:   5 * time.Second,
+	})
+	if err == nil || !strings.Contains(err.Error(), "no tcp6 addresses") {
+		t.Errorf("expected a missing IPv6 address error, got %v", err)
+	}
+
+	_, err = rawhttp.NewSender().Do(context.Background(), []byte("GET / HTTP/1.1\r\nHost: dual.example\r\n\r\n"), rawhttp.Options{
+		Scheme:  "http",
+		Host:    "dual.example",
+		Port:    port,
+		Network: "udp",
+	})
+	if err == nil || !strings.Contains(err.Error(), "unsupported network") {
+		t.Errorf("expected an invalid network error, got %v", err)
+	}
+}
+
+// A and AAAA are queried in parallel; a late AAAA answer is only awaited for the
+// resolution delay.
+func TestHappyEyeballs_ResolutionDelay(t *testing.T) {
+	d := transport.Dialer{Resolver: familyResolver{v4: "192.0.2.1", v6: "2001:db8::1", v6Delay: 10 * time.Millisecond}}
+	ips, err := d.LookupDualStack(context.Background(), "tcp", "dual.example")
+	if err != nil {
+		t.Fatalf("lookup failed: %v", err)
+	}
+	if len(ips) != 2 || ips[0].String() != "2001:db8::1" || ips[1].String() != "192.0.2.1" {
+		t.Errorf("expected IPv6 then IPv4, got %v", ips)
+	}
+
+	d.Resolver = familyResolver{v4: "192.0.2.1", v6: "2001:db8::1", v6Delay: time.Second}
+	start := time.Now()
+	ips, err = d.LookupDualStack(context.Background(), "tcp", "dual.example")
+	if err != nil {
+		t.Fatalf("lookup failed: %v", err)
+	}
+	if len(ips) != 1 || ips[0].String() != "192.0.2.1" {
+		t.Errorf("expected only the IPv4 answer, got %v", ips)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("lookup waited %v for the late AAAA answer", elapsed)
+	}
+}
+
+func TestHappyEyeballs_HTTP2(t *testing.T) {
+	srv := newHTTP2Server(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("h2"))
+	})
+	defer srv.Close()
+	dial, _ := brokenIPv6Dialer(nil)
+
+	opts := h2Opts(srv)
+	opts.Host = "dual.example"
+	opts.Resolver = dualStackResolver{"2001:db8::1", "127.0.0.1"}
+	opts.DialContext = dial
+	opts.HappyEyeballs = true
+	opts.FallbackDelay = 20 * time.Millisecond
+
+	resp, err := rawhttp.NewSender().Do(context.Background(), []byte("GET / HTTP/2\r\nHost: dual.example\r\n\r\n"), opts)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	defer resp.Raw.Close()
+
+	if len(resp.DialAttempts) != 2 || resp.DialAttempts[0].Outcome != transport.DialCancelled ||
+		resp.DialAttempts[1].Outcome != transport.DialConnected {
+		t.Errorf("unexpected dial attempts %+v", resp.DialAttempts)
+	}
+	if resp.ConnectedIP != "127.0.0.1" {
+		t.Errorf("expected to be connected over IPv4, got %s", resp.ConnectedIP)
+	}
+}
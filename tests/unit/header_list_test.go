@@ -0,0 +1,102 @@
+package unit
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/WhileEndless/go-rawhttp"
+)
+
+func TestHeaderList_PreservesWireOrderAndMalformedLines(t *testing.T) {
+	port := startRawServer(t, func(conn net.Conn) {
+		defer conn.Close()
+		if err := readRequestHead(bufio.NewReader(conn)); err != nil {
+			return
+		}
+		io.WriteString(conn, "HTTP/1.1 200 OK\r\n"+
+			"x-LOWER: a\r\n"+
+			"Set-Cookie: one=1\r\n"+
+			"X-Folded: first\r\n"+
+			"\tsecond\r\n"+
+			"Set-Cookie: two=2\n"+
+			"no colon here\r\n"+
+			"Content-Length: 2\r\n"+
+			"\r\n"+
+			"ok")
+	})
+
+	req := []byte("GET / HTTP/1.1\r\nHost: 127.0.0.1\r\n\r\n")
+	resp, err := rawhttp.NewSender().Do(context.Background(), req, streamOpts(port))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	defer resp.Raw.Close()
+
+	want := []rawhttp.HeaderField{
+		{Name: "x-LOWER", Value: "a", RawLine: "x-LOWER: a\r\n"},
+		{Name: "Set-Cookie", Value: "one=1", RawLine: "Set-Cookie: one=1\r\n"},
+		{Name: "X-Folded", Value: "first", RawLine: "X-Folded: first\r\n"},
+		{Value: "second", RawLine: "\tsecond\r\n"},
+		{Name: "Set-Cookie", Value: "two=2", RawLine: "Set-Cookie: two=2\n"},
+		{Value: "no colon here", RawLine: "no colon here\r\n"},
+		{Name: "Content-Length", Value: "2", RawLine: "Content-Length: 2\r\n"},
+	}
+	if len(resp.HeaderList) != len(want) {
+		t.Fatalf("expected %d header lines, got %+v", len(want), resp.HeaderList)
+	}
+	for i, f := range want {
+		if resp.HeaderList[i] != f {
+			t.Errorf("line %d: expected %+v, got %+v", i, f, resp.HeaderList[i])
+		}
+	}
+
+	// The canonical map is unchanged.
+	if got := resp.Headers["X-Lower"]; len(got) != 1 || got[0] != "a" {
+		t.Errorf("expected canonical X-Lower in map, got %v", resp.Headers)
+	}
+	if got := resp.Headers["X-Folded"]; len(got) != 1 || got[0] != "firstsecond" {
+		t.Errorf("expected folded value in map, got %v", got)
+	}
+	if got := resp.Headers["Set-Cookie"]; len(got) != 2 {
+		t.Errorf("expected both Set-Cookie values in map, got %v", got)
+	}
+}
+
+func TestHeaderList_HTTP2(t *testing.T) {
+	srv := newHTTP2Server(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("X-Dup", "1")
+		w.Header().Add("X-Dup", "2")
+		io.WriteString(w, "ok")
+	})
+	defer srv.Close()
+
+	req := []byte("GET / HTTP/2\r\nHost: localhost\r\n\r\n")
+	resp, err := rawhttp.NewSender().Do(context.Background(), req, h2Opts(srv))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	defer resp.Raw.Close()
+
+	var dups []string
+	for _, f := range resp.HeaderList {
+		if strings.HasPrefix(f.Name, ":") {
+			t.Errorf("pseudo-header %q in HeaderList", f.Name)
+		}
+		if f.RawLine != f.Name+": "+f.Value+"\r\n" {
+			t.Errorf("unexpected RawLine %q for %s", f.RawLine, f.Name)
+		}
+		if f.Name == "x-dup" {
+			dups = append(dups, f.Value)
+		}
+	}
+	if len(dups) != 2 || dups[0] != "1" || dups[1] != "2" {
+		t.Errorf("expected x-dup 1 then 2, got %v", dups)
+	}
+}
@@ -0,0 +1,315 @@
+package http2_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	h2 "github.com/WhileEndless/go-rawhttp/pkg/http2"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+// A response header block split across HEADERS and CONTINUATION frames is
+// reassembled before HPACK decoding.
+func TestH2_Continuation_ResponseReassembled(t *testing.T) {
+	bigCookie := strings.Repeat("c", 50000)
+	srv := startRawH2Server(t, func(fr *http2.Framer) {
+		fr.WriteSettings()
+		for {
+			f, err := fr.ReadFrame()
+			if err != nil {
+				return
+			}
+			switch f := f.(type) {
+			case *http2.SettingsFrame:
+				if !f.IsAck() {
+					fr.WriteSettingsAck()
+				}
+			case *http2.HeadersFrame:
+				var buf bytes.Buffer
+				enc := hpack.NewEncoder(&buf)
+				enc.WriteField(hpack.HeaderField{Name: ":status", Value: "200"})
+				enc.WriteField(hpack.HeaderField{Name: "set-cookie", Value: "a=" + bigCookie})
+				enc.WriteField(hpack.HeaderField{Name: "set-cookie", Value: "b=2"})
+				block := buf.Bytes()
+				fr.WriteHeaders(http2.HeadersFrameParam{
+					StreamID:      f.StreamID,
+					BlockFragment: block[:1000],
+					EndStream:     true,
+				})
+				fr.WriteContinuation(f.StreamID, false, block[1000:20000])
+				fr.WriteContinuation(f.StreamID, true, block[20000:])
+			}
+		}
+	})
+
+	opts := h2TestOptions()
+	client := h2.NewClient(opts)
+	defer client.Close()
+
+	req := []byte("GET / HTTP/2\r\nHost: localhost\r\n\r\n")
+	resp, err := client.DoWithOptions(context.Background(), req, "localhost", srv.port, "https", opts)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.Status != 200 {
+		t.Fatalf("expected 200, got %d", resp.Status)
+	}
+	cookies := resp.Headers["set-cookie"]
+	if len(cookies) != 2 || cookies[0] != "a="+bigCookie || cookies[1] != "b=2" {
+		t.Fatalf("header block not reassembled correctly: got %d set-cookie values", len(cookies))
+	}
+}
+
+// A fingerprint that does not advertise SETTINGS_MAX_HEADER_LIST_SIZE still
+// leaves CONTINUATION reassembly bounded, by MaxHeaderListSize.
+func TestH2_Continuation_BoundedWithoutAdvertisedLimit(t *testing.T) {
+	sent := make(chan int, 1)
+	srv := startRawH2Server(t, func(fr *http2.Framer) {
+		fr.WriteSettings()
+		for {
+			f, err := fr.ReadFrame()
+			if err != nil {
+				return
+			}
+			switch f := f.(type) {
+			case *http2.SettingsFrame:
+				if !f.IsAck() {
+					fr.WriteSettingsAck()
+				}
+			case *http2.HeadersFrame:
+				fr.WriteHeaders(http2.HeadersFrameParam{StreamID: f.StreamID, BlockFragment: []byte{0x88}})
+				fragment := make([]byte, 16384)
+				total := 0
+				for ; total < 64<<20; total += len(fragment) {
+					if err := fr.WriteContinuation(f.StreamID, false, fragment); err != nil {
+						break
+					}
+				}
+				sent <- total
+				return
+			}
+		}
+	})
+
+	fp, ok := h2.Profile("firefox")
+	if !ok {
+		t.Fatal("firefox profile missing")
+	}
+	for _, s := range fp.Settings {
+		if s.ID == http2.SettingMaxHeaderListSize {
+			t.Fatal("the firefox profile advertises SETTINGS_MAX_HEADER_LIST_SIZE")
+		}
+	}
+	opts := h2TestOptions()
+	opts.Fingerprint = fp
+	opts.MaxHeaderListSize = 64 << 10
+	client := h2.NewClient(opts)
+	defer client.Close()
+
+	req := []byte("GET / HTTP/2\r\nHost: localhost\r\n\r\n")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := client.DoWithOptions(ctx, req, "localhost", srv.port, "https", opts)
+	if err == nil || !strings.Contains(err.Error(), "exceeds") {
+		t.Fatalf("expected the header block to exceed the bound, got %v", err)
+	}
+	if total := <-sent; total >= 64<<20 {
+		t.Errorf("client accepted %d bytes of CONTINUATION", total)
+	}
+}
+
+// A request header block larger than the peer's SETTINGS_MAX_FRAME_SIZE is split
+// into HEADERS and CONTINUATION frames.
+func TestH2_Continuation_RequestSplit(t *testing.T) {
+	const maxFrame = 16384
+	bigValue := strings.Repeat("v", 60000)
+
+	type result struct {
+		frames   int
+		oversize bool
+		value    string
+	}
+	got := make(chan result, 1)
+	srv := startRawH2Server(t, func(fr *http2.Framer) {
+		fr.WriteSettings()
+		dec := hpack.NewDecoder(4096, nil)
+		var block []byte
+		var r result
+		for {
+			f, err := fr.ReadFrame()
+			if err != nil {
+				return
+			}
+			if f.Header().Length > maxFrame {
+				r.oversize = true
+			}
+			switch f := f.(type) {
+			case *http2.SettingsFrame:
+				if !f.IsAck() {
+					fr.WriteSettingsAck()
+				}
+				continue
+			case *http2.HeadersFrame:
+				block = append(block[:0], f.HeaderBlockFragment()...)
+				r.frames = 1
+				if !f.HeadersEnded() {
+					continue
+				}
+			case *http2.ContinuationFrame:
+				block = append(block, f.HeaderBlockFragment()...)
+				r.frames++
+				if !f.HeadersEnded() {
+					continue
+				}
+			default:
+				continue
+			}
+			fields, err := dec.DecodeFull(block)
+			if err != nil {
+				return
+			}
+			for _, hf := range fields {
+				if hf.Name == "x-big" {
+					r.value = hf.Value
+				}
+			}
+			got <- r
+			writeResponse(fr, f.Header().StreamID, 200)
+		}
+	})
+
+	opts := h2TestOptions()
+	client := h2.NewClient(opts)
+	defer client.Close()
+
+	req := []byte("GET / HTTP/2\r\nHost: localhost\r\nX-Big: " + bigValue + "\r\n\r\n")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := client.DoWithOptions(ctx, req, "localhost", srv.port, "https", opts); err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+
+	r := <-got
+	if r.oversize {
+		t.Error("a frame exceeded SETTINGS_MAX_FRAME_SIZE")
+	}
+	if r.frames < 2 {
+		t.Errorf("expected HEADERS plus CONTINUATION frames, got %d frame(s)", r.frames)
+	}
+	if r.value != bigValue {
+		t.Errorf("server decoded x-big of %d bytes, want %d", len(r.value), len(bigValue))
+	}
+}
+
+func encodeBlock(t *testing.T, n int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	enc := hpack.NewEncoder(&buf)
+	enc.WriteField(hpack.HeaderField{Name: ":method", Value: "GET"})
+	for i := 0; i < n; i++ {
+		enc.WriteField(hpack.HeaderField{Name: "x-h" + strconv.Itoa(i), Value: strings.Repeat("z", 100)})
+	}
+	return buf.Bytes()
+}
+
+func TestRawFrameBuilder_HeaderBlock(t *testing.T) {
+	block := encodeBlock(t, 50)
+
+	t.Run("valid sequence", func(t *testing.T) {
+		raw := h2.NewRawFrameBuilder().BuildHeaderBlock(1, block, h2.ContinuationOptions{FragmentSize: 1000})
+		fr := http2.NewFramer(nil, bytes.NewReader(raw))
+		fr.ReadMetaHeaders = hpack.NewDecoder(4096, nil)
+		f, err := fr.ReadFrame()
+		if err != nil {
+			t.Fatalf("framer rejected a valid sequence: %v", err)
+		}
+		mh := f.(*http2.MetaHeadersFrame)
+		if len(mh.Fields) != 51 {
+			t.Errorf("expected 51 fields, got %d", len(mh.Fields))
+		}
+	})
+
+	// readAll reads frames until the framer stops, returning their headers and the
+	// terminating error.
+	readAll := func(raw []byte) ([]http2.FrameHeader, error) {
+		fr := http2.NewFramer(nil, bytes.NewReader(raw))
+		var headers []http2.FrameHeader
+		for {
+			f, err := fr.ReadFrame()
+			if err != nil {
+				return headers, err
+			}
+			headers = append(headers, f.Header())
+		}
+	}
+	fragments := (len(block) + 999) / 1000
+
+	for name, opts := range map[string]h2.ContinuationOptions{
+		"wrong stream": {FragmentSize: 1000, ContinuationStreamID: 3},
+		"interleaved":  {FragmentSize: 1000, Interleave: h2.NewRawFrameBuilder().BuildPingFrame([8]byte{}, false)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := readAll(h2.NewRawFrameBuilder().BuildHeaderBlock(1, block, opts))
+			if _, ok := err.(http2.ConnectionError); !ok {
+				t.Errorf("expected the framer to reject the sequence, got %v", err)
+			}
+		})
+	}
+
+	t.Run("unterminated", func(t *testing.T) {
+		raw := h2.NewRawFrameBuilder().BuildHeaderBlock(1, block, h2.ContinuationOptions{FragmentSize: 1000, OmitEndHeaders: true})
+		headers, _ := readAll(raw)
+		if len(headers) != fragments {
+			t.Fatalf("expected %d frames, got %d", fragments, len(headers))
+		}
+		for _, fh := range headers {
+			if fh.Flags.Has(http2.FlagHeadersEndHeaders) {
+				t.Errorf("END_HEADERS set on %v", fh)
+			}
+		}
+	})
+
+	t.Run("empty continuations", func(t *testing.T) {
+		raw := h2.NewRawFrameBuilder().BuildHeaderBlock(1, block, h2.ContinuationOptions{FragmentSize: 1000, EmptyContinuations: 3})
+		headers, err := readAll(raw)
+		if len(headers) != fragments+3 {
+			t.Fatalf("expected %d frames, got %d (%v)", fragments+3, len(headers), err)
+		}
+		last := headers[len(headers)-1]
+		if last.Length != 0 || !last.Flags.Has(http2.FlagContinuationEndHeaders) {
+			t.Errorf("expected an empty final CONTINUATION with END_HEADERS, got %v", last)
+		}
+	})
+}
+
+// FrameHandler reassembles CONTINUATION frames up to MaxHeaderListSize.
+func TestFrameHandler_ContinuationBound(t *testing.T) {
+	block := encodeBlock(t, 50)
+	raw := h2.NewRawFrameBuilder().BuildHeaderBlock(1, block, h2.ContinuationOptions{FragmentSize: 1000})
+	handler := func() *h2.FrameHandler {
+		return h2.NewFrameHandler(struct {
+			io.Reader
+			io.Writer
+		}{bytes.NewReader(raw), io.Discard})
+	}
+
+	f, err := handler().ReadFrame()
+	if err != nil {
+		t.Fatalf("reading the header block: %v", err)
+	}
+	if hf, ok := f.(*h2.HeadersFrame); !ok || len(hf.Fields) != 51 {
+		t.Fatalf("expected a HEADERS frame with 51 fields, got %+v", f)
+	}
+
+	bounded := handler()
+	bounded.MaxHeaderListSize = 2000
+	if _, err := bounded.ReadFrame(); err == nil || !strings.Contains(err.Error(), "exceeds 2000 bytes") {
+		t.Errorf("expected the block to exceed the bound, got %v", err)
+	}
+}
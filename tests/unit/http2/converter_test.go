@@ -126,6 +126,32 @@ func TestTextToFrames(t *testing.T) {
 				}
 			},
 		},
+		{
+			name:     "Chunked request trailers become a trailing HEADERS frame",
+			input:    []byte("POST /rpc HTTP/2\r\nHost: example.com\r\nTE: trailers\r\nTransfer-Encoding: chunked\r\n\r\n5\r\nhello\r\n6\r\n world\r\n0\r\nGrpc-Status: 0\r\n\r\n"),
+			streamID: 9,
+			wantErr:  false,
+			validate: func(t *testing.T, frames []http2.Frame) {
+				if len(frames) != 3 {
+					t.Fatalf("expected HEADERS, DATA, HEADERS; got %d frames", len(frames))
+				}
+				hf := frames[0].(*http2.HeadersFrame)
+				if hf.EndStream {
+					t.Error("request HEADERS must not end the stream")
+				}
+				if hf.Headers["te"] != "trailers" {
+					t.Errorf("expected te: trailers to be kept, got %v", hf.Headers)
+				}
+				df := frames[1].(*http2.DataFrame)
+				if string(df.Data) != "hello world" || df.EndStream {
+					t.Errorf("expected de-chunked body without END_STREAM, got %q (end=%v)", df.Data, df.EndStream)
+				}
+				tf := frames[2].(*http2.HeadersFrame)
+				if !tf.EndStream || tf.Headers["grpc-status"] != "0" {
+					t.Errorf("expected trailers with END_STREAM, got %v (end=%v)", tf.Headers, tf.EndStream)
+				}
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,209 @@
+package http2_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	h2 "github.com/WhileEndless/go-rawhttp/pkg/http2"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+// clientPreface is what the server saw of the client before its first request.
+type clientPreface struct {
+	settings     []http2.Setting
+	windowUpdate uint32
+	priorities   []http2.PriorityFrame
+	headers      *http2.HeadersFrame
+	fields       []string
+}
+
+// The preface follows the profile's SETTINGS order, WINDOW_UPDATE and PRIORITY
+// frames, and the request HEADERS its pseudo-header order, header order and
+// priority.
+func TestH2_Fingerprint_Preface(t *testing.T) {
+	seen := make(chan clientPreface, 1)
+	srv := startRawH2Server(t, func(fr *http2.Framer) {
+		fr.WriteSettings()
+		var p clientPreface
+		dec := hpack.NewDecoder(4096, nil)
+		for {
+			f, err := fr.ReadFrame()
+			if err != nil {
+				return
+			}
+			switch f := f.(type) {
+			case *http2.SettingsFrame:
+				if f.IsAck() {
+					continue
+				}
+				f.ForeachSetting(func(s http2.Setting) error {
+					p.settings = append(p.settings, s)
+					return nil
+				})
+				fr.WriteSettingsAck()
+			case *http2.WindowUpdateFrame:
+				if f.StreamID == 0 && p.windowUpdate == 0 {
+					p.windowUpdate = f.Increment
+				}
+			case *http2.PriorityFrame:
+				p.priorities = append(p.priorities, *f)
+			case *http2.HeadersFrame:
+				fields, err := dec.DecodeFull(f.HeaderBlockFragment())
+				if err != nil {
+					return
+				}
+				for _, hf := range fields {
+					p.fields = append(p.fields, hf.Name)
+				}
+				p.headers = f
+				seen <- p
+				writeResponse(fr, f.StreamID, 200)
+			}
+		}
+	})
+
+	fp, err := h2.ParseAkamaiFingerprint("1:65536;4:131072;2:0;5:16384|12517377|3:0:0:201,5:1:3:101|m,p,a,s")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	fp.HeaderPriority = &h2.PriorityParam{StreamDependency: 5, Weight: 41}
+	fp.HeaderOrder = []string{"user-agent", "accept"}
+
+	opts := h2TestOptions()
+	opts.Fingerprint = fp
+	client := h2.NewClient(opts)
+	defer client.Close()
+
+	req := []byte("GET / HTTP/2\r\nHost: localhost\r\nX-Custom: 1\r\nAccept: */*\r\nX-Other: 2\r\nUser-Agent: test\r\n\r\n")
+	if _, err := client.DoWithOptions(context.Background(), req, "localhost", srv.port, "https", opts); err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	p := <-seen
+
+	if !reflect.DeepEqual(p.settings, fp.Settings) {
+		t.Errorf("expected SETTINGS %v, got %v", fp.Settings, p.settings)
+	}
+	if p.windowUpdate != 12517377 {
+		t.Errorf("expected WINDOW_UPDATE 12517377, got %d", p.windowUpdate)
+	}
+	if len(p.priorities) != 2 || p.priorities[0].StreamID != 3 || p.priorities[0].Weight != 200 ||
+		p.priorities[1].StreamID != 5 || !p.priorities[1].Exclusive || p.priorities[1].StreamDep != 3 {
+		t.Errorf("unexpected PRIORITY frames %+v", p.priorities)
+	}
+
+	if p.headers.StreamID != 7 {
+		t.Errorf("expected the request above the PRIORITY streams (7), got stream %d", p.headers.StreamID)
+	}
+	if !p.headers.HasPriority() || p.headers.Priority.StreamDep != 5 || p.headers.Priority.Weight != 41 {
+		t.Errorf("unexpected HEADERS priority %+v", p.headers.Priority)
+	}
+	want := []string{":method", ":path", ":authority", ":scheme", "user-agent", "accept", "x-custom", "x-other"}
+	if !reflect.DeepEqual(p.fields, want) {
+		t.Errorf("expected header order %v, got %v", want, p.fields)
+	}
+}
+
+// Without a profile regular headers keep the order of the raw request.
+func TestH2_HeaderOrder_FollowsRawRequest(t *testing.T) {
+	got := make(chan []string, 1)
+	srv := startRawH2Server(t, func(fr *http2.Framer) {
+		fr.WriteSettings()
+		dec := hpack.NewDecoder(4096, nil)
+		for {
+			f, err := fr.ReadFrame()
+			if err != nil {
+				return
+			}
+			switch f := f.(type) {
+			case *http2.SettingsFrame:
+				if !f.IsAck() {
+					fr.WriteSettingsAck()
+				}
+			case *http2.HeadersFrame:
+				fields, _ := dec.DecodeFull(f.HeaderBlockFragment())
+				var names []string
+				for _, hf := range fields {
+					names = append(names, hf.Name)
+				}
+				got <- names
+				writeResponse(fr, f.StreamID, 200)
+			}
+		}
+	})
+
+	client := h2.NewClient(h2TestOptions())
+	defer client.Close()
+
+	req := []byte("GET / HTTP/2\r\nZ-First: 1\r\nHost: localhost\r\nA-Second: 2\r\nM-Third: 3\r\n\r\n")
+	if _, err := client.DoWithOptions(context.Background(), req, "localhost", srv.port, "https", h2TestOptions()); err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	want := []string{":method", ":path", ":scheme", ":authority", "z-first", "a-second", "m-third"}
+	if names := <-got; !reflect.DeepEqual(names, want) {
+		t.Errorf("expected %v, got %v", want, names)
+	}
+}
+
+func TestParseAkamaiFingerprint(t *testing.T) {
+	valid := []string{
+		"1:65536;2:0;4:6291456;6:262144|15663105|0|m,a,s,p",
+		"1:65536;4:131072;5:16384|12517377|3:0:0:201,5:0:0:101,7:0:0:1|m,p,a,s",
+		"|0|0|m,s,p,a",
+	}
+	for _, s := range valid {
+		fp, err := h2.ParseAkamaiFingerprint(s)
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", s, err)
+			continue
+		}
+		if fp.String() != s {
+			t.Errorf("%q: round trip gave %q", s, fp.String())
+		}
+	}
+
+	invalid := []string{
+		"",
+		"1:65536|0|0",
+		"1=65536|0|0|m,a,s,p",
+		"4:4294967295|0|0|m,a,s,p", // INITIAL_WINDOW_SIZE above 2^31-1
+		"1:65536|-1|0|m,a,s,p",
+		"1:65536|0|3:2:0:16|m,a,s,p",
+		"1:65536|0|3:0:0:257|m,a,s,p",
+		"1:65536|0|0|m,a,s",
+		"1:65536|0|0|m,a,s,s",
+		"1:65536|0|0|m,a,s,x",
+	}
+	for _, s := range invalid {
+		if _, err := h2.ParseAkamaiFingerprint(s); err == nil {
+			t.Errorf("%q: expected an error", s)
+		}
+	}
+}
+
+func TestFingerprintProfiles(t *testing.T) {
+	for _, name := range []string{"chrome", "firefox", "safari"} {
+		fp, err := h2.ParseFingerprint(name)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(fp.Settings) == 0 || fp.WindowUpdate == 0 || fp.HeaderPriority == nil || len(fp.HeaderOrder) == 0 {
+			t.Errorf("%s: incomplete profile %+v", name, fp)
+		}
+		// Profiles are copies
+		fp.HeaderOrder[0] = "x-changed"
+		if again, _ := h2.Profile(name); again.HeaderOrder[0] == "x-changed" {
+			t.Errorf("%s: profile shares its header order", name)
+		}
+	}
+
+	chrome, _ := h2.Profile("Chrome")
+	if got := chrome.String(); !strings.HasSuffix(got, "|m,a,s,p") {
+		t.Errorf("unexpected chrome fingerprint %q", got)
+	}
+	if _, ok := h2.Profile("netscape"); ok {
+		t.Error("expected no profile for an unknown name")
+	}
+}
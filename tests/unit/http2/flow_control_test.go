@@ -0,0 +1,310 @@
+package http2_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	h2 "github.com/WhileEndless/go-rawhttp/pkg/http2"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+// rawH2Server is a minimal frame-level HTTP/2 server over TLS for tests that need
+// to control exactly which frames the peer sends.
+type rawH2Server struct {
+	ln   net.Listener
+	port int
+}
+
+func startRawH2Server(t *testing.T, serve func(fr *http2.Framer)) *rawH2Server {
+	t.Helper()
+
+	// Borrow httptest's self-signed certificate.
+	certSrv := httptest.NewUnstartedServer(nil)
+	certSrv.StartTLS()
+	cfg := certSrv.TLS.Clone()
+	certSrv.Close()
+	cfg.NextProtos = []string{"h2"}
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", cfg)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				preface := make([]byte, len(http2.ClientPreface))
+				if _, err := io.ReadFull(conn, preface); err != nil {
+					return
+				}
+				serve(http2.NewFramer(conn, conn))
+			}()
+		}
+	}()
+	return &rawH2Server{ln: ln, port: ln.Addr().(*net.TCPAddr).Port}
+}
+
+// writeResponse sends a complete header-only response on a stream.
+func writeResponse(fr *http2.Framer, streamID uint32, status int, extra ...hpack.HeaderField) error {
+	var buf bytes.Buffer
+	enc := hpack.NewEncoder(&buf)
+	enc.WriteField(hpack.HeaderField{Name: ":status", Value: strconv.Itoa(status)})
+	for _, f := range extra {
+		enc.WriteField(f)
+	}
+	return fr.WriteHeaders(http2.HeadersFrameParam{
+		StreamID:      streamID,
+		BlockFragment: buf.Bytes(),
+		EndHeaders:    true,
+		EndStream:     true,
+	})
+}
+
+func uploadRequest(size int) []byte {
+	body := strings.Repeat("x", size)
+	return []byte(fmt.Sprintf("POST /upload HTTP/2\r\nHost: localhost\r\nContent-Length: %d\r\n\r\n%s", size, body))
+}
+
+// The client must never send more DATA than the peer granted, must split frames at
+// the peer's SETTINGS_MAX_FRAME_SIZE, and must resume when WINDOW_UPDATEs arrive.
+func TestH2_SendFlowControl_RespectsWindows(t *testing.T) {
+	const (
+		streamGrant = 1000
+		maxFrame    = 16384
+		bodySize    = 200000
+	)
+
+	violation := make(chan string, 1)
+	srv := startRawH2Server(t, func(fr *http2.Framer) {
+		fr.WriteSettings(
+			http2.Setting{ID: http2.SettingInitialWindowSize, Val: streamGrant},
+			http2.Setting{ID: http2.SettingMaxFrameSize, Val: maxFrame},
+		)
+		streamCredit, connCredit := int64(streamGrant), int64(65535)
+		received := 0
+		for {
+			f, err := fr.ReadFrame()
+			if err != nil {
+				return
+			}
+			switch f := f.(type) {
+			case *http2.SettingsFrame:
+				if !f.IsAck() {
+					fr.WriteSettingsAck()
+				}
+			case *http2.DataFrame:
+				n := int64(len(f.Data()))
+				if n > maxFrame || n > streamCredit || n > connCredit {
+					violation <- fmt.Sprintf("DATA of %d bytes with stream credit %d, conn credit %d", n, streamCredit, connCredit)
+					return
+				}
+				streamCredit -= n
+				connCredit -= n
+				received += int(n)
+				if streamCredit == 0 {
+					fr.WriteWindowUpdate(f.StreamID, streamGrant)
+					streamCredit += streamGrant
+				}
+				if connCredit < streamGrant {
+					fr.WriteWindowUpdate(0, 65535)
+					connCredit += 65535
+				}
+				if f.StreamEnded() {
+					writeResponse(fr, f.StreamID, 200,
+						hpack.HeaderField{Name: "x-received", Value: strconv.Itoa(received)})
+				}
+			}
+		}
+	})
+
+	opts := h2TestOptions()
+	client := h2.NewClient(opts)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	resp, err := client.DoWithOptions(ctx, uploadRequest(bodySize), "localhost", srv.port, "https", opts)
+	select {
+	case v := <-violation:
+		t.Fatalf("flow-control violation: %s", v)
+	default:
+	}
+	if err != nil {
+		t.Fatalf("upload failed: %v", err)
+	}
+	if resp.Status != 200 {
+		t.Fatalf("expected 200, got %d", resp.Status)
+	}
+	if got := resp.Headers["x-received"]; len(got) != 1 || got[0] != strconv.Itoa(bodySize) {
+		t.Fatalf("server received %v bytes, want %d", got, bodySize)
+	}
+}
+
+// A server that answers before the body is complete (and never grants more credit)
+// must not leave the writer blocked: the early response is returned.
+func TestH2_SendFlowControl_EarlyResponse(t *testing.T) {
+	srv := startRawH2Server(t, func(fr *http2.Framer) {
+		fr.WriteSettings(http2.Setting{ID: http2.SettingInitialWindowSize, Val: 100})
+		for {
+			f, err := fr.ReadFrame()
+			if err != nil {
+				return
+			}
+			switch f := f.(type) {
+			case *http2.SettingsFrame:
+				if !f.IsAck() {
+					fr.WriteSettingsAck()
+				}
+			case *http2.HeadersFrame:
+				writeResponse(fr, f.StreamID, 413)
+			}
+		}
+	})
+
+	opts := h2TestOptions()
+	client := h2.NewClient(opts)
+	defer client.Close()
+
+	resp, err := client.DoWithOptions(context.Background(), uploadRequest(50000), "localhost", srv.port, "https", opts)
+	if err != nil {
+		t.Fatalf("expected the early response, got error: %v", err)
+	}
+	if resp.Status != 413 {
+		t.Fatalf("expected 413, got %d", resp.Status)
+	}
+}
+
+// A writer starved of credit gives up after ReadTimeout instead of hanging.
+func TestH2_SendFlowControl_StarvedWriterTimesOut(t *testing.T) {
+	srv := startRawH2Server(t, func(fr *http2.Framer) {
+		fr.WriteSettings(http2.Setting{ID: http2.SettingInitialWindowSize, Val: 10})
+		for {
+			f, err := fr.ReadFrame()
+			if err != nil {
+				return
+			}
+			if sf, ok := f.(*http2.SettingsFrame); ok && !sf.IsAck() {
+				fr.WriteSettingsAck()
+			}
+		}
+	})
+
+	opts := h2TestOptions()
+	opts.ReadTimeout = 300 * time.Millisecond
+	client := h2.NewClient(opts)
+	defer client.Close()
+
+	start := time.Now()
+	_, err := client.DoWithOptions(context.Background(), uploadRequest(1000), "localhost", srv.port, "https", opts)
+	if err == nil {
+		t.Fatal("expected a timeout waiting for send window")
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Fatalf("starved writer took %v to give up", elapsed)
+	}
+}
+
+// Uploads larger than the default 64KB window succeed against a real server.
+func TestH2_SendFlowControl_LargeUpload(t *testing.T) {
+	srv := startH2Server(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := io.Copy(io.Discard, r.Body)
+		fmt.Fprint(w, n)
+	})
+	defer srv.Close()
+
+	opts := h2TestOptions()
+	client := h2.NewClient(opts)
+	defer client.Close()
+
+	const size = 4 << 20
+	port := srv.Listener.Addr().(*net.TCPAddr).Port
+	resp, err := client.DoWithOptions(context.Background(), uploadRequest(size), "localhost", port, "https", opts)
+	if err != nil {
+		t.Fatalf("upload failed: %v", err)
+	}
+	if string(resp.Body) != strconv.Itoa(size) {
+		t.Fatalf("server received %s bytes, want %d", resp.Body, size)
+	}
+}
+
+// A BodyStream nobody reads holds back only its own stream: the read loop keeps
+// serving other requests on the same connection, and the unread body is
+// throttled by its stream window instead of buffered.
+func TestH2_SlowBodyStream_DoesNotStallConnection(t *testing.T) {
+	const size = 16 << 20
+	chunk := bytes.Repeat([]byte("x"), 32<<10)
+	srv := startH2Server(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/big" {
+			w.Write([]byte("ok"))
+			return
+		}
+		for sent := 0; sent < size; sent += len(chunk) {
+			if _, err := w.Write(chunk); err != nil {
+				return
+			}
+		}
+	})
+	defer srv.Close()
+	port := srv.Listener.Addr().(*net.TCPAddr).Port
+
+	opts := h2TestOptions()
+	opts.MaxConnsPerHost = 1
+	opts.InitialWindowSize = 65535
+	client := h2.NewClient(opts)
+	defer client.Close()
+
+	big, err := client.DoStreamWithOptions(context.Background(),
+		[]byte("GET /big HTTP/2\r\nHost: localhost\r\n\r\n"), "localhost", port, "https", opts)
+	if err != nil {
+		t.Fatalf("stream request failed: %v", err)
+	}
+	defer big.BodyStream.Close()
+	time.Sleep(200 * time.Millisecond) // let the server fill the stream window
+
+	for i := 0; i < 3; i++ {
+		resp, err := client.DoWithOptions(context.Background(),
+			[]byte("GET /small HTTP/2\r\nHost: localhost\r\n\r\n"), "localhost", port, "https", opts)
+		if err != nil {
+			t.Fatalf("request %d behind the unread stream failed: %v", i, err)
+		}
+		if !resp.ConnectionReused || string(resp.Body) != "ok" {
+			t.Fatalf("request %d: reused=%v body=%q", i, resp.ConnectionReused, resp.Body)
+		}
+	}
+
+	n, err := io.Copy(io.Discard, big.BodyStream)
+	if err != nil || n != size {
+		t.Fatalf("read %d bytes of the streamed body (err %v), want %d", n, err, size)
+	}
+
+	// The frames of a streamed body keep their lengths, not their payloads.
+	recorded := 0
+	for _, f := range big.Frames {
+		if df, ok := f.(*h2.DataFrame); ok {
+			if df.Data != nil {
+				t.Fatalf("streamed DATA frame kept its %d-byte payload", len(df.Data))
+			}
+			recorded += df.Length
+		}
+	}
+	if recorded != size {
+		t.Errorf("DATA frame lengths add up to %d, want %d", recorded, size)
+	}
+}
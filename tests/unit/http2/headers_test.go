@@ -0,0 +1,163 @@
+package http2_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/WhileEndless/go-rawhttp/pkg/errors"
+	h2 "github.com/WhileEndless/go-rawhttp/pkg/http2"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+func TestDecodeHeaderFields_PreservesDuplicatesAndOrder(t *testing.T) {
+	want := []hpack.HeaderField{
+		{Name: ":status", Value: "200"},
+		{Name: "set-cookie", Value: "a=1"},
+		{Name: "x-z", Value: "last-alphabetically"},
+		{Name: "set-cookie", Value: "b=2"},
+	}
+	var buf bytes.Buffer
+	enc := hpack.NewEncoder(&buf)
+	for _, f := range want {
+		enc.WriteField(f)
+	}
+
+	got, err := h2.NewConverter().DecodeHeaderFields(buf.Bytes())
+	if err != nil {
+		t.Fatalf("DecodeHeaderFields() error = %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d fields, got %v", len(want), got)
+	}
+	for i := range want {
+		if got[i].Name != want[i].Name || got[i].Value != want[i].Value {
+			t.Errorf("field %d: expected %v, got %v", i, want[i], got[i])
+		}
+	}
+}
+
+func TestH2_Response_DuplicateAndOrderedHeaders(t *testing.T) {
+	srv := startRawH2Server(t, func(fr *http2.Framer) {
+		fr.WriteSettings()
+		for {
+			f, err := fr.ReadFrame()
+			if err != nil {
+				return
+			}
+			switch f := f.(type) {
+			case *http2.SettingsFrame:
+				if !f.IsAck() {
+					fr.WriteSettingsAck()
+				}
+			case *http2.HeadersFrame:
+				writeResponse(fr, f.StreamID, 200,
+					hpack.HeaderField{Name: "set-cookie", Value: "a=1"},
+					hpack.HeaderField{Name: "x-zeta", Value: "z"},
+					hpack.HeaderField{Name: "set-cookie", Value: "b=2"},
+					hpack.HeaderField{Name: "x-alpha", Value: "a"},
+				)
+			}
+		}
+	})
+
+	opts := h2TestOptions()
+	client := h2.NewClient(opts)
+	defer client.Close()
+
+	req := []byte("GET / HTTP/2\r\nHost: localhost\r\n\r\n")
+	resp, err := client.DoWithOptions(context.Background(), req, "localhost", srv.port, "https", opts)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+
+	if got := resp.Headers["set-cookie"]; len(got) != 2 || got[0] != "a=1" || got[1] != "b=2" {
+		t.Errorf("expected both set-cookie values in order, got %v", got)
+	}
+
+	var names []string
+	for _, f := range resp.HeaderList {
+		names = append(names, f.Name)
+	}
+	if got, want := strings.Join(names, ","), ":status,set-cookie,x-zeta,set-cookie,x-alpha"; got != want {
+		t.Errorf("HeaderList order = %s, want %s", got, want)
+	}
+
+	formatted := string(client.FormatResponse(resp))
+	wantHead := "HTTP/2 200 OK\r\nSet-Cookie: a=1\r\nX-Zeta: z\r\nSet-Cookie: b=2\r\nX-Alpha: a\r\n\r\n"
+	if formatted != wantHead {
+		t.Errorf("FormatResponse() = %q, want %q", formatted, wantHead)
+	}
+}
+
+// Header limits are enforced while the block is decoded: a small block of
+// references to one large table entry fails with a limit error without
+// materializing the expanded list, and the connection's HPACK state stays in sync
+// for the next response.
+func TestH2_HeaderLimits_EnforcedWhileDecoding(t *testing.T) {
+	big := hpack.HeaderField{Name: "x-big", Value: strings.Repeat("v", 3000)}
+	srv := startRawH2Server(t, func(fr *http2.Framer) {
+		fr.WriteSettings()
+		var buf bytes.Buffer
+		enc := hpack.NewEncoder(&buf)
+		for {
+			f, err := fr.ReadFrame()
+			if err != nil {
+				return
+			}
+			switch f := f.(type) {
+			case *http2.SettingsFrame:
+				if !f.IsAck() {
+					fr.WriteSettingsAck()
+				}
+			case *http2.HeadersFrame:
+				buf.Reset()
+				enc.WriteField(hpack.HeaderField{Name: ":status", Value: "200"})
+				repeat := 1
+				if f.StreamID == 1 {
+					repeat = 5000 // one literal, then 1-byte references: ~15 MB expanded
+				}
+				for i := 0; i < repeat; i++ {
+					enc.WriteField(big)
+				}
+				fr.WriteHeaders(http2.HeadersFrameParam{
+					StreamID:      f.StreamID,
+					BlockFragment: buf.Bytes(),
+					EndHeaders:    true,
+					EndStream:     true,
+				})
+			}
+		}
+	})
+
+	client := h2.NewClient(h2TestOptions())
+	defer client.Close()
+	req := []byte("GET / HTTP/2\r\nHost: localhost\r\n\r\n")
+
+	opts := h2TestOptions()
+	opts.MaxHeaderBytes = 64 << 10
+	resp, err := client.DoWithOptions(context.Background(), req, "localhost", srv.port, "https", opts)
+	if !errors.IsLimitError(err) {
+		t.Fatalf("expected a MaxHeaderBytes limit error, got %v", err)
+	}
+	if resp != nil {
+		size := 0
+		for _, f := range resp.HeaderList {
+			size += int(f.Size())
+		}
+		if size > opts.MaxHeaderBytes {
+			t.Errorf("kept %d header fields (%d bytes) past the limit", len(resp.HeaderList), size)
+		}
+	}
+
+	resp, err = client.DoWithOptions(context.Background(), req, "localhost", srv.port, "https", h2TestOptions())
+	if err != nil {
+		t.Fatalf("request after the limit error failed: %v", err)
+	}
+	if !resp.ConnectionReused || resp.Headers["x-big"][0] != big.Value {
+		t.Errorf("expected the table entry decoded on the reused connection, got reused=%v headers %v",
+			resp.ConnectionReused, len(resp.Headers["x-big"]))
+	}
+}
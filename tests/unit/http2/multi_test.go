@@ -0,0 +1,160 @@
+package http2_test
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	h2 "github.com/WhileEndless/go-rawhttp/pkg/http2"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+type recordedFrame struct {
+	streamID  uint32
+	kind      string
+	data      string
+	endStream bool
+}
+
+// startRecordingServer answers every stream once its request has ended (with its
+// stream ID in x-stream) and records the request frames it received.
+func startRecordingServer(t *testing.T) (*rawH2Server, *int32, func() []recordedFrame) {
+	var (
+		conns  int32
+		mu     sync.Mutex
+		frames []recordedFrame
+	)
+	srv := startRawH2Server(t, func(fr *http2.Framer) {
+		atomic.AddInt32(&conns, 1)
+		fr.WriteSettings()
+		for {
+			f, err := fr.ReadFrame()
+			if err != nil {
+				return
+			}
+			var rec recordedFrame
+			switch f := f.(type) {
+			case *http2.SettingsFrame:
+				if !f.IsAck() {
+					fr.WriteSettingsAck()
+				}
+				continue
+			case *http2.HeadersFrame:
+				rec = recordedFrame{streamID: f.StreamID, kind: "HEADERS", endStream: f.StreamEnded()}
+			case *http2.DataFrame:
+				rec = recordedFrame{streamID: f.StreamID, kind: "DATA", data: string(f.Data()), endStream: f.StreamEnded()}
+			default:
+				continue
+			}
+			mu.Lock()
+			frames = append(frames, rec)
+			mu.Unlock()
+			if rec.endStream {
+				writeResponse(fr, rec.streamID, 200,
+					hpack.HeaderField{Name: "x-stream", Value: strconv.Itoa(int(rec.streamID))})
+			}
+		}
+	})
+	return srv, &conns, func() []recordedFrame {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]recordedFrame(nil), frames...)
+	}
+}
+
+func TestH2_DoMulti_SharesOneConnection(t *testing.T) {
+	srv, conns, _ := startRecordingServer(t)
+
+	opts := h2TestOptions()
+	client := h2.NewClient(opts)
+	defer client.Close()
+
+	reqs := [][]byte{
+		[]byte("GET /a HTTP/2\r\nHost: localhost\r\n\r\n"),
+		[]byte("not a request"),
+		[]byte("GET /b HTTP/2\r\nHost: localhost\r\n\r\n"),
+		uploadRequest(10),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	results, err := client.DoMulti(ctx, reqs, "localhost", srv.port, "https", opts)
+	if err != nil {
+		t.Fatalf("DoMulti failed: %v", err)
+	}
+	if len(results) != len(reqs) {
+		t.Fatalf("expected %d results, got %d", len(reqs), len(results))
+	}
+	if got := atomic.LoadInt32(conns); got != 1 {
+		t.Fatalf("expected 1 connection, got %d", got)
+	}
+
+	if results[1].Err == nil || results[1].StreamID != 0 {
+		t.Errorf("expected the malformed request to fail alone, got %+v", results[1])
+	}
+	seen := map[uint32]bool{}
+	for _, i := range []int{0, 2, 3} {
+		r := results[i]
+		if r.Index != i || r.Err != nil {
+			t.Fatalf("result %d: index %d, err %v", i, r.Index, r.Err)
+		}
+		if r.StreamID == 0 || seen[r.StreamID] {
+			t.Fatalf("result %d: stream ID %d not unique", i, r.StreamID)
+		}
+		seen[r.StreamID] = true
+		if got := r.Response.Headers["x-stream"]; len(got) != 1 || got[0] != strconv.Itoa(int(r.StreamID)) {
+			t.Errorf("result %d: response from stream %v, want %d", i, got, r.StreamID)
+		}
+		if r.SentAt.IsZero() || r.ReceivedAt.Before(r.SentAt) || r.Response.Metrics == nil {
+			t.Errorf("result %d: missing per-stream timings: %+v", i, r)
+		}
+	}
+}
+
+// In single-packet mode every request is held back by its final frame, and all
+// final frames reach the server together, after everything else.
+func TestH2_DoMulti_SinglePacket(t *testing.T) {
+	srv, _, recorded := startRecordingServer(t)
+
+	opts := h2TestOptions()
+	opts.SinglePacket = true
+	client := h2.NewClient(opts)
+	defer client.Close()
+
+	reqs := [][]byte{
+		uploadRequest(3),
+		[]byte("GET / HTTP/2\r\nHost: localhost\r\n\r\n"),
+		uploadRequest(1),
+	}
+	results, err := client.DoMulti(context.Background(), reqs, "localhost", srv.port, "https", opts)
+	if err != nil {
+		t.Fatalf("DoMulti failed: %v", err)
+	}
+	for _, r := range results {
+		if r.Err != nil {
+			t.Fatalf("request %d failed: %v", r.Index, r.Err)
+		}
+		if !r.SentAt.Equal(results[0].SentAt) {
+			t.Errorf("request %d released at %v, want %v", r.Index, r.SentAt, results[0].SentAt)
+		}
+	}
+
+	frames := recorded()
+	tail := frames[len(frames)-len(reqs):]
+	for i, f := range tail {
+		if !f.endStream || f.kind != "DATA" || f.streamID != results[i].StreamID {
+			t.Fatalf("expected the final DATA of every stream last, got %+v", frames)
+		}
+	}
+	if tail[0].data != "x" || tail[1].data != "" || tail[2].data != "x" {
+		t.Errorf("unexpected final frames %+v", tail)
+	}
+	for _, f := range frames[:len(frames)-len(reqs)] {
+		if f.endStream {
+			t.Fatalf("stream %d ended before the release: %+v", f.streamID, frames)
+		}
+	}
+}
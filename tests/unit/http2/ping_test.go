@@ -0,0 +1,88 @@
+package http2_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/WhileEndless/go-rawhttp/pkg/errors"
+	h2 "github.com/WhileEndless/go-rawhttp/pkg/http2"
+	"golang.org/x/net/http2"
+)
+
+// pingServer completes the SETTINGS handshake and acknowledges PINGs when ack is set.
+func pingServer(t *testing.T, ack bool) *rawH2Server {
+	return startRawH2Server(t, func(fr *http2.Framer) {
+		fr.WriteSettings()
+		for {
+			f, err := fr.ReadFrame()
+			if err != nil {
+				return
+			}
+			switch f := f.(type) {
+			case *http2.SettingsFrame:
+				if !f.IsAck() {
+					fr.WriteSettingsAck()
+				}
+			case *http2.PingFrame:
+				if ack && !f.IsAck() {
+					fr.WritePing(true, f.Data)
+				}
+			}
+		}
+	})
+}
+
+func TestH2_Ping_MeasuresRTT(t *testing.T) {
+	srv := pingServer(t, true)
+	opts := h2TestOptions()
+	tr := h2.NewTransport(opts)
+	defer tr.Close()
+
+	conn, err := tr.Connect(context.Background(), "localhost", srv.port, "https", opts)
+	if err != nil {
+		t.Fatalf("connect failed: %v", err)
+	}
+	if conn.RTT() != 0 {
+		t.Fatalf("expected no RTT before the first PING, got %v", conn.RTT())
+	}
+
+	rtt, err := conn.Ping(context.Background())
+	if err != nil {
+		t.Fatalf("ping failed: %v", err)
+	}
+	if rtt <= 0 || conn.RTT() != rtt {
+		t.Errorf("expected a positive RTT recorded on the connection, got %v (RTT() %v)", rtt, conn.RTT())
+	}
+	for _, cs := range tr.GetPoolStats().Connections {
+		if cs.RTT != rtt {
+			t.Errorf("expected pool stats RTT %v, got %v", rtt, cs.RTT)
+		}
+	}
+
+	conn.Close()
+	if _, err := conn.Ping(context.Background()); err == nil {
+		t.Error("expected an error pinging a closed connection")
+	}
+}
+
+func TestH2_Ping_Timeout(t *testing.T) {
+	srv := pingServer(t, false)
+	opts := h2TestOptions()
+	tr := h2.NewTransport(opts)
+	defer tr.Close()
+
+	conn, err := tr.Connect(context.Background(), "localhost", srv.port, "https", opts)
+	if err != nil {
+		t.Fatalf("connect failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := conn.Ping(ctx); !errors.IsTimeoutError(err) {
+		t.Fatalf("expected a timeout error, got %v", err)
+	}
+	if conn.RTT() != 0 {
+		t.Errorf("an unanswered PING must not record an RTT, got %v", conn.RTT())
+	}
+}
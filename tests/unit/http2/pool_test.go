@@ -0,0 +1,48 @@
+package http2_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+
+	h2 "github.com/WhileEndless/go-rawhttp/pkg/http2"
+)
+
+// Requests to the same origin with different TLS options never share a connection.
+func TestH2_Pool_KeyedByTLSOptions(t *testing.T) {
+	srv := startH2Server(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	defer srv.Close()
+	port := srv.Listener.Addr().(*net.TCPAddr).Port
+
+	client := h2.NewClient(h2TestOptions())
+	defer client.Close()
+
+	req := []byte("GET / HTTP/2\r\nHost: localhost\r\n\r\n")
+	var reused []bool
+	for _, sni := range []string{"a.example", "b.example", "a.example"} {
+		opts := h2TestOptions()
+		opts.SNI = sni
+		resp, err := client.DoWithOptions(context.Background(), req, "localhost", port, "https", opts)
+		if err != nil {
+			t.Fatalf("request with SNI %q failed: %v", sni, err)
+		}
+		reused = append(reused, resp.ConnectionReused)
+	}
+	if reused[0] || reused[1] || !reused[2] {
+		t.Errorf("expected only the third request to reuse a connection, got %v", reused)
+	}
+
+	stats := client.GetPoolStats()
+	if stats.ActiveConnections != 2 {
+		t.Fatalf("expected 2 pooled connections, got %d", stats.ActiveConnections)
+	}
+	for name, cs := range stats.Connections {
+		if cs.Address != cs.Key.String() || name != fmt.Sprintf("%s#%d", cs.Address, cs.ID) || cs.Key.ALPN != "h2,http/1.1" || cs.Key.Verification != "insecure" {
+			t.Errorf("connection %q has unexpected key %+v", name, cs.Key)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package http2_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/WhileEndless/go-rawhttp/pkg/errors"
+	h2 "github.com/WhileEndless/go-rawhttp/pkg/http2"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+// pushHandler pushes /style.css and /app.js with the response to /.
+func pushHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/":
+		if p, ok := w.(http.Pusher); ok {
+			p.Push("/style.css", nil)
+			p.Push("/app.js", &http.PushOptions{Header: http.Header{"X-Push": {"1"}}})
+		}
+		io.WriteString(w, "index")
+	case "/style.css":
+		w.Header().Set("Content-Type", "text/css")
+		io.WriteString(w, "body{}")
+	case "/app.js":
+		io.WriteString(w, strings.Repeat("a", 200000))
+	}
+}
+
+func TestH2_ServerPush_Received(t *testing.T) {
+	srv := startH2Server(pushHandler)
+	defer srv.Close()
+
+	opts := h2TestOptions()
+	opts.DisableServerPush = false
+	client := h2.NewClient(opts)
+	defer client.Close()
+
+	port := srv.Listener.Addr().(*net.TCPAddr).Port
+	req := []byte("GET / HTTP/2\r\nHost: localhost\r\n\r\n")
+	resp, err := client.DoWithOptions(context.Background(), req, "localhost", port, "https", opts)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if string(resp.Body) != "index" {
+		t.Fatalf("unexpected body %q", resp.Body)
+	}
+	if len(resp.ServerPush) != 2 {
+		t.Fatalf("expected 2 pushes, got %d", len(resp.ServerPush))
+	}
+
+	bodies := map[string]string{}
+	for _, pp := range resp.ServerPush {
+		if pp.Err != nil || pp.Response == nil {
+			t.Fatalf("push %d failed: %v", pp.PromisedStreamID, pp.Err)
+		}
+		if pp.PromisedStreamID%2 != 0 {
+			t.Errorf("promised stream %d must be server-initiated (even)", pp.PromisedStreamID)
+		}
+		if pp.Response.Status != 200 || pp.Response.TotalTime <= 0 {
+			t.Errorf("push %s: status %d, total time %v", pp.Headers[":path"], pp.Response.Status, pp.Response.TotalTime)
+		}
+		bodies[pp.Headers[":path"]] = string(pp.Response.Body)
+		if pp.Headers[":path"] == "/app.js" && pp.Headers["x-push"] != "1" {
+			t.Errorf("promised request headers not decoded: %v", pp.Headers)
+		}
+	}
+	if bodies["/style.css"] != "body{}" || len(bodies["/app.js"]) != 200000 {
+		t.Errorf("unexpected pushed bodies: css %q, js %d bytes", bodies["/style.css"], len(bodies["/app.js"]))
+	}
+
+	// The connection stays usable and no pushed stream is left registered.
+	resp, err = client.DoWithOptions(context.Background(), []byte("GET /style.css HTTP/2\r\nHost: localhost\r\n\r\n"), "localhost", port, "https", opts)
+	if err != nil || string(resp.Body) != "body{}" {
+		t.Fatalf("follow-up request: %v %q", err, resp.Body)
+	}
+	if stats := client.GetPoolStats(); stats.TotalStreams != 0 {
+		t.Errorf("expected no active streams, got %d", stats.TotalStreams)
+	}
+}
+
+func TestH2_ServerPush_DisabledByDefault(t *testing.T) {
+	srv := startH2Server(pushHandler)
+	defer srv.Close()
+
+	opts := h2TestOptions()
+	client := h2.NewClient(opts)
+	defer client.Close()
+
+	port := srv.Listener.Addr().(*net.TCPAddr).Port
+	resp, err := client.DoWithOptions(context.Background(), []byte("GET / HTTP/2\r\nHost: localhost\r\n\r\n"), "localhost", port, "https", opts)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if len(resp.ServerPush) != 0 {
+		t.Errorf("expected no pushes with push disabled, got %d", len(resp.ServerPush))
+	}
+}
+
+// A pushed stream the server never ends must not hold a complete response: it is
+// cancelled ReadTimeout after the response ended, even while DATA trickles in.
+func TestH2_ServerPush_UnfinishedPushBounded(t *testing.T) {
+	srv := startRawH2Server(t, func(fr *http2.Framer) {
+		fr.WriteSettings()
+		for {
+			f, err := fr.ReadFrame()
+			if err != nil {
+				return
+			}
+			switch f := f.(type) {
+			case *http2.SettingsFrame:
+				if !f.IsAck() {
+					fr.WriteSettingsAck()
+				}
+			case *http2.HeadersFrame:
+				var buf bytes.Buffer
+				enc := hpack.NewEncoder(&buf)
+				enc.WriteField(hpack.HeaderField{Name: ":method", Value: "GET"})
+				enc.WriteField(hpack.HeaderField{Name: ":scheme", Value: "https"})
+				enc.WriteField(hpack.HeaderField{Name: ":authority", Value: "localhost"})
+				enc.WriteField(hpack.HeaderField{Name: ":path", Value: "/endless"})
+				fr.WritePushPromise(http2.PushPromiseParam{
+					StreamID:      f.StreamID,
+					PromiseID:     2,
+					BlockFragment: buf.Bytes(),
+					EndHeaders:    true,
+				})
+				buf.Reset()
+				enc.WriteField(hpack.HeaderField{Name: ":status", Value: "200"})
+				fr.WriteHeaders(http2.HeadersFrameParam{StreamID: 2, BlockFragment: buf.Bytes(), EndHeaders: true})
+				writeResponse(fr, f.StreamID, 200)
+				go func() {
+					for i := 0; i < 100; i++ {
+						if fr.WriteData(2, false, []byte("x")) != nil {
+							return
+						}
+						time.Sleep(50 * time.Millisecond)
+					}
+				}()
+			}
+		}
+	})
+
+	opts := h2TestOptions()
+	opts.DisableServerPush = false
+	opts.ReadTimeout = 300 * time.Millisecond
+	client := h2.NewClient(opts)
+	defer client.Close()
+
+	start := time.Now()
+	req := []byte("GET / HTTP/2\r\nHost: localhost\r\n\r\n")
+	resp, err := client.DoWithOptions(context.Background(), req, "localhost", srv.port, "https", opts)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("response held for %v by an unfinished push", elapsed)
+	}
+	if resp.Status != 200 || len(resp.ServerPush) != 1 {
+		t.Fatalf("expected a 200 with one push, got %d with %d", resp.Status, len(resp.ServerPush))
+	}
+	if pp := resp.ServerPush[0]; pp.Response != nil || !errors.IsTimeoutError(pp.Err) {
+		t.Errorf("expected the push to time out, got response %v, err %v", pp.Response, pp.Err)
+	}
+}
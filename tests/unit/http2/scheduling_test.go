@@ -0,0 +1,124 @@
+package http2_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	h2 "github.com/WhileEndless/go-rawhttp/pkg/http2"
+)
+
+// limitedServer advertises SETTINGS_MAX_CONCURRENT_STREAMS = 1 and holds every
+// response until release is closed. It reports each request as it arrives and
+// counts the connections it accepted.
+func limitedServer(t *testing.T, release <-chan struct{}) (srv *httptest.Server, arrived chan struct{}, conns *int32) {
+	t.Helper()
+	arrived = make(chan struct{}, 16)
+	conns = new(int32)
+	srv = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		arrived <- struct{}{}
+		<-release
+		w.Write([]byte("ok"))
+	}))
+	srv.EnableHTTP2 = true
+	srv.Config.HTTP2 = &http.HTTP2Config{MaxConcurrentStreams: 1}
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(conns, 1)
+		}
+	}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv, arrived, conns
+}
+
+// Saturated connections make the pool dial more, up to MaxConnsPerHost; beyond
+// that requests wait for a free stream slot instead of exceeding the peer's limit.
+func TestH2_Scheduling_SpreadsOverConnections(t *testing.T) {
+	release := make(chan struct{})
+	srv, arrived, conns := limitedServer(t, release)
+	port := srv.Listener.Addr().(*net.TCPAddr).Port
+
+	opts := h2TestOptions()
+	opts.MaxConnsPerHost = 2
+	client := h2.NewClient(opts)
+	defer client.Close()
+
+	req := []byte("GET / HTTP/2\r\nHost: localhost\r\n\r\n")
+	var wg sync.WaitGroup
+	errs := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := client.DoWithOptions(context.Background(), req, "localhost", port, "https", opts)
+			if err == nil && resp.Status != 200 {
+				t.Errorf("expected 200, got %d", resp.Status)
+			}
+			errs <- err
+		}()
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-arrived:
+		case <-time.After(3 * time.Second):
+			t.Fatal("expected two requests in flight")
+		}
+	}
+	select {
+	case <-arrived:
+		t.Fatal("a third request must wait for a free stream slot")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	stats := client.GetPoolStats()
+	if stats.ActiveConnections != 2 {
+		t.Fatalf("expected 2 pooled connections, got %d", stats.ActiveConnections)
+	}
+	for name, cs := range stats.Connections {
+		if cs.StreamsMax != 1 || cs.Load != 1 {
+			t.Errorf("connection %q: expected load 1 of 1, got %d of %d", name, cs.Load, cs.StreamsMax)
+		}
+	}
+
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("request failed: %v", err)
+		}
+	}
+	if n := atomic.LoadInt32(conns); n != 2 {
+		t.Errorf("expected 2 connections to the server, got %d", n)
+	}
+}
+
+// A waiting request gives up with its context.
+func TestH2_Scheduling_WaitHonorsContext(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	srv, arrived, _ := limitedServer(t, release)
+	port := srv.Listener.Addr().(*net.TCPAddr).Port
+
+	opts := h2TestOptions()
+	opts.MaxConnsPerHost = 1
+	client := h2.NewClient(opts)
+	defer client.Close()
+
+	req := []byte("GET / HTTP/2\r\nHost: localhost\r\n\r\n")
+	go client.DoWithOptions(context.Background(), req, "localhost", port, "https", opts)
+	<-arrived
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := client.DoWithOptions(ctx, req, "localhost", port, "https", opts); err == nil {
+		t.Fatal("expected the waiting request to fail with its context")
+	}
+}
@@ -0,0 +1,85 @@
+package http2_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	h2 "github.com/WhileEndless/go-rawhttp/pkg/http2"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+// The server's handshake frames and SETTINGS (mid-connection updates included)
+// are recorded on the response, and its SETTINGS_HEADER_TABLE_SIZE is honored by
+// the HPACK encoder.
+func TestH2_PeerSettings_Recorded(t *testing.T) {
+	preface := []http2.Setting{
+		{ID: http2.SettingHeaderTableSize, Val: 0},
+		{ID: http2.SettingMaxConcurrentStreams, Val: 7},
+		{ID: http2.SettingInitialWindowSize, Val: 1 << 20},
+	}
+	srv := startRawH2Server(t, func(fr *http2.Framer) {
+		fr.WriteSettings(preface...)
+		fr.WriteWindowUpdate(0, 12345)
+
+		// A decoder without dynamic table fails on any reference to an entry the
+		// client should not have indexed.
+		dec := hpack.NewDecoder(0, nil)
+		requests := 0
+		for {
+			f, err := fr.ReadFrame()
+			if err != nil {
+				return
+			}
+			switch f := f.(type) {
+			case *http2.SettingsFrame:
+				if !f.IsAck() {
+					fr.WriteSettingsAck()
+				}
+			case *http2.HeadersFrame:
+				if _, err := dec.DecodeFull(f.HeaderBlockFragment()); err != nil {
+					fr.WriteRSTStream(f.StreamID, http2.ErrCodeCompression)
+					continue
+				}
+				if requests++; requests == 2 {
+					fr.WriteSettings(http2.Setting{ID: http2.SettingMaxConcurrentStreams, Val: 9})
+				}
+				writeResponse(fr, f.StreamID, 200)
+			}
+		}
+	})
+
+	client := h2.NewClient(h2TestOptions())
+	defer client.Close()
+
+	req := []byte("GET / HTTP/2\r\nHost: localhost\r\nX-Token: abcdef\r\n\r\n")
+	var resps []*h2.Response
+	for i := 0; i < 2; i++ {
+		resp, err := client.DoWithOptions(context.Background(), req, "localhost", srv.port, "https", h2TestOptions())
+		if err != nil {
+			t.Fatalf("request %d failed: %v", i+1, err)
+		}
+		resps = append(resps, resp)
+	}
+
+	p := resps[0].PeerPreface
+	if p == nil || !reflect.DeepEqual(p.Settings, preface) || p.WindowUpdate != 12345 {
+		t.Fatalf("unexpected peer preface %+v", p)
+	}
+	var frames []http2.FrameType
+	for _, h := range p.Frames {
+		frames = append(frames, h.Type)
+	}
+	want := []http2.FrameType{http2.FrameSettings, http2.FrameWindowUpdate, http2.FrameSettings}
+	if !reflect.DeepEqual(frames, want) || !p.Frames[2].Flags.Has(http2.FlagSettingsAck) {
+		t.Errorf("expected handshake frames %v ending with the ACK, got %v", want, p.Frames)
+	}
+
+	if got := resps[0].PeerSettings[http2.SettingMaxConcurrentStreams]; got != 7 {
+		t.Errorf("expected MAX_CONCURRENT_STREAMS 7 on the first response, got %d", got)
+	}
+	if got := resps[1].PeerSettings[http2.SettingMaxConcurrentStreams]; got != 9 {
+		t.Errorf("expected the updated MAX_CONCURRENT_STREAMS 9, got %d", got)
+	}
+}
@@ -0,0 +1,154 @@
+package unit
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/WhileEndless/go-rawhttp"
+)
+
+func TestLimits_HTTP1(t *testing.T) {
+	head := "HTTP/1.1 200 OK\r\nX-A: 1\r\nX-B: 2\r\nX-C: 3\r\n"
+	tests := []struct {
+		name     string
+		response string
+		limit    func(*rawhttp.Options)
+		body     string // body read up to the limit
+		headers  int    // header fields read up to the limit
+	}{
+		{
+			name:     "header count",
+			response: head + "Content-Length: 2\r\n\r\nok",
+			limit:    func(o *rawhttp.Options) { o.MaxHeaderCount = 2 },
+			headers:  2,
+		},
+		{
+			name:     "header bytes",
+			response: head + "X-Long: " + strings.Repeat("x", 200) + "\r\n\r\n",
+			limit:    func(o *rawhttp.Options) { o.MaxHeaderBytes = 100 },
+			headers:  3,
+		},
+		{
+			name:     "fixed body",
+			response: head + "Content-Length: 10\r\n\r\n0123456789",
+			limit:    func(o *rawhttp.Options) { o.MaxBodyBytes = 4 },
+			body:     "0123",
+			headers:  4,
+		},
+		{
+			name:     "chunked body",
+			response: head + "Transfer-Encoding: chunked\r\n\r\n3\r\n012\r\n3\r\n345\r\n0\r\n\r\n",
+			limit:    func(o *rawhttp.Options) { o.MaxBodyBytes = 4 },
+			body:     "0123",
+			headers:  4,
+		},
+		{
+			name:     "chunk line",
+			response: head + "Transfer-Encoding: chunked\r\n\r\n3\r\n012\r\n3;" + strings.Repeat("x", 100) + "\r\n345\r\n0\r\n\r\n",
+			limit:    func(o *rawhttp.Options) { o.MaxChunkLineLength = 64 },
+			body:     "012",
+			headers:  4,
+		},
+	}
+
+	sender := rawhttp.NewSender()
+	req := []byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			port := fixedResponseServer(t, tt.response)
+
+			// Within the default limits the response is read in full
+			resp, err := sender.Do(context.Background(), req, streamOpts(port))
+			if err != nil {
+				t.Fatalf("unlimited request failed: %v", err)
+			}
+			resp.Body.Close()
+			resp.Raw.Close()
+
+			opts := streamOpts(port)
+			tt.limit(&opts)
+			resp, err = sender.Do(context.Background(), req, opts)
+			if !rawhttp.IsLimitError(err) || rawhttp.GetErrorType(err) != string(rawhttp.ErrorTypeLimit) {
+				t.Fatalf("expected a limit error, got %v", err)
+			}
+			if resp == nil {
+				t.Fatal("expected the partial response along with the error")
+			}
+			defer resp.Body.Close()
+			defer resp.Raw.Close()
+			if string(resp.Body.Bytes()) != tt.body {
+				t.Errorf("expected body %q, got %q", tt.body, resp.Body.Bytes())
+			}
+			if len(resp.HeaderList) != tt.headers {
+				t.Errorf("expected %d header fields, got %d", tt.headers, len(resp.HeaderList))
+			}
+			if raw := string(resp.Raw.Bytes()); raw == "" || !strings.HasPrefix(tt.response, raw) {
+				t.Errorf("raw bytes %q are not a prefix of the response", raw)
+			}
+		})
+	}
+}
+
+func TestLimits_HTTP1Stream(t *testing.T) {
+	port := fixedResponseServer(t, "HTTP/1.1 200 OK\r\nContent-Length: 10\r\n\r\n0123456789")
+
+	opts := streamOpts(port)
+	opts.MaxBodyBytes = 4
+	resp, err := rawhttp.NewSender().DoStream(context.Background(), []byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n"), opts)
+	if err != nil {
+		t.Fatalf("DoStream failed: %v", err)
+	}
+	defer resp.BodyStream.Close()
+
+	body, err := io.ReadAll(resp.BodyStream)
+	if !rawhttp.IsLimitError(err) {
+		t.Fatalf("expected a limit error, got %v", err)
+	}
+	if string(body) != "0123" {
+		t.Errorf("expected body %q, got %q", "0123", body)
+	}
+}
+
+func TestLimits_HTTP2(t *testing.T) {
+	srv := newHTTP2Server(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-A", "1")
+		w.Header().Set("X-B", "2")
+		io.WriteString(w, strings.Repeat("x", 1000))
+	})
+	defer srv.Close()
+
+	sender := rawhttp.NewSender()
+	req := []byte("GET / HTTP/2\r\nHost: localhost\r\n\r\n")
+
+	opts := h2Opts(srv)
+	opts.MaxBodyBytes = 100
+	resp, err := sender.Do(context.Background(), req, opts)
+	if !rawhttp.IsLimitError(err) {
+		t.Fatalf("expected a limit error, got %v", err)
+	}
+	if resp == nil || resp.StatusCode != 200 || resp.Body.Size() != 100 {
+		t.Fatalf("expected a 200 response with 100 body bytes, got %+v", resp)
+	}
+
+	opts = h2Opts(srv)
+	opts.MaxHeaderCount = 2
+	resp, err = sender.Do(context.Background(), req, opts)
+	if !rawhttp.IsLimitError(err) || !strings.Contains(err.Error(), "MaxHeaderCount") {
+		t.Fatalf("expected a MaxHeaderCount limit error, got %v", err)
+	}
+	if resp == nil || resp.StatusCode != 200 || resp.Body.Size() != 0 {
+		t.Fatalf("expected a 200 response without body, got %+v", resp)
+	}
+
+	// The connection stays usable: only the stream was cancelled
+	resp, err = sender.Do(context.Background(), req, h2Opts(srv))
+	if err != nil {
+		t.Fatalf("request after the limit errors failed: %v", err)
+	}
+	if resp.Body.Size() != 1000 || !resp.ConnectionReused {
+		t.Errorf("expected the full body on the reused connection, got %d bytes (reused %v)", resp.Body.Size(), resp.ConnectionReused)
+	}
+}
@@ -0,0 +1,170 @@
+package unit
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/WhileEndless/go-rawhttp"
+)
+
+// pipelineServer answers on a connection only after reading n request heads, so
+// the requests must have been sent without waiting for responses.
+func pipelineServer(t *testing.T, n int, responses string) int {
+	return startRawServer(t, func(conn net.Conn) {
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for i := 0; i < n; i++ {
+			if err := readRequestHead(r); err != nil {
+				return
+			}
+		}
+		io.WriteString(conn, responses) // one write: the client buffers it all at once
+	})
+}
+
+func TestDoPipelined_Responses(t *testing.T) {
+	first := "HTTP/1.1 200 OK\r\nContent-Length: 3\r\n\r\none"
+	head := "HTTP/1.1 200 OK\r\nContent-Length: 10\r\n\r\n" // HEAD: no body follows
+	chunked := "HTTP/1.1 201 Created\r\nTransfer-Encoding: chunked\r\n\r\n5\r\nthree\r\n0\r\n\r\n"
+	extra := "HTTP/1.1 400 Bad Request\r\nContent-Length: 0\r\n\r\n"
+	port := pipelineServer(t, 3, first+head+chunked+extra)
+
+	result, err := rawhttp.NewSender().DoPipelined(context.Background(), [][]byte{
+		[]byte("GET /1 HTTP/1.1\r\nHost: localhost\r\n\r\n"),
+		[]byte("HEAD /2 HTTP/1.1\r\nHost: localhost\r\n\r\n"),
+		[]byte("POST /3 HTTP/1.1\r\nHost: localhost\r\nContent-Length: 0\r\n\r\n"),
+	}, streamOpts(port))
+	if err != nil {
+		t.Fatalf("DoPipelined failed: %v", err)
+	}
+	if result.Err != nil {
+		t.Fatalf("unexpected read error: %v", result.Err)
+	}
+	if len(result.Responses) != 3 {
+		t.Fatalf("expected 3 responses, got %d", len(result.Responses))
+	}
+
+	for i, want := range []struct {
+		status int
+		body   string
+		raw    string
+	}{
+		{200, "one", first},
+		{200, "", head},
+		{201, "three", chunked},
+	} {
+		resp := result.Responses[i]
+		defer resp.Body.Close()
+		defer resp.Raw.Close()
+		if resp.StatusCode != want.status || string(resp.Body.Bytes()) != want.body {
+			t.Errorf("response %d: got %d %q, want %d %q", i, resp.StatusCode, resp.Body.Bytes(), want.status, want.body)
+		}
+		if string(resp.Raw.Bytes()) != want.raw {
+			t.Errorf("response %d: unexpected raw bytes %q", i, resp.Raw.Bytes())
+		}
+		if resp.Timings.TTFB <= 0 {
+			t.Errorf("response %d: expected a TTFB", i)
+		}
+	}
+	if string(result.Unattributed) != extra {
+		t.Errorf("expected the extra response to be unattributed, got %q", result.Unattributed)
+	}
+}
+
+// An extra response written separately, after the expected ones, is still
+// attributed even though the server keeps the connection open.
+func TestDoPipelined_LateExtraResponse(t *testing.T) {
+	ok := "HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\nok"
+	extra := "HTTP/1.1 400 Bad Request\r\nContent-Length: 0\r\n\r\n"
+	port := startRawServer(t, func(conn net.Conn) {
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for i := 0; i < 2; i++ {
+			if err := readRequestHead(r); err != nil {
+				return
+			}
+		}
+		io.WriteString(conn, ok+ok)
+		time.Sleep(50 * time.Millisecond)
+		io.WriteString(conn, extra)
+		io.Copy(io.Discard, r) // keep the connection open until the client closes it
+	})
+
+	req := []byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n")
+	start := time.Now()
+	result, err := rawhttp.NewSender().DoPipelined(context.Background(), [][]byte{req, req}, streamOpts(port))
+	if err != nil {
+		t.Fatalf("DoPipelined failed: %v", err)
+	}
+	if result.Err != nil || len(result.Responses) != 2 {
+		t.Fatalf("expected 2 responses, got %d (%v)", len(result.Responses), result.Err)
+	}
+	for _, resp := range result.Responses {
+		resp.Body.Close()
+		resp.Raw.Close()
+	}
+	if string(result.Unattributed) != extra {
+		t.Errorf("expected the late response to be unattributed, got %q", result.Unattributed)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("draining took %v on an open connection", elapsed)
+	}
+}
+
+func TestDoPipelined_SinglePacket(t *testing.T) {
+	reqs := [][]byte{
+		[]byte("GET /a HTTP/1.1\r\nHost: localhost\r\n\r\n"),
+		[]byte("GET /b HTTP/1.1\r\nHost: localhost\r\n\r\n"),
+	}
+	received := make(chan string, 1)
+	port := startRawServer(t, func(conn net.Conn) {
+		defer conn.Close()
+		buf := make([]byte, 4096)
+		n, _ := conn.Read(buf)
+		received <- string(buf[:n])
+		io.WriteString(conn, strings.Repeat("HTTP/1.1 204 No Content\r\n\r\n", 2))
+	})
+
+	opts := streamOpts(port)
+	opts.PipelineSinglePacket = true
+	result, err := rawhttp.NewSender().DoPipelined(context.Background(), reqs, opts)
+	if err != nil {
+		t.Fatalf("DoPipelined failed: %v", err)
+	}
+	if got, want := <-received, string(reqs[0])+string(reqs[1]); got != want {
+		t.Errorf("expected both requests in the first read, got %q", got)
+	}
+	if result.Err != nil || len(result.Responses) != 2 {
+		t.Fatalf("expected 2 responses, got %d (%v)", len(result.Responses), result.Err)
+	}
+	for _, resp := range result.Responses {
+		resp.Body.Close()
+		resp.Raw.Close()
+	}
+}
+
+func TestDoPipelined_UnparsableResponse(t *testing.T) {
+	port := pipelineServer(t, 2, "HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\nokgarbage\r\n\r\n")
+
+	req := []byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n")
+	result, err := rawhttp.NewSender().DoPipelined(context.Background(), [][]byte{req, req}, streamOpts(port))
+	if err != nil {
+		t.Fatalf("DoPipelined failed: %v", err)
+	}
+	if result.Err == nil {
+		t.Fatal("expected a parse error for the second response")
+	}
+	if len(result.Responses) != 1 || string(result.Responses[0].Body.Bytes()) != "ok" {
+		t.Fatalf("expected the first response only, got %d", len(result.Responses))
+	}
+	result.Responses[0].Body.Close()
+	result.Responses[0].Raw.Close()
+	if string(result.Unattributed) != "garbage\r\n\r\n" {
+		t.Errorf("expected the unparsable bytes to be unattributed, got %q", result.Unattributed)
+	}
+}
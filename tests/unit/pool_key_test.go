@@ -0,0 +1,126 @@
+package unit
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/WhileEndless/go-rawhttp"
+	"github.com/WhileEndless/go-rawhttp/pkg/transport"
+)
+
+func TestPoolKey_Dimensions(t *testing.T) {
+	base := transport.Config{Scheme: "https", Host: "example.com", Port: 443}
+	alpn := []string{"http/1.1"}
+	key := transport.NewPoolKey(base, alpn)
+
+	if key.SNI != "example.com" || key.Verification != "verify" || key.ALPN != "http/1.1" {
+		t.Fatalf("unexpected default key %+v", key)
+	}
+	if key != transport.NewPoolKey(base, alpn) {
+		t.Fatal("identical configurations must produce equal keys")
+	}
+
+	variants := map[string]func(c *transport.Config){
+		"scheme":       func(c *transport.Config) { c.Scheme = "http" },
+		"sni":          func(c *transport.Config) { c.SNI = "other.example" },
+		"disable sni":  func(c *transport.Config) { c.DisableSNI = true },
+		"tls sni":      func(c *transport.Config) { c.TLSConfig = &tls.Config{ServerName: "cfg.example"} },
+		"insecure":     func(c *transport.Config) { c.InsecureTLS = true },
+		"cert":         func(c *transport.Config) { c.ClientCertPEM = []byte("cert-a") },
+		"ca":           func(c *transport.Config) { c.CustomCACerts = [][]byte{[]byte("ca")} },
+		"min version":  func(c *transport.Config) { c.MinTLSVersion = tls.VersionTLS13 },
+		"max version":  func(c *transport.Config) { c.MaxTLSVersion = tls.VersionTLS12 },
+		"ciphers":      func(c *transport.Config) { c.CipherSuites = []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256} },
+		"proxy":        func(c *transport.Config) { c.Proxy = &transport.ProxyConfig{Type: "socks5", Host: "proxy"} },
+		"network":      func(c *transport.Config) { c.Network = "tcp6" },
+		"dialer":       func(c *transport.Config) { c.DialContext = dialVia("a") },
+		"other dialer": func(c *transport.Config) { c.DialContext = dialVia("b") },
+		"resolver":     func(c *transport.Config) { c.Resolver = &staticResolver{ip: "127.0.0.1"} },
+	}
+	seen := map[string]string{key.String(): "base"}
+	for name, mutate := range variants {
+		config := base
+		mutate(&config)
+		s := transport.NewPoolKey(config, alpn).String()
+		if prev, dup := seen[s]; dup {
+			t.Errorf("%s: key %q collides with %s", name, s, prev)
+		}
+		seen[s] = name
+	}
+
+	other := base
+	other.ClientCertPEM = []byte("cert-b")
+	withA := base
+	withA.ClientCertPEM = []byte("cert-a")
+	if transport.NewPoolKey(withA, alpn).ClientCert == transport.NewPoolKey(other, alpn).ClientCert {
+		t.Error("different client certificates must have different fingerprints")
+	}
+	if got := transport.NewPoolKey(transport.Config{Scheme: "http", Host: "h", Port: 80}, alpn).String(); got != "http://h:80" {
+		t.Errorf("unexpected plain key %q", got)
+	}
+}
+
+// dialVia returns a distinct closure of the same function literal per name, as a
+// per-namespace dialer would be.
+func dialVia(name string) transport.DialContextFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return nil, fmt.Errorf("dial via %s", name)
+	}
+}
+
+// Pooled HTTP/1.1 connections are only shared by requests with the same TLS setup.
+func TestPoolKey_IsolatesTLSOptions(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+	port := srv.Listener.Addr().(*net.TCPAddr).Port
+
+	sender := rawhttp.NewSender()
+	send := func(sni string) {
+		t.Helper()
+		opts := rawhttp.Options{
+			Scheme:          "https",
+			Host:            "127.0.0.1",
+			Port:            port,
+			SNI:             sni,
+			InsecureTLS:     true,
+			ReuseConnection: true,
+			ConnTimeout:     5 * time.Second,
+			ReadTimeout:     5 * time.Second,
+		}
+		resp, err := sender.Do(context.Background(), []byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n"), opts)
+		if err != nil {
+			t.Fatalf("request with SNI %q failed: %v", sni, err)
+		}
+		resp.Body.Close()
+		resp.Raw.Close()
+	}
+
+	send("a.example")
+	send("b.example")
+	send("a.example")
+
+	stats := sender.PoolStats()
+	if stats.TotalCreated != 2 || stats.TotalReused != 1 {
+		t.Fatalf("expected 2 connections and 1 reuse, got created=%d reused=%d", stats.TotalCreated, stats.TotalReused)
+	}
+	if len(stats.HostStats) != 2 {
+		t.Fatalf("expected 2 pools, got %v", stats.HostStats)
+	}
+	for name, hs := range stats.HostStats {
+		if !strings.Contains(name, "|sni="+hs.Key.SNI+"|") || hs.Key.Scheme != "https" || hs.Key.Verification != "insecure" {
+			t.Errorf("pool %q has unexpected key %+v", name, hs.Key)
+		}
+		if hs.IdleConns != 1 {
+			t.Errorf("pool %q: expected 1 idle connection, got %d", name, hs.IdleConns)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package unit
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/WhileEndless/go-rawhttp"
+	"github.com/WhileEndless/go-rawhttp/pkg/client"
+)
+
+func TestServerPush_SurfacedBySender(t *testing.T) {
+	srv := newHTTP2Server(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.(http.Pusher).Push("/pushed.txt", nil)
+			io.WriteString(w, "index")
+			return
+		}
+		io.WriteString(w, "pushed")
+	})
+	defer srv.Close()
+
+	opts := h2Opts(srv)
+	opts.HTTP2Settings = &client.HTTP2Settings{
+		MaxConcurrentStreams: 100,
+		InitialWindowSize:    4194304,
+		MaxFrameSize:         16384,
+		MaxHeaderListSize:    10485760,
+		HeaderTableSize:      4096,
+		EnableCompression:    true,
+		DisableServerPush:    false,
+	}
+
+	resp, err := rawhttp.NewSender().Do(context.Background(), []byte("GET / HTTP/2\r\nHost: localhost\r\n\r\n"), opts)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	defer resp.Raw.Close()
+
+	if len(resp.ServerPush) != 1 {
+		t.Fatalf("expected 1 pushed response, got %d", len(resp.ServerPush))
+	}
+	pushed := resp.ServerPush[0]
+	if pushed.Err != nil || pushed.Response == nil {
+		t.Fatalf("pushed response failed: %v", pushed.Err)
+	}
+	defer pushed.Response.Body.Close()
+	defer pushed.Response.Raw.Close()
+
+	var path string
+	for _, f := range pushed.RequestHeaders {
+		if f.Name == ":path" {
+			path = f.Value
+		}
+	}
+	if path != "/pushed.txt" {
+		t.Errorf("expected promised :path /pushed.txt, got %q", path)
+	}
+	if pushed.Response.StatusCode != 200 || string(pushed.Response.Body.Bytes()) != "pushed" {
+		t.Errorf("unexpected pushed response %d %q", pushed.Response.StatusCode, pushed.Response.Body.Bytes())
+	}
+}
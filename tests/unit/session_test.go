@@ -0,0 +1,120 @@
+package unit
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/WhileEndless/go-rawhttp"
+)
+
+// sessionServer answers keep-alive requests with their request line as the body
+// (no body for HEAD) and closes the connection after a "/close" request. It
+// counts the connections it accepted.
+func sessionServer(t *testing.T) (int, *int32) {
+	var conns int32
+	port := startRawServer(t, func(conn net.Conn) {
+		atomic.AddInt32(&conns, 1)
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			if err := readRequestHead(r); err != nil {
+				return
+			}
+			line = strings.TrimSpace(line)
+			body := line
+			if strings.HasPrefix(line, "HEAD ") {
+				body = ""
+			}
+			fmt.Fprintf(conn, "HTTP/1.1 200 OK\r\nContent-Length: %d\r\n\r\n%s", len(line), body)
+			if strings.Contains(line, "/close") {
+				return
+			}
+		}
+	})
+	return port, &conns
+}
+
+func TestSession_OneConnection(t *testing.T) {
+	port, conns := sessionServer(t)
+
+	session, err := rawhttp.NewSender().OpenSession(context.Background(), streamOpts(port))
+	if err != nil {
+		t.Fatalf("OpenSession failed: %v", err)
+	}
+	defer session.Close()
+
+	for i, path := range []string{"/a", "/b", "/close"} {
+		resp, err := session.Do([]byte("GET " + path + " HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+		if err != nil {
+			t.Fatalf("request %d failed: %v", i, err)
+		}
+		if want := "GET " + path + " HTTP/1.1"; string(resp.Body.Bytes()) != want {
+			t.Errorf("request %d: expected body %q, got %q", i, want, resp.Body.Bytes())
+		}
+		if resp.ConnectionReused != (i > 0) {
+			t.Errorf("request %d: unexpected ConnectionReused %v", i, resp.ConnectionReused)
+		}
+		if resp.LocalAddr != session.ConnectionMetadata().LocalAddr {
+			t.Errorf("request %d: sent from %s, session is %s", i, resp.LocalAddr, session.ConnectionMetadata().LocalAddr)
+		}
+		resp.Body.Close()
+		resp.Raw.Close()
+	}
+
+	// The server closed the connection after /close
+	if _, err := session.Do([]byte("GET /d HTTP/1.1\r\nHost: localhost\r\n\r\n")); !errors.Is(err, io.EOF) {
+		t.Errorf("expected an error wrapping io.EOF after the server closed, got %v", err)
+	}
+	if n := atomic.LoadInt32(conns); n != 1 {
+		t.Errorf("expected exactly one connection, got %d", n)
+	}
+
+	session.Close()
+	if err := session.WriteRaw([]byte("GET / HTTP/1.1\r\n\r\n")); err == nil {
+		t.Error("expected an error writing to a closed session")
+	}
+}
+
+func TestSession_WriteRawThenRead(t *testing.T) {
+	port, _ := sessionServer(t)
+
+	session, err := rawhttp.NewSender().OpenSession(context.Background(), streamOpts(port))
+	if err != nil {
+		t.Fatalf("OpenSession failed: %v", err)
+	}
+	defer session.Close()
+
+	// Both requests before any response, the HEAD request split over two writes
+	for _, p := range []string{
+		"GET /1 HTTP/1.1\r\nHost: localhost\r\n\r\n",
+		"HEAD /2 HTTP/1.1\r\n",
+		"Host: localhost\r\n\r\n",
+	} {
+		if err := session.WriteRaw([]byte(p)); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+
+	for i, want := range []string{"GET /1 HTTP/1.1", ""} {
+		resp, err := session.ReadResponse()
+		if err != nil {
+			t.Fatalf("response %d failed: %v", i, err)
+		}
+		if string(resp.Body.Bytes()) != want {
+			t.Errorf("response %d: expected body %q, got %q", i, want, resp.Body.Bytes())
+		}
+		resp.Body.Close()
+		resp.Raw.Close()
+	}
+}
@@ -0,0 +1,260 @@
+package unit
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/WhileEndless/go-rawhttp"
+)
+
+// startRawServer accepts connections on a local listener and hands each one to
+// handle. The listener is closed when the test ends.
+func startRawServer(t *testing.T, handle func(net.Conn)) int {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go handle(conn)
+		}
+	}()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+// readRequestHead consumes one request head (no body) from the connection.
+func readRequestHead(r *bufio.Reader) error {
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		if line == "\r\n" {
+			return nil
+		}
+	}
+}
+
+func streamOpts(port int) rawhttp.Options {
+	return rawhttp.Options{
+		Scheme:      "http",
+		Host:        "127.0.0.1",
+		Port:        port,
+		ConnTimeout: 5 * time.Second,
+		ReadTimeout: 5 * time.Second,
+	}
+}
+
+func TestDoStream_ChunkedIncremental(t *testing.T) {
+	release := make(chan struct{})
+	port := startRawServer(t, func(conn net.Conn) {
+		defer conn.Close()
+		if err := readRequestHead(bufio.NewReader(conn)); err != nil {
+			return
+		}
+		io.WriteString(conn, "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n")
+		io.WriteString(conn, "6;ext=1\r\nevent1\r\n")
+		// The second chunk is held back until the client has seen the first one,
+		// which proves DoStream returns before the body is complete.
+		<-release
+		io.WriteString(conn, "6\r\nevent2\r\n0\r\nX-Checksum: abc\r\n\r\n")
+	})
+
+	sender := rawhttp.NewSender()
+	req := []byte("GET /events HTTP/1.1\r\nHost: 127.0.0.1\r\n\r\n")
+	resp, err := sender.DoStream(context.Background(), req, streamOpts(port))
+	if err != nil {
+		t.Fatalf("DoStream failed: %v", err)
+	}
+	defer resp.BodyStream.Close()
+
+	if resp.StatusCode != 200 {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	if resp.Body.Size() != 0 {
+		t.Errorf("expected empty buffered body, got %d bytes", resp.Body.Size())
+	}
+
+	first := make([]byte, 6)
+	if _, err := io.ReadFull(resp.BodyStream, first); err != nil {
+		t.Fatalf("reading first chunk: %v", err)
+	}
+	if string(first) != "event1" {
+		t.Fatalf("expected %q, got %q", "event1", first)
+	}
+	close(release)
+
+	rest, err := io.ReadAll(resp.BodyStream)
+	if err != nil {
+		t.Fatalf("reading rest: %v", err)
+	}
+	if string(rest) != "event2" {
+		t.Errorf("expected %q, got %q", "event2", rest)
+	}
+	if got := resp.Trailers["X-Checksum"]; len(got) != 1 || got[0] != "abc" {
+		t.Errorf("expected trailer X-Checksum in Trailers, got %v", resp.Trailers)
+	}
+}
+
+func TestDoStream_ConnectionReuseAfterDrain(t *testing.T) {
+	port := startRawServer(t, func(conn net.Conn) {
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			if err := readRequestHead(r); err != nil {
+				return
+			}
+			io.WriteString(conn, "HTTP/1.1 200 OK\r\nContent-Length: 5\r\n\r\nhello")
+		}
+	})
+
+	sender := rawhttp.NewSender()
+	opts := streamOpts(port)
+	opts.ReuseConnection = true
+	req := []byte("GET / HTTP/1.1\r\nHost: 127.0.0.1\r\n\r\n")
+
+	for i := 0; i < 2; i++ {
+		resp, err := sender.DoStream(context.Background(), req, opts)
+		if err != nil {
+			t.Fatalf("request %d failed: %v", i, err)
+		}
+		if i == 1 && !resp.ConnectionReused {
+			t.Error("expected drained and closed stream to return its connection to the pool")
+		}
+		if stats := sender.PoolStats(); stats.ActiveConns != 1 {
+			t.Errorf("request %d: expected 1 active connection while streaming, got %d", i, stats.ActiveConns)
+		}
+		body, err := io.ReadAll(resp.BodyStream)
+		if err != nil || string(body) != "hello" {
+			t.Fatalf("request %d: body %q, err %v", i, body, err)
+		}
+		resp.BodyStream.Close()
+	}
+
+	if stats := sender.PoolStats(); stats.ActiveConns != 0 || stats.IdleConns != 1 {
+		t.Errorf("expected 0 active / 1 idle after close, got %d / %d", stats.ActiveConns, stats.IdleConns)
+	}
+}
+
+func TestDoStream_EarlyCloseDiscardsConnection(t *testing.T) {
+	port := startRawServer(t, func(conn net.Conn) {
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			if err := readRequestHead(r); err != nil {
+				return
+			}
+			body := strings.Repeat("x", 64*1024)
+			fmt.Fprintf(conn, "HTTP/1.1 200 OK\r\nContent-Length: %d\r\n\r\n%s", len(body), body)
+		}
+	})
+
+	sender := rawhttp.NewSender()
+	opts := streamOpts(port)
+	opts.ReuseConnection = true
+	req := []byte("GET /big HTTP/1.1\r\nHost: 127.0.0.1\r\n\r\n")
+
+	resp, err := sender.DoStream(context.Background(), req, opts)
+	if err != nil {
+		t.Fatalf("DoStream failed: %v", err)
+	}
+	buf := make([]byte, 10)
+	if _, err := io.ReadFull(resp.BodyStream, buf); err != nil {
+		t.Fatalf("partial read: %v", err)
+	}
+	resp.BodyStream.Close()
+
+	// Closing is idempotent and reading afterwards fails.
+	if err := resp.BodyStream.Close(); err != nil {
+		t.Errorf("second Close returned %v", err)
+	}
+	if _, err := resp.BodyStream.Read(buf); err == nil {
+		t.Error("expected error reading from a closed body stream")
+	}
+
+	if stats := sender.PoolStats(); stats.IdleConns != 0 {
+		t.Errorf("partially read connection must not be pooled, got %d idle", stats.IdleConns)
+	}
+
+	resp, err = sender.DoStream(context.Background(), req, opts)
+	if err != nil {
+		t.Fatalf("second DoStream failed: %v", err)
+	}
+	defer resp.BodyStream.Close()
+	if resp.ConnectionReused {
+		t.Error("expected a fresh connection after an early close")
+	}
+}
+
+func TestDoStream_HTTP2(t *testing.T) {
+	release := make(chan struct{})
+	srv := newHTTP2Server(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Trailer", "X-Done")
+		w.WriteHeader(200)
+		io.WriteString(w, "part1")
+		w.(http.Flusher).Flush()
+		<-release
+		io.WriteString(w, "part2")
+		w.Header().Set("X-Done", "yes")
+	})
+	defer srv.Close()
+
+	sender := rawhttp.NewSender()
+	opts := h2Opts(srv)
+	req := []byte("GET /stream HTTP/2\r\nHost: localhost\r\n\r\n")
+
+	resp, err := sender.DoStream(context.Background(), req, opts)
+	if err != nil {
+		t.Fatalf("DoStream failed: %v", err)
+	}
+	defer resp.BodyStream.Close()
+
+	if resp.StatusCode != 200 || resp.HTTPVersion != "HTTP/2" {
+		t.Fatalf("unexpected response: %d %s", resp.StatusCode, resp.HTTPVersion)
+	}
+
+	first := make([]byte, 5)
+	if _, err := io.ReadFull(resp.BodyStream, first); err != nil {
+		t.Fatalf("reading first DATA frame: %v", err)
+	}
+	if string(first) != "part1" {
+		t.Fatalf("expected %q, got %q", "part1", first)
+	}
+	close(release)
+
+	rest, err := io.ReadAll(resp.BodyStream)
+	if err != nil {
+		t.Fatalf("reading rest: %v", err)
+	}
+	if string(rest) != "part2" {
+		t.Errorf("expected %q, got %q", "part2", rest)
+	}
+	if got := resp.Trailers["x-done"]; len(got) != 1 || got[0] != "yes" {
+		t.Errorf("expected trailer x-done in Trailers, got %v", resp.Trailers)
+	}
+
+	// The pooled connection must still be usable after the stream is closed.
+	resp.BodyStream.Close()
+	resp2, err := sender.Do(context.Background(), []byte("GET /after HTTP/2\r\nHost: localhost\r\n\r\n"), opts)
+	if err != nil {
+		t.Fatalf("follow-up request failed: %v", err)
+	}
+	defer resp2.Body.Close()
+	defer resp2.Raw.Close()
+	if resp2.StatusCode != 200 {
+		t.Errorf("follow-up request: expected 200, got %d", resp2.StatusCode)
+	}
+}
@@ -1,9 +1,17 @@
 package unit
 
 import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/WhileEndless/go-rawhttp"
 	"github.com/WhileEndless/go-rawhttp/pkg/timing"
 )
 
@@ -128,3 +136,216 @@ func containsMiddle(s, substr string) bool {
 	}
 	return false
 }
+
+func TestTimerPhases(t *testing.T) {
+	var nilTimer *timing.Timer
+	nilTimer.StartDNS() // a nil timer measures nothing
+	if m := nilTimer.GetMetrics(); m.TotalTime != 0 || len(m.Phases) != 0 {
+		t.Errorf("expected empty metrics from a nil timer, got %+v", m)
+	}
+
+	timer := timing.NewTimer()
+	timer.StartPoolWait()
+	timer.EndPoolWait()
+	timer.StartWrite()
+	timer.EndWrite()
+	timer.StartTTFB()
+	time.Sleep(10 * time.Millisecond)
+	timer.EndTTFB()
+	time.Sleep(10 * time.Millisecond)
+	timer.StartBody()
+	time.Sleep(10 * time.Millisecond)
+	timer.EndBody()
+	timer.StartProxy() // never ended: not reported
+
+	m := timer.GetMetrics()
+	var names []string
+	for _, p := range m.Phases {
+		names = append(names, p.Name)
+		if p.Start.Before(m.Start) || p.End.Before(p.Start) {
+			t.Errorf("phase %s has inconsistent timestamps %v..%v (start %v)", p.Name, p.Start, p.End, m.Start)
+		}
+	}
+	if got := strings.Join(names, ","); got != "pool_wait,write,ttfb,body" {
+		t.Errorf("unexpected phases %s", got)
+	}
+	if m.FirstBodyByte < m.TTFB+5*time.Millisecond {
+		t.Errorf("first body byte (%v) should follow the first byte (%v)", m.FirstBodyByte, m.TTFB)
+	}
+	if m.BodyTransfer < 5*time.Millisecond || m.ProxyConnect != 0 {
+		t.Errorf("unexpected body transfer %v / proxy connect %v", m.BodyTransfer, m.ProxyConnect)
+	}
+	for _, substr := range []string{"PoolWait:", "RequestWrite:", "FirstBodyByte:", "BodyTransfer:"} {
+		if !strings.Contains(m.String(), substr) {
+			t.Errorf("string representation should contain %q: %s", substr, m.String())
+		}
+	}
+	if strings.Contains(m.String(), "ProxyConnect") || strings.Contains(m.String(), "SettingsExchange") {
+		t.Errorf("unmeasured phases should be left out: %s", m.String())
+	}
+}
+
+// slowBodyHandler sends the response head, then the body in two delayed parts.
+func slowBodyHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Length", "4")
+	w.WriteHeader(http.StatusOK)
+	w.(http.Flusher).Flush()
+	time.Sleep(30 * time.Millisecond)
+	io.WriteString(w, "ab")
+	w.(http.Flusher).Flush()
+	time.Sleep(30 * time.Millisecond)
+	io.WriteString(w, "cd")
+}
+
+func phaseNames(m timing.Metrics) string {
+	var names []string
+	for _, p := range m.Phases {
+		names = append(names, p.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+func checkBodyTimings(t *testing.T, m timing.Metrics) {
+	t.Helper()
+	if m.FirstBodyByte-m.TTFB < 20*time.Millisecond {
+		t.Errorf("expected the head to arrive ~30ms before the body: TTFB %v, first body byte %v", m.TTFB, m.FirstBodyByte)
+	}
+	if m.BodyTransfer < 20*time.Millisecond {
+		t.Errorf("expected a ~30ms body transfer, got %v", m.BodyTransfer)
+	}
+}
+
+func TestTimingPhases_HTTP1(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(slowBodyHandler))
+	defer srv.Close()
+
+	opts := rawhttp.Options{
+		Scheme:          "https",
+		Host:            "timing.example",
+		Port:            srv.Listener.Addr().(*net.TCPAddr).Port,
+		Resolver:        &staticResolver{ip: "127.0.0.1"},
+		InsecureTLS:     true,
+		ReuseConnection: true,
+		ConnTimeout:     5 * time.Second,
+		ReadTimeout:     5 * time.Second,
+	}
+	sender := rawhttp.NewSender()
+	req := []byte("GET / HTTP/1.1\r\nHost: timing.example\r\n\r\n")
+	for i, want := range []string{
+		"pool_wait,dns,tcp,tls,write,ttfb,body",
+		"pool_wait,write,ttfb,body", // pooled connection
+	} {
+		resp, err := sender.Do(context.Background(), req, opts)
+		if err != nil {
+			t.Fatalf("request %d failed: %v", i, err)
+		}
+		resp.Body.Close()
+		resp.Raw.Close()
+		if got := phaseNames(resp.Timings); got != want {
+			t.Errorf("request %d: expected phases %s, got %s", i, want, got)
+		}
+		checkBodyTimings(t, resp.Timings)
+	}
+}
+
+func TestTimingPhases_HTTP2(t *testing.T) {
+	srv := newHTTP2Server(slowBodyHandler)
+	defer srv.Close()
+
+	resp, err := rawhttp.NewSender().Do(context.Background(), []byte("GET / HTTP/2\r\nHost: localhost\r\n\r\n"), h2Opts(srv))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	defer resp.Raw.Close()
+
+	m := resp.Timings
+	if got, want := phaseNames(m), "pool_wait,dns,tcp,tls,settings,write,ttfb,body"; got != want {
+		t.Errorf("expected phases %s, got %s", want, got)
+	}
+	if m.SettingsExchange <= 0 || m.TLSHandshake <= 0 {
+		t.Errorf("expected SETTINGS and TLS to be timed separately: %s", m)
+	}
+	if m.TTFB >= m.TotalTime-m.BodyTransfer {
+		t.Errorf("TTFB (%v) should end at the first frame, not the end of the body (total %v)", m.TTFB, m.TotalTime)
+	}
+	checkBodyTimings(t, m)
+}
+
+// DoStream times the body as it is read: Timings is complete once BodyStream
+// reaches EOF, on both protocols.
+func TestTimingPhases_Stream(t *testing.T) {
+	h1 := httptest.NewServer(http.HandlerFunc(slowBodyHandler))
+	defer h1.Close()
+	h2 := newHTTP2Server(slowBodyHandler)
+	defer h2.Close()
+
+	for _, tt := range []struct {
+		name string
+		req  string
+		opts rawhttp.Options
+	}{
+		{"HTTP/1.1", "GET / HTTP/1.1\r\nHost: localhost\r\n\r\n", streamOpts(h1.Listener.Addr().(*net.TCPAddr).Port)},
+		{"HTTP/2", "GET / HTTP/2\r\nHost: localhost\r\n\r\n", h2Opts(h2)},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := rawhttp.NewSender().DoStream(context.Background(), []byte(tt.req), tt.opts)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.BodyStream.Close()
+			if strings.HasSuffix(phaseNames(resp.Timings), ",body") {
+				t.Errorf("body timed before it was read: %s", phaseNames(resp.Timings))
+			}
+			if body, err := io.ReadAll(resp.BodyStream); err != nil || string(body) != "abcd" {
+				t.Fatalf("expected body %q, got %q (%v)", "abcd", body, err)
+			}
+			if got := phaseNames(resp.Timings); !strings.HasSuffix(got, ",ttfb,body") {
+				t.Errorf("expected the body phase after TTFB, got %s", got)
+			}
+			checkBodyTimings(t, resp.Timings)
+		})
+	}
+}
+
+func TestTimingPhases_Proxy(t *testing.T) {
+	dial, _ := pipeDialer(func(conn net.Conn) {
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		if err := readRequestHead(r); err != nil {
+			return
+		}
+		time.Sleep(30 * time.Millisecond)
+		io.WriteString(conn, "HTTP/1.1 200 Connection Established\r\n\r\n")
+		if err := readRequestHead(r); err != nil {
+			return
+		}
+		io.WriteString(conn, "HTTP/1.1 204 No Content\r\nConnection: close\r\n\r\n")
+	})
+
+	resp, err := rawhttp.NewSender().Do(context.Background(), []byte("GET / HTTP/1.1\r\nHost: target\r\n\r\n"), rawhttp.Options{
+		Scheme:      "http",
+		Host:        "192.0.2.1",
+		Port:        80,
+		Proxy:       &rawhttp.ProxyConfig{Type: "http", Host: "192.0.2.10", Port: 3128},
+		DialContext: dial,
+		ConnTimeout: 5 * time.Second,
+		ReadTimeout: 5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	resp.Raw.Close()
+
+	m := resp.Timings
+	if got, want := phaseNames(m), "dns,tcp,proxy,write,ttfb"; got != want {
+		t.Errorf("expected phases %s, got %s", want, got)
+	}
+	if m.ProxyConnect < 20*time.Millisecond || m.TCPConnect >= m.ProxyConnect {
+		t.Errorf("expected the CONNECT exchange (~30ms) apart from the dial: tcp %v, proxy %v", m.TCPConnect, m.ProxyConnect)
+	}
+	if m.GetConnectionTime() < m.ProxyConnect {
+		t.Errorf("connection time %v should include the proxy tunnel %v", m.GetConnectionTime(), m.ProxyConnect)
+	}
+}
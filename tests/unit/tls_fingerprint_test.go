@@ -0,0 +1,210 @@
+package unit
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/WhileEndless/go-rawhttp"
+	"github.com/WhileEndless/go-rawhttp/pkg/transport"
+)
+
+// helloListener records the first TLS record each accepted connection reads:
+// the ClientHello as the server saw it.
+type helloListener struct {
+	net.Listener
+	mu     sync.Mutex
+	hellos [][]byte
+}
+
+func (l *helloListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &helloConn{Conn: conn, l: l}, nil
+}
+
+func (l *helloListener) last(t *testing.T) transport.ClientHelloFingerprint {
+	t.Helper()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.hellos) == 0 {
+		t.Fatal("server saw no ClientHello")
+	}
+	fp, err := transport.FingerprintClientHello(l.hellos[len(l.hellos)-1])
+	if err != nil {
+		t.Fatalf("server could not parse the ClientHello: %v", err)
+	}
+	return fp
+}
+
+type helloConn struct {
+	net.Conn
+	l    *helloListener
+	buf  []byte
+	done bool
+}
+
+func (c *helloConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if !c.done {
+		c.buf = append(c.buf, p[:n]...)
+		if len(c.buf) >= 5 && len(c.buf) >= 5+(int(c.buf[3])<<8|int(c.buf[4])) {
+			c.done = true
+			c.l.mu.Lock()
+			c.l.hellos = append(c.l.hellos, c.buf)
+			c.l.mu.Unlock()
+		}
+	}
+	return n, err
+}
+
+func startHelloServer(t *testing.T, h2 bool) (*httptest.Server, *helloListener) {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	ln := &helloListener{Listener: srv.Listener}
+	srv.Listener = ln
+	srv.EnableHTTP2 = h2
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv, ln
+}
+
+func fingerprintOptions(srv *httptest.Server, proto string, fp *rawhttp.TLSFingerprint) rawhttp.Options {
+	addr := srv.Listener.Addr().(*net.TCPAddr)
+	return rawhttp.Options{
+		Scheme:         "https",
+		Host:           "127.0.0.1",
+		Port:           addr.Port,
+		SNI:            "localhost",
+		InsecureTLS:    true,
+		Protocol:       proto,
+		TLSFingerprint: fp,
+		ConnTimeout:    5 * time.Second,
+		ReadTimeout:    5 * time.Second,
+	}
+}
+
+// A JA3 fingerprint produces a ClientHello with exactly that JA3, GREASE
+// aside, and the response reports it.
+func TestTLSFingerprint_JA3(t *testing.T) {
+	srv, ln := startHelloServer(t, false)
+	ja3 := "771,4865-4866-4867-49195-49199-49196-49200-52393-52392,0-23-65281-10-11-35-16-5-13-18-51-45-43-27-21,29-23-24,0"
+
+	fp, err := rawhttp.ParseTLSFingerprint(ja3)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	fp.GREASE = true
+
+	sender := rawhttp.NewSender()
+	req := []byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n")
+	resp, err := sender.Do(context.Background(), req, fingerprintOptions(srv, "http/1.1", fp))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if seen := ln.last(t); seen.JA3 != ja3 {
+		t.Errorf("server saw JA3 %q, want %q", seen.JA3, ja3)
+	}
+	if resp.JA3 != ja3 {
+		t.Errorf("response reports JA3 %q, want %q", resp.JA3, ja3)
+	}
+	sum := md5.Sum([]byte(ja3))
+	if resp.JA3Hash != hex.EncodeToString(sum[:]) {
+		t.Errorf("unexpected JA3 hash %q", resp.JA3Hash)
+	}
+	if !strings.HasPrefix(resp.JA4, "t13d0915h1_") {
+		t.Errorf("unexpected JA4 %q", resp.JA4)
+	}
+	if resp.TLSVersion != "TLS 1.3" {
+		t.Errorf("expected TLS 1.3, got %q", resp.TLSVersion)
+	}
+}
+
+// A browser profile is used over HTTP/2 too, with h2 in its ALPN.
+func TestTLSFingerprint_ProfileHTTP2(t *testing.T) {
+	srv, ln := startHelloServer(t, true)
+
+	sender := rawhttp.NewSender()
+	req := []byte("GET / HTTP/2\r\nHost: localhost\r\n\r\n")
+	opts := fingerprintOptions(srv, "http/2", &rawhttp.TLSFingerprint{Profile: "chrome"})
+	resp, err := sender.Do(context.Background(), req, opts)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.NegotiatedProtocol != "h2" {
+		t.Errorf("expected h2, got %q", resp.NegotiatedProtocol)
+	}
+	seen := ln.last(t)
+	if resp.JA4 == "" || resp.JA4 != seen.JA4 || resp.JA3 != seen.JA3 {
+		t.Errorf("response reports JA3 %q JA4 %q, server saw %+v", resp.JA3, resp.JA4, seen)
+	}
+	if !strings.HasPrefix(seen.JA4, "t13d") || !strings.Contains(seen.JA4, "h2_") {
+		t.Errorf("unexpected JA4 %q for the chrome profile", seen.JA4)
+	}
+}
+
+// Without a fingerprint crypto/tls's ClientHello is sent, and still reported.
+func TestTLSFingerprint_Default(t *testing.T) {
+	srv, ln := startHelloServer(t, false)
+
+	sender := rawhttp.NewSender()
+	req := []byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n")
+	resp, err := sender.Do(context.Background(), req, fingerprintOptions(srv, "http/1.1", nil))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if seen := ln.last(t); resp.JA3 == "" || resp.JA3 != seen.JA3 || resp.JA4 != seen.JA4 {
+		t.Errorf("response reports JA3 %q JA4 %q, server saw %+v", resp.JA3, resp.JA4, seen)
+	}
+}
+
+func TestParseTLSFingerprint(t *testing.T) {
+	for _, name := range []string{"chrome", "Firefox", "safari", "edge", "ios", "android"} {
+		fp, err := rawhttp.ParseTLSFingerprint(name)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if fp.String() != strings.ToLower(name) {
+			t.Errorf("%s: unexpected String() %q", name, fp.String())
+		}
+	}
+
+	invalid := []string{
+		"netscape",
+		"",
+		"771,4865,0-10",
+		"999,4865,0-10-11,29,0",
+		"771,4865-x,0-10-11,29,0",
+		"771,4865,0-10-41,29,0",
+		"771,4865,0-10-11,29,256",
+	}
+	for _, s := range invalid {
+		if _, err := rawhttp.ParseTLSFingerprint(s); err == nil {
+			t.Errorf("%q: expected an error", s)
+		}
+	}
+
+	a := &rawhttp.TLSFingerprint{JA3: "771,4865,0-10,29,0"}
+	b := &rawhttp.TLSFingerprint{JA3: "771,4865,0-10,29,0", GREASE: true}
+	if a.String() == b.String() {
+		t.Errorf("GREASE should be part of the fingerprint key, both are %q", a.String())
+	}
+}
@@ -0,0 +1,236 @@
+package unit
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/WhileEndless/go-rawhttp"
+)
+
+// traceRecorder builds a ClientTrace that records every hook as a short string.
+type traceRecorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *traceRecorder) add(format string, args ...interface{}) {
+	r.mu.Lock()
+	r.events = append(r.events, fmt.Sprintf(format, args...))
+	r.mu.Unlock()
+}
+
+func (r *traceRecorder) list() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.events...)
+}
+
+// frames returns the recorded frame events only; the other hooks without them.
+func (r *traceRecorder) split() (hooks, frames []string) {
+	for _, e := range r.list() {
+		if strings.HasPrefix(e, "FrameRead") || strings.HasPrefix(e, "FrameWritten") {
+			frames = append(frames, e)
+		} else {
+			hooks = append(hooks, e)
+		}
+	}
+	return hooks, frames
+}
+
+func (r *traceRecorder) trace() *rawhttp.ClientTrace {
+	return &rawhttp.ClientTrace{
+		DNSStart: func(host string) { r.add("DNSStart %s", host) },
+		DNSDone: func(info rawhttp.DNSDoneInfo) {
+			r.add("DNSDone %v %v", info.Addrs, info.Err)
+		},
+		ConnectStart: func(network, addr string) { r.add("ConnectStart %s", network) },
+		ConnectDone:  func(network, addr string, err error) { r.add("ConnectDone %v", err) },
+		ProxyConnectDone: func(proxyType, proxyAddr string, err error) {
+			r.add("ProxyConnectDone %s %s %v", proxyType, proxyAddr, err)
+		},
+		TLSHandshakeStart: func() { r.add("TLSHandshakeStart") },
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			r.add("TLSHandshakeDone %s %v", state.NegotiatedProtocol, err)
+		},
+		GotConn: func(info rawhttp.GotConnInfo) {
+			if info.LocalAddr == "" || info.RemoteAddr == "" {
+				r.add("GotConn without addresses")
+			}
+			r.add("GotConn reused=%v", info.Reused)
+		},
+		WroteHeaders:         func() { r.add("WroteHeaders") },
+		WroteRequest:         func(err error) { r.add("WroteRequest %v", err) },
+		GotFirstResponseByte: func() { r.add("GotFirstResponseByte") },
+		Got1xxResponse: func(code int, headers map[string][]string) {
+			r.add("Got1xxResponse %d %v", code, headers["Link"])
+		},
+		FrameRead: func(f rawhttp.FrameInfo) {
+			r.add("FrameRead %s %d", f.Type, f.StreamID)
+		},
+		FrameWritten: func(f rawhttp.FrameInfo) {
+			r.add("FrameWritten %s %d", f.Type, f.StreamID)
+		},
+	}
+}
+
+func expectEvents(t *testing.T, got, want []string) {
+	t.Helper()
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected trace events\ngot:\n  %s\nwant:\n  %s",
+			strings.Join(got, "\n  "), strings.Join(want, "\n  "))
+	}
+}
+
+func TestTrace_HTTP1Lifecycle(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", "</style.css>; rel=preload")
+		w.WriteHeader(http.StatusEarlyHints)
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	rec := &traceRecorder{}
+	opts := rawhttp.Options{
+		Scheme:          "https",
+		Host:            "trace.example",
+		Port:            srv.Listener.Addr().(*net.TCPAddr).Port,
+		Resolver:        &staticResolver{ip: "127.0.0.1"},
+		InsecureTLS:     true,
+		ReuseConnection: true,
+		Trace:           rec.trace(),
+		ConnTimeout:     5 * time.Second,
+		ReadTimeout:     5 * time.Second,
+	}
+	sender := rawhttp.NewSender()
+	req := []byte("GET / HTTP/1.1\r\nHost: trace.example\r\n\r\n")
+	for i := 0; i < 2; i++ {
+		resp, err := sender.Do(context.Background(), req, opts)
+		if err != nil {
+			t.Fatalf("request %d failed: %v", i, err)
+		}
+		resp.Body.Close()
+		resp.Raw.Close()
+	}
+
+	request := []string{
+		"WroteHeaders",
+		"WroteRequest <nil>",
+		"GotFirstResponseByte",
+		"Got1xxResponse 103 [</style.css>; rel=preload]",
+	}
+	want := []string{
+		"DNSStart trace.example",
+		"DNSDone [127.0.0.1] <nil>",
+		"ConnectStart tcp",
+		"ConnectDone <nil>",
+		"TLSHandshakeStart",
+		"TLSHandshakeDone http/1.1 <nil>",
+		"GotConn reused=false",
+	}
+	want = append(want, request...)
+	want = append(want, "GotConn reused=true") // pooled: no DNS, connect or TLS events
+	want = append(want, request...)
+	expectEvents(t, rec.list(), want)
+}
+
+func TestTrace_Proxy(t *testing.T) {
+	dial, _ := pipeDialer(func(conn net.Conn) {
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		if err := readRequestHead(r); err != nil {
+			return
+		}
+		io.WriteString(conn, "HTTP/1.1 200 Connection Established\r\n\r\n")
+		if err := readRequestHead(r); err != nil {
+			return
+		}
+		io.WriteString(conn, "HTTP/1.1 204 No Content\r\nConnection: close\r\n\r\n")
+	})
+
+	rec := &traceRecorder{}
+	opts := rawhttp.Options{
+		Scheme:      "http",
+		Host:        "192.0.2.1",
+		Port:        80,
+		Proxy:       &rawhttp.ProxyConfig{Type: "http", Host: "192.0.2.10", Port: 3128},
+		DialContext: dial,
+		Trace:       rec.trace(),
+		ConnTimeout: 5 * time.Second,
+		ReadTimeout: 5 * time.Second,
+	}
+	resp, err := rawhttp.NewSender().Do(context.Background(), []byte("GET / HTTP/1.1\r\nHost: target\r\n\r\n"), opts)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	resp.Raw.Close()
+
+	hooks := rec.list()
+	expectEvents(t, hooks[:3], []string{
+		"ConnectStart tcp",
+		"ConnectDone <nil>",
+		"ProxyConnectDone http 192.0.2.10:3128 <nil>",
+	})
+}
+
+func TestTrace_HTTP2Frames(t *testing.T) {
+	srv := newHTTP2Server(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "h2")
+	})
+	defer srv.Close()
+
+	rec := &traceRecorder{}
+	opts := h2Opts(srv)
+	opts.Trace = rec.trace()
+	resp, err := rawhttp.NewSender().Do(context.Background(), []byte("GET / HTTP/2\r\nHost: localhost\r\n\r\n"), opts)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	resp.Raw.Close()
+
+	hooks, frames := rec.split()
+	if len(hooks) < 2 || hooks[0] != "DNSStart localhost" || !strings.HasPrefix(hooks[1], "DNSDone") {
+		t.Fatalf("expected the lookup of localhost first, got %v", hooks)
+	}
+	expectEvents(t, hooks[2:], []string{
+		"ConnectStart tcp",
+		"ConnectDone <nil>",
+		"TLSHandshakeStart",
+		"TLSHandshakeDone h2 <nil>",
+		"GotConn reused=false",
+		"WroteHeaders",
+		"WroteRequest <nil>",
+		"GotFirstResponseByte",
+	})
+
+	has := func(event string) bool {
+		for _, f := range frames {
+			if f == event {
+				return true
+			}
+		}
+		return false
+	}
+	for _, event := range []string{
+		"FrameWritten SETTINGS 0", // connection preface settings
+		"FrameRead SETTINGS 0",
+		"FrameWritten HEADERS 1",
+		"FrameRead HEADERS 1",
+		"FrameRead DATA 1",
+	} {
+		if !has(event) {
+			t.Errorf("missing %q in frame events %v", event, frames)
+		}
+	}
+}
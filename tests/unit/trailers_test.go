@@ -0,0 +1,85 @@
+package unit
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/WhileEndless/go-rawhttp"
+)
+
+func TestTrailers_HTTP1Chunked(t *testing.T) {
+	port := startRawServer(t, func(conn net.Conn) {
+		defer conn.Close()
+		if err := readRequestHead(bufio.NewReader(conn)); err != nil {
+			return
+		}
+		io.WriteString(conn, "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\nTrailer: X-Checksum\r\n\r\n"+
+			"2\r\nok\r\n0\r\nX-Checksum: abc\r\nX-Checksum: def\r\n\r\n")
+	})
+
+	req := []byte("GET / HTTP/1.1\r\nHost: 127.0.0.1\r\n\r\n")
+	resp, err := rawhttp.NewSender().Do(context.Background(), req, streamOpts(port))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	defer resp.Raw.Close()
+
+	if string(resp.Body.Bytes()) != "ok" {
+		t.Fatalf("unexpected body %q", resp.Body.Bytes())
+	}
+	if got := resp.Trailers["X-Checksum"]; len(got) != 2 || got[0] != "abc" || got[1] != "def" {
+		t.Errorf("expected both X-Checksum trailers, got %v", resp.Trailers)
+	}
+	if _, leaked := resp.Headers["X-Checksum"]; leaked {
+		t.Errorf("trailers must not be merged into Headers: %v", resp.Headers)
+	}
+}
+
+// grpcEcho reads the request body and trailers and answers with grpc-status in
+// the response trailers.
+func grpcEcho(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	w.Header().Set("Trailer", "Grpc-Status, X-Echo-Trailer")
+	w.WriteHeader(200)
+	w.Write(body)
+	w.Header().Set("Grpc-Status", "0")
+	w.Header().Set("X-Echo-Trailer", r.Trailer.Get("X-Request-Trailer"))
+}
+
+func TestTrailers_HTTP2RequestAndResponse(t *testing.T) {
+	srv := newHTTP2Server(grpcEcho)
+	defer srv.Close()
+
+	req := []byte("POST /svc/Method HTTP/2\r\nHost: localhost\r\nTE: trailers\r\n" +
+		"Transfer-Encoding: chunked\r\nTrailer: X-Request-Trailer\r\n\r\n" +
+		"5\r\nhello\r\n0\r\nX-Request-Trailer: sent\r\n\r\n")
+	resp, err := rawhttp.NewSender().Do(context.Background(), req, h2Opts(srv))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	defer resp.Raw.Close()
+
+	if resp.StatusCode != 200 || string(resp.Body.Bytes()) != "hello" {
+		t.Fatalf("expected 200 %q, got %d %q", "hello", resp.StatusCode, resp.Body.Bytes())
+	}
+	if got := resp.Trailers["grpc-status"]; len(got) != 1 || got[0] != "0" {
+		t.Errorf("expected grpc-status trailer, got %v", resp.Trailers)
+	}
+	if got := resp.Trailers["x-echo-trailer"]; len(got) != 1 || got[0] != "sent" {
+		t.Errorf("request trailer did not reach the server, got %v", resp.Trailers)
+	}
+	if _, leaked := resp.Headers["grpc-status"]; leaked {
+		t.Errorf("trailers must not be merged into Headers: %v", resp.Headers)
+	}
+	for _, h := range resp.HeaderList {
+		if h.Name == "grpc-status" {
+			t.Errorf("trailer field in HeaderList: %+v", h)
+		}
+	}
+}